@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/batch"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+func NewBatchCmd() *cobra.Command {
+	var outDir string
+	var spk string
+	var modelId string
+	var lang string
+	var format string
+	var apiURL string
+	var concurrency int
+	var statePath string
+	var noState bool
+	var retries int
+	var cacheOpts cacheFlags
+	var editOpts editFlags
+
+	cmd := &cobra.Command{
+		Use:   "batch MANIFEST",
+		Short: "Synthesize every row of a CSV or JSONL manifest",
+		Long: `Synthesize many clips from a manifest file.
+
+JSONL manifests have one object per line; CSV manifests have a header row.
+Both use the same field names:
+
+  text, speaker, model, lang, format, output,
+  temperature, top_p, repetition_penalty, max_tokens,
+  sampling_rate, speed_alpha, pause_between_brackets,
+  phonemize_between_brackets, inline_speed_alpha,
+  no_text_normalization, save_oovs
+
+Rows that omit speaker, model, lang or format use the values from the flags.
+Rows without an output path are written to --out-dir, named by their line
+number zero-padded to four digits and their format, e.g. 0007.wav. Rows whose
+MP3 audio is trimmed or padded are saved as WAV, so get a .wav extension.
+
+Progress is recorded in a state file (MANIFEST.state.json by default). When a
+run is repeated, rows whose output still exists with the recorded content hash
+and whose manifest entry and edit flags are unchanged are skipped, so an
+interrupted run can simply be restarted. Failed rows are recorded with their error and retried.
+
+--normalize brings every clip to the same loudness target, such as -16LUFS
+(EBU R128), so clips from different speakers and models play back at an
+even level. MP3 rows can't be normalized.
+
+--trim-silence removes silence from the start and end of each clip, so
+prompts start playing without a delay, and --pad-start and --pad-end add a
+fixed amount back. Trimmed or padded MP3 rows are saved as WAV.
+
+Example manifest.jsonl:
+  {"text": "Welcome to Rime.", "speaker": "astra", "model": "arcana", "output": "welcome.wav"}
+  {"text": "Goodbye!", "speaker": "celeste", "model": "mistv2", "speed_alpha": 1.2}`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows, err := batch.LoadManifest(args[0])
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return fmt.Errorf("manifest %s has no rows", args[0])
+			}
+
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative, got %d", retries)
+			}
+			edits, err := editOpts.edits(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			var state *batch.State
+			if !noState {
+				if statePath == "" {
+					statePath = args[0] + ".state.json"
+				}
+				state, err = batch.LoadState(statePath)
+				if err != nil {
+					return err
+				}
+			}
+
+			respCache, err := cacheOpts.open()
+			if err != nil {
+				return err
+			}
+
+			client, err := tts.NewClient(Version, apiURL, ConfigEnv, ConfigFile, retries, respCache)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			opts := batch.Options{
+				OutDir:      outDir,
+				Concurrency: concurrency,
+				State:       state,
+				Edits:       edits,
+				Defaults: batch.Defaults{
+					Speaker: spk,
+					ModelID: modelId,
+					Lang:    lang,
+					Format:  format,
+				},
+				OnResult: func(res batch.RowResult) {
+					if JSONOutput {
+						encoder.Encode(res)
+						return
+					}
+					if Quiet {
+						return
+					}
+					if res.Skipped {
+						fmt.Fprintln(os.Stderr, styles.Dim(fmt.Sprintf("line %d: %s is up to date", res.Line, res.OutputFile)))
+					} else if res.OK() {
+						fmt.Fprintln(os.Stderr, styles.Successf("line %d: saved %s", res.Line, res.OutputFile))
+					} else {
+						fmt.Fprintln(os.Stderr, styles.Error(fmt.Sprintf("line %d: %s", res.Line, res.Error)))
+					}
+				},
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			summary := batch.Run(ctx, client, rows, opts)
+
+			if !Quiet && !JSONOutput {
+				line := fmt.Sprintf("%d rows: %d succeeded, %d skipped, %d failed", summary.Total, summary.Succeeded, summary.Skipped, summary.Failed)
+				if summary.NotStarted > 0 {
+					line += fmt.Sprintf(", %d not started", summary.NotStarted)
+				}
+				fmt.Fprintln(os.Stderr, styles.Dim(line))
+			}
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("interrupted with %d of %d rows unfinished: %w", summary.Failed+summary.NotStarted, summary.Total, err)
+			}
+			if summary.Failed > 0 {
+				return fmt.Errorf("%d of %d rows failed", summary.Failed, summary.Total)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outDir, "out-dir", "d", "", "Directory for relative output paths (default: current directory)")
+	cmd.Flags().StringVarP(&spk, "speaker", "s", "", "Default speaker for rows that omit one")
+	cmd.Flags().StringVarP(&modelId, "model-id", "m", "", "Default model for rows that omit one")
+	cmd.Flags().StringVarP(&lang, "lang", "l", "", "Default language for rows that omit one (default: eng)")
+	cmd.Flags().StringVarP(&format, "format", "f", "", "Default audio format for rows that omit one: wav, mp3, pcm, mulaw or alaw")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 1, "Number of rows to synthesize in parallel")
+	cmd.Flags().StringVar(&statePath, "state", "", "State file for resuming interrupted runs (default: MANIFEST.state.json)")
+	cmd.Flags().BoolVar(&noState, "no-state", false, "Do not read or write a state file")
+	cmd.Flags().IntVar(&retries, "retries", 0, "Retry failed requests (network errors, 429 and 5xx) up to N times")
+	editOpts.register(cmd.Flags())
+	cacheOpts.register(cmd.Flags())
+	cmd.Flags().StringVar(&apiURL, "api-url", "", "API URL (default: $RIME_API_URL or https://users.rime.ai/v1/rime-tts)")
+	registerTTSCompletions(cmd, "model-id")
+
+	return cmd
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+	"github.com/rimelabs/rime-cli/internal/batch"
+)
+
+func TestBatch_JSONOutput(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(2400)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.WriteHeader(http.StatusOK)
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	setupSpeedtestConfig(t, server.URL)
+	Version = "test-version"
+	Quiet = false
+	JSONOutput = true
+	ConfigFile = ""
+	ConfigEnv = ""
+	defer func() { JSONOutput = false }()
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.jsonl")
+	os.WriteFile(manifest, []byte(`{"text": "one", "output": "one.wav"}
+{"text": "two", "speaker": "celeste", "output": "two.wav"}
+`), 0644)
+
+	cmd := NewBatchCmd()
+	cmd.SetArgs([]string{manifest, "-s", "astra", "-m", "arcana", "-d", dir})
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+
+	out, _ := io.ReadAll(r)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d: %s", len(lines), out)
+	}
+	var res batch.RowResult
+	if err := json.Unmarshal([]byte(lines[1]), &res); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if res.Line != 2 || res.Speaker != "celeste" || res.ModelID != "arcana" || res.SizeBytes == 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "two.wav")); err != nil {
+		t.Errorf("expected output file: %v", err)
+	}
+}
+
+func TestBatch_FailuresReturnError(t *testing.T) {
+	setupSpeedtestConfig(t, "http://127.0.0.1:1")
+	Quiet = true
+	JSONOutput = false
+	ConfigFile = ""
+	ConfigEnv = ""
+	defer func() { Quiet = false }()
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.csv")
+	os.WriteFile(manifest, []byte("text,speaker,model\n,astra,arcana\n"), 0644)
+
+	cmd := NewBatchCmd()
+	cmd.SetArgs([]string{manifest, "-d", dir})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 rows failed") {
+		t.Errorf("expected row failure error, got: %v", err)
+	}
+}
+
+func TestBatch_EmptyManifest(t *testing.T) {
+	setupSpeedtestConfig(t, "http://127.0.0.1:1")
+	manifest := filepath.Join(t.TempDir(), "empty.jsonl")
+	os.WriteFile(manifest, nil, 0644)
+
+	cmd := NewBatchCmd()
+	cmd.SetArgs([]string{manifest})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "no rows") {
+		t.Errorf("expected empty manifest error, got: %v", err)
+	}
+}
+
+func TestBatch_RetriesTransientErrors(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(2400)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	setupSpeedtestConfig(t, server.URL)
+	Quiet = true
+	JSONOutput = false
+	ConfigFile = ""
+	ConfigEnv = ""
+	defer func() { Quiet = false }()
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.jsonl")
+	os.WriteFile(manifest, []byte(`{"text": "one", "output": "one.wav"}`+"\n"), 0644)
+
+	cmd := NewBatchCmd()
+	cmd.SetArgs([]string{manifest, "-s", "astra", "-m", "arcana", "-d", dir, "--no-state", "--retries", "2"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
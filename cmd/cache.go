@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/cache"
+	"github.com/rimelabs/rime-cli/internal/output/formatters"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+)
+
+func NewCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local TTS response cache",
+		Long: `Manages the on-disk cache of TTS responses in ~/.rime/cache.
+
+The cache is off by default. Enable it for one command with --cache, or for
+every command by setting RIME_CACHE=1; --no-cache bypasses it. Identical
+requests (text, speaker, model, parameters and format) are then answered from
+disk. Once the cache grows past RIME_CACHE_MAX_SIZE (default 500MB) the least
+recently used responses are removed.`,
+	}
+	cmd.AddCommand(NewCacheLsCmd())
+	cmd.AddCommand(NewCacheClearCmd())
+	cmd.AddCommand(NewCacheStatsCmd())
+	return cmd
+}
+
+func NewCacheLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List cached responses, most recently used first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+			entries, err := c.List()
+			if err != nil {
+				return err
+			}
+
+			if JSONOutput {
+				if entries == nil {
+					entries = []cache.Entry{}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(entries)
+			}
+
+			if len(entries) == 0 {
+				if !Quiet {
+					fmt.Fprintln(os.Stderr, "The cache is empty.")
+				}
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "KEY", "SPEAKER", "MODEL", "SIZE", "HITS", "LAST USED", "TEXT")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+					e.Key[:12], e.Speaker, e.ModelID, formatters.FormatBytes(int(e.SizeBytes)), e.Hits,
+					e.LastUsed.Local().Format("2006-01-02 15:04"), formatters.TruncateText(strings.Join(strings.Fields(e.Text), " "), 40))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func NewCacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every cached response",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+			n, freed, err := c.Clear()
+			if err != nil {
+				return err
+			}
+			if JSONOutput {
+				return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"removed":     n,
+					"freed_bytes": freed,
+				})
+			}
+			if !Quiet {
+				fmt.Fprintln(os.Stderr, styles.Successf("Removed %d cached responses (%s)", n, formatters.FormatBytes(int(freed))))
+			}
+			return nil
+		},
+	}
+}
+
+func NewCacheStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show cache size and usage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+			stats, err := c.Stats()
+			if err != nil {
+				return err
+			}
+			if JSONOutput {
+				stats := struct {
+					cache.Stats
+					Enabled bool `json:"enabled"`
+				}{stats, cache.Enabled()}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(stats)
+			}
+
+			enabled := "no (use --cache or set " + cache.EnvCache + "=1)"
+			if cache.Enabled() {
+				enabled = "yes (" + cache.EnvCache + ")"
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "Directory:\t%s\n", stats.Dir)
+			fmt.Fprintf(w, "Enabled by default:\t%s\n", enabled)
+			fmt.Fprintf(w, "Entries:\t%d\n", stats.Entries)
+			fmt.Fprintf(w, "Size:\t%s of %s\n", formatters.FormatBytes(int(stats.SizeBytes)), formatters.FormatBytes(int(stats.MaxSizeBytes)))
+			fmt.Fprintf(w, "Hits:\t%d\n", stats.Hits)
+			return w.Flush()
+		},
+	}
+}
+
+// cacheFlags are the --cache and --no-cache flags of commands that call the
+// TTS API.
+type cacheFlags struct {
+	use bool
+	off bool
+}
+
+func (f *cacheFlags) register(flags *pflag.FlagSet) {
+	flags.BoolVar(&f.use, "cache", false, "Answer repeated requests from the local response cache (default from $"+cache.EnvCache+")")
+	flags.BoolVar(&f.off, "no-cache", false, "Bypass the response cache even if $"+cache.EnvCache+" is set")
+}
+
+// open returns the response cache if it is enabled, or nil.
+func (f *cacheFlags) open() (api.ResponseCache, error) {
+	if f.use && f.off {
+		return nil, fmt.Errorf("cannot use both --cache and --no-cache")
+	}
+	if f.off || !(f.use || cache.Enabled()) {
+		return nil, nil
+	}
+	c, err := cache.Open()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+	"github.com/rimelabs/rime-cli/internal/cache"
+	"github.com/rimelabs/rime-cli/internal/config"
+)
+
+func TestTTS_CacheFlags(t *testing.T) {
+	var calls int32
+	wavData := testhelpers.MakeValidWAV(24000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("RIME_API_URL", server.URL)
+	t.Setenv(cache.EnvCache, "")
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+	Quiet = true
+	defer func() { Quiet = false }()
+
+	output := filepath.Join(tmpDir, "out.wav")
+	run := func(extra ...string) error {
+		cmd := NewTTSCmd()
+		cmd.SetArgs(append([]string{"hello", "-s", "astra", "-m", "arcana", "-o", output}, extra...))
+		return cmd.Execute()
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := run("--cache"); err != nil {
+			t.Fatalf("tts --cache failed: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected the second --cache run to be a cache hit, got %d API calls", n)
+	}
+
+	// Without the flag or RIME_CACHE the cache is not used.
+	if err := run(); err != nil {
+		t.Fatalf("tts failed: %v", err)
+	}
+	t.Setenv(cache.EnvCache, "1")
+	if err := run("--no-cache"); err != nil {
+		t.Fatalf("tts --no-cache failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("expected runs without the cache to call the API, got %d calls", n)
+	}
+
+	if err := run("--cache", "--no-cache"); err == nil || !strings.Contains(err.Error(), "cannot use both") {
+		t.Errorf("expected a flag conflict error, got %v", err)
+	}
+
+	JSONOutput = true
+	defer func() { JSONOutput = false }()
+	statsCmd := NewCacheCmd()
+	statsCmd.SetArgs([]string{"stats"})
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := statsCmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("cache stats failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+
+	var stats struct {
+		Entries int  `json:"entries"`
+		Hits    int  `json:"hits"`
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	if stats.Entries != 1 || stats.Hits != 1 || !stats.Enabled {
+		t.Errorf("unexpected stats: %s", data)
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/config"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+)
+
+type completionFunc func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)
+
+// completeModelIDs completes model flags from the supported model IDs.
+func completeModelIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return withPrefix(api.ValidModelIDs(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// langCompletion completes --lang with the languages valid for the model
+// given in modelFlag, or for arcana if none has been typed yet.
+func langCompletion(modelFlag string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		modelID, _ := cmd.Flags().GetString(modelFlag)
+		if !api.IsValidModelID(modelID) {
+			modelID = api.ModelIDArcana
+		}
+		return withPrefix(api.ValidLangsForModel(modelID), toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeEnvironments completes --env from the environments in the config
+// file selected by --config.
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var cfg *config.Config
+	var err error
+	if ConfigFile != "" {
+		cfg, err = config.LoadConfigFromPath(ConfigFile)
+	} else {
+		cfg, err = config.LoadConfig()
+	}
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return withPrefix(cfg.ListEnvironments(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func withPrefix(values []string, prefix string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// registerTTSCompletions wires up completion for the speaker, model and
+// language flags shared by the synthesis commands.
+func registerTTSCompletions(cmd *cobra.Command, modelFlag string) {
+	cmd.RegisterFlagCompletionFunc(modelFlag, completeModelIDs)
+	if cmd.Flags().Lookup("speaker") != nil {
+		cmd.RegisterFlagCompletionFunc("speaker", speakerCompletion(modelFlag))
+	}
+	if cmd.Flags().Lookup("lang") != nil {
+		cmd.RegisterFlagCompletionFunc("lang", langCompletion(modelFlag))
+	}
+}
+
+// addCompletionInstallCmd adds `completion install` to Cobra's default
+// completion command.
+func addCompletionInstallCmd(root *cobra.Command) {
+	root.InitDefaultCompletionCmd()
+	for _, c := range root.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(newCompletionInstallCmd())
+			return
+		}
+	}
+}
+
+func newCompletionInstallCmd() *cobra.Command {
+	var shell string
+	var path string
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the completion script for your shell",
+		Long: `Writes the completion script for bash, zsh or fish to the location that
+shell loads completions from. The shell is taken from $SHELL unless --shell is
+given.
+
+  bash  ~/.local/share/bash-completion/completions/rime (needs bash-completion)
+  zsh   ~/.zfunc/_rime (add ~/.zfunc to fpath before compinit)
+  fish  ~/.config/fish/completions/rime.fish`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if shell == "" {
+				shell = filepath.Base(os.Getenv("SHELL"))
+			}
+			written, hint, err := installCompletion(cmd.Root(), shell, path)
+			if err != nil {
+				return err
+			}
+			if !Quiet {
+				fmt.Fprintln(os.Stderr, styles.Successf("Installed %s completions to %s", shell, written))
+				if hint != "" {
+					fmt.Fprintln(os.Stderr, styles.Dim(hint))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&shell, "shell", "", "Shell to install for: bash, zsh or fish (default: from $SHELL)")
+	cmd.Flags().StringVar(&path, "path", "", "Write the script here instead of the default location")
+	cmd.RegisterFlagCompletionFunc("shell", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return withPrefix([]string{"bash", "fish", "zsh"}, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+// installCompletion generates root's completion script for shell and writes
+// it to path, or to the shell's default location if path is empty. It
+// returns the path written and any setup the user still needs to do.
+func installCompletion(root *cobra.Command, shell, path string) (string, string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	name := root.Name()
+
+	var script bytes.Buffer
+	var defaultPath, hint string
+	switch shell {
+	case "bash":
+		err = root.GenBashCompletionV2(&script, true)
+		defaultPath = filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local/share"), "bash-completion", "completions", name)
+		hint = "Start a new shell to load them. Requires the bash-completion package."
+	case "zsh":
+		err = root.GenZshCompletion(&script)
+		defaultPath = filepath.Join(home, ".zfunc", "_"+name)
+		hint = "Make sure ~/.zshrc has 'fpath+=~/.zfunc' before 'autoload -Uz compinit && compinit', then start a new shell."
+	case "fish":
+		err = root.GenFishCompletion(&script, true)
+		defaultPath = filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "fish", "completions", name+".fish")
+		hint = "Start a new shell to load them."
+	case "":
+		return "", "", fmt.Errorf("could not detect your shell; use --shell bash, zsh or fish")
+	default:
+		return "", "", fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish). Use 'rime completion %s' to print a script", shell, shell)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate %s completions: %w", shell, err)
+	}
+
+	if path == "" {
+		path = defaultPath
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(path, script.Bytes(), 0644); err != nil {
+		return "", "", err
+	}
+	return path, hint, nil
+}
+
+func xdgDir(env, home, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, fallback)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/voices"
+)
+
+// complete runs Cobra's hidden __complete command and returns the
+// suggestions, without the trailing directive line.
+func complete(t *testing.T, args ...string) []string {
+	t.Helper()
+	root := NewRootCmd("test")
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs(append([]string{"__complete"}, args...))
+	if err := root.Execute(); err != nil {
+		t.Fatalf("__complete %v failed: %v", args, err)
+	}
+	var suggestions []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line != "" && !strings.HasPrefix(line, ":") {
+			suggestions = append(suggestions, strings.SplitN(line, "\t", 2)[0])
+		}
+	}
+	return suggestions
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCompletion_ModelAndLang(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ConfigFile = ""
+
+	models := complete(t, "tts", "--model-id", "arc")
+	if !contains(models, "arcana") || !contains(models, "arcanav2") || contains(models, "mistv2") {
+		t.Errorf("unexpected model completions: %v", models)
+	}
+
+	if langs := complete(t, "tts", "--lang", ""); !contains(langs, "jpn") {
+		t.Errorf("arcana languages should include jpn: %v", langs)
+	}
+	langs := complete(t, "tts", "--model-id", "mistv2", "--lang", "")
+	if contains(langs, "jpn") || !contains(langs, "spa") {
+		t.Errorf("mistv2 languages should follow the typed model: %v", langs)
+	}
+}
+
+func TestCompletion_Environments(t *testing.T) {
+	setupSpeedtestConfigWithEnv(t, "http://default", "http://slow")
+	ConfigFile = ""
+
+	envs := complete(t, "tts", "--env", "")
+	if !contains(envs, "default") || !contains(envs, "slow") {
+		t.Errorf("unexpected env completions: %v", envs)
+	}
+}
+
+func TestCompletionInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	want := map[string]string{
+		"bash": filepath.Join(home, ".local", "share", "bash-completion", "completions", "rime"),
+		"zsh":  filepath.Join(home, ".zfunc", "_rime"),
+		"fish": filepath.Join(home, ".config", "fish", "completions", "rime.fish"),
+	}
+	for shell, path := range want {
+		got, _, err := installCompletion(NewRootCmd("test"), shell, "")
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		if got != path {
+			t.Errorf("%s: installed to %s, want %s", shell, got, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !bytes.Contains(data, []byte("rime")) {
+			t.Errorf("%s: expected a completion script at %s (err %v)", shell, path, err)
+		}
+	}
+
+	if _, _, err := installCompletion(NewRootCmd("test"), "tcsh", ""); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
+
+func TestCompletion_SpeakersFromCacheOnly(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode([]api.Voice{{Speaker: "astra", ModelID: "arcana", Lang: "eng"}})
+	}))
+	defer server.Close()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(api.EnvVoicesURL, server.URL)
+	ConfigFile = ""
+
+	if speakers := complete(t, "tts", "--speaker", ""); len(speakers) != 0 || requests != 0 {
+		t.Errorf("with nothing cached: suggested %v after %d requests, want nothing and no requests", speakers, requests)
+	}
+
+	if _, err := voices.Load(context.Background(), api.NewVoicesClient("test"), voices.LoadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	requests = 0
+	if speakers := complete(t, "tts", "--speaker", "as"); !contains(speakers, "astra") || requests != 0 {
+		t.Errorf("with the catalog cached: suggested %v after %d requests, want astra and no requests", speakers, requests)
+	}
+}
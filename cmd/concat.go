@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/output/formatters"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+)
+
+// concatResult is the --json output of rime concat.
+type concatResult struct {
+	Inputs      []string `json:"inputs"`
+	Output      string   `json:"output"`
+	ContentType string   `json:"content_type"`
+	DurationMs  int64    `json:"duration_ms"`
+	SizeBytes   int      `json:"size_bytes"`
+	// Transcript joins the transcripts found in the inputs' metadata.
+	Transcript string `json:"transcript,omitempty"`
+}
+
+func NewConcatCmd() *cobra.Command {
+	var output string
+	var opts convert.ConcatOptions
+
+	cmd := &cobra.Command{
+		Use:   "concat INPUT... -o OUTPUT",
+		Short: "Join audio files one after another",
+		Long: `Join WAV, MP3 or headerless audio files into one file, in order.
+
+Use it to assemble prompts from pieces:
+  rime concat balance-is.wav amount.wav dollars.wav -o out.wav --gap 150ms --crossfade 20ms
+
+--gap inserts silence between the files, and --crossfade fades each file out
+and the next one in over the given time, overlapping them by as much, so the
+joins don't click.
+
+The output has the first file's sample rate, channel count and bit depth, and
+its format follows the OUTPUT extension: .wav, or .pcm, .ulaw or .alaw for
+headerless audio. Files with a different sample rate or channel count are an
+error unless --resample is given, which converts them.
+
+The transcripts that rime tts embeds in each file are joined into one and
+embedded in WAV output, so 'rime play' shows the whole prompt.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				return fmt.Errorf("--output is required")
+			}
+			if opts.Gap < 0 || opts.Crossfade < 0 {
+				return fmt.Errorf("--gap and --crossfade must not be negative")
+			}
+			outContentType, err := convertOutputType(output)
+			if err != nil {
+				return err
+			}
+
+			parts := make([]convert.Part, len(args))
+			var comments []metadata.ParsedComment
+			for i, path := range args {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				contentType := detectformat.DetectFile(path, data)
+				if contentType == "" {
+					return fmt.Errorf("unrecognized audio format: %s", path)
+				}
+				parts[i] = convert.Part{Name: path, Data: data, ContentType: contentType}
+				if !codec.IsRaw(contentType) {
+					if comment, ok := metadata.GetParsedCommentFromFile(data); ok {
+						comments = append(comments, *comment)
+					}
+				}
+			}
+
+			res, err := convert.Concat(parts, outContentType, opts)
+			if err != nil {
+				return fmt.Errorf("failed to concatenate: %w", err)
+			}
+			var transcript string
+			if len(comments) > 0 {
+				merged := metadata.MergeComments(comments)
+				transcript = merged.Text
+				if res.ContentType == "audio/wav" {
+					res.Data = metadata.EmbedMetadata(res.Data, metadata.WavMetadata{
+						Artist:  "Rime AI TTS",
+						Name:    fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", merged.Speaker, merged.ModelID, merged.Language, formatters.TruncateText(merged.Text, 50)),
+						Comment: merged.String(),
+					})
+				}
+			}
+			if err := os.WriteFile(output, res.Data, 0644); err != nil {
+				return err
+			}
+
+			duration := analyze.CalculateWavDuration(res.Data)
+			if f, ok := codec.ParseContentType(res.ContentType); ok {
+				duration = f.Duration(len(res.Data))
+			}
+
+			if JSONOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(concatResult{
+					Inputs:      args,
+					Output:      output,
+					ContentType: res.ContentType,
+					DurationMs:  duration.Milliseconds(),
+					SizeBytes:   len(res.Data),
+					Transcript:  transcript,
+				})
+			}
+			if !Quiet {
+				fmt.Fprintln(os.Stderr, styles.Successf("Joined %d files into %s", len(args), output))
+				fmt.Fprintln(os.Stderr, styles.Dim(fmt.Sprintf("Duration: %s | Size: %s",
+					formatters.FormatDuration(duration), formatters.FormatBytes(len(res.Data)))))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file path (required)")
+	cmd.Flags().DurationVar(&opts.Gap, "gap", 0, "Silence to insert between files, e.g. 150ms")
+	cmd.Flags().DurationVar(&opts.Crossfade, "crossfade", 0, "Fade between files over this long, e.g. 20ms")
+	cmd.Flags().BoolVar(&opts.Resample, "resample", false, "Convert files whose sample rate or channel count differ from the first file's")
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func TestConcat_MergesTranscripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	var inputs []string
+	for i, text := range []string{"Your balance is", "forty two dollars."} {
+		path := filepath.Join(tmpDir, []string{"a.wav", "b.wav"}[i])
+		data := metadata.EmbedMetadata(testhelpers.MakeValidWAV(2400), metadata.WavMetadata{Comment: "[astra-arcana-eng]: " + text})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, path)
+	}
+	output := filepath.Join(tmpDir, "out.wav")
+
+	JSONOutput = true
+	defer func() { JSONOutput = false }()
+	cmd := NewConcatCmd()
+	cmd.SetArgs(append(inputs, "-o", output, "--gap", "100ms"))
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("concat failed: %v", err)
+	}
+
+	out, _ := io.ReadAll(r)
+	var res concatResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if res.Transcript != "Your balance is forty two dollars." {
+		t.Errorf("transcript = %q", res.Transcript)
+	}
+	// Two 100 ms parts and a 100 ms gap.
+	if res.DurationMs != 300 {
+		t.Errorf("duration = %d ms, want 300", res.DurationMs)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("output not written: %v", err)
+	}
+	parsed, ok := metadata.GetParsedCommentFromFile(data)
+	if !ok || parsed.Text != res.Transcript || parsed.Speaker != "astra" {
+		t.Errorf("output comment = %+v, %v", parsed, ok)
+	}
+}
+
+func TestConcat_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.wav")
+	b := filepath.Join(tmpDir, "b.wav")
+	os.WriteFile(a, testhelpers.MakeValidWAV(100), 0644)
+	os.WriteFile(b, testhelpers.MakeMinimalWAV(), 0644)
+	out := filepath.Join(tmpDir, "out.wav")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{a}, "requires at least 2 arg(s)"},
+		{[]string{a, a}, "--output is required"},
+		{[]string{a, a, "-o", out, "--gap", "-1s"}, "must not be negative"},
+		{[]string{a, a, "-o", "out.mp3"}, "MP3 output is not supported"},
+		{[]string{a, b, "-o", out}, "use --resample"},
+	}
+	for _, tt := range tests {
+		cmd := NewConcatCmd()
+		cmd.SetArgs(tt.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
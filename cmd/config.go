@@ -144,6 +144,7 @@ func NewConfigInitCmd() *cobra.Command {
 
 			cfg := fmt.Sprintf(`api_key = %q
 api_url = "https://users.rime.ai/v1/rime-tts"
+# output_device = "default"  # see 'rime devices'
 
 # [env.example]
 # api_url = "https://example.rime.ai/v1/rime-tts"
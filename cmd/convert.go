@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
+	"github.com/rimelabs/rime-cli/internal/output/formatters"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+)
+
+// convertResult is the --json output of rime convert.
+type convertResult struct {
+	Input       string `json:"input"`
+	Output      string `json:"output"`
+	ContentType string `json:"content_type"`
+	DurationMs  int64  `json:"duration_ms"`
+	SizeBytes   int    `json:"size_bytes"`
+	// Loudness is set when --normalize was given.
+	Loudness *loudness.Report `json:"loudness,omitempty"`
+}
+
+func NewConvertCmd() *cobra.Command {
+	var opts convert.Options
+	var editOpts editFlags
+	inputFormat := rawFormatFlags{prefix: "input-"}
+
+	cmd := &cobra.Command{
+		Use:   "convert INPUT OUTPUT",
+		Short: "Convert audio to another sample rate, channel count or bit depth",
+		Long: `Convert a WAV, MP3 or headerless audio file to WAV or headerless audio.
+
+The output format follows the OUTPUT extension: .wav, or .pcm, .ulaw or .alaw
+for headerless audio. MP3 output is not supported. Resampling uses a
+windowed-sinc filter, stereo is mixed down to mono by averaging (and mono
+copied to both channels of stereo), and samples rounded to a lower bit depth
+get triangular dither unless --no-dither is given. Options left out keep the
+input's values:
+  rime convert in.mp3 out.wav --rate 16000 --channels 1 --bits 16
+  rime convert prompt.wav prompt.ulaw --rate 8000
+
+--normalize brings the integrated loudness (EBU R128) to a target such as
+-16LUFS, limiting true peaks to -1 dBTP:
+  rime convert clip.wav clip-16.wav --normalize -16LUFS
+
+--trim-silence cuts silence from the start and end, and --pad-start and
+--pad-end add silence around what is left:
+  rime convert prompt.mp3 prompt.wav --trim-silence --pad-end 250ms
+
+Headerless input is recognized by its extension or by --input-format, with
+--input-rate and --input-channels giving its sample rate and channel count.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inPath, outPath := args[0], args[1]
+			edits, err := editOpts.edits(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			opts.Normalize = edits.Normalize
+			opts.TrimSilence = edits.TrimSilence
+			opts.PadStart, opts.PadEnd = edits.PadStart, edits.PadEnd
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			outContentType, err := convertOutputType(outPath)
+			if err != nil {
+				return err
+			}
+			contentType, err := inputFormat.contentType(inPath)
+			if err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(inPath)
+			if err != nil {
+				return err
+			}
+			if contentType == "" {
+				contentType = detectformat.DetectFile(inPath, data)
+				if contentType == "" {
+					return fmt.Errorf("unrecognized audio format: %s (use --input-format)", inPath)
+				}
+			}
+
+			res, err := convert.Convert(data, contentType, outContentType, opts)
+			if err != nil {
+				return fmt.Errorf("failed to convert %s: %w", inPath, err)
+			}
+			if err := os.WriteFile(outPath, res.Data, 0644); err != nil {
+				return err
+			}
+
+			duration := analyze.CalculateWavDuration(res.Data)
+			if f, ok := codec.ParseContentType(res.ContentType); ok {
+				duration = f.Duration(len(res.Data))
+			}
+
+			if JSONOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(convertResult{
+					Input:       inPath,
+					Output:      outPath,
+					ContentType: res.ContentType,
+					DurationMs:  duration.Milliseconds(),
+					SizeBytes:   len(res.Data),
+					Loudness:    res.Loudness,
+				})
+			}
+			if !Quiet {
+				fmt.Fprintln(os.Stderr, styles.Successf("Converted %s to %s", inPath, outPath))
+				stats := fmt.Sprintf("Duration: %s | Size: %s",
+					formatters.FormatDuration(duration), formatters.FormatBytes(len(res.Data)))
+				if res.Loudness != nil {
+					stats += " | " + res.Loudness.String()
+				}
+				fmt.Fprintln(os.Stderr, styles.Dim(stats))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.SampleRate, "rate", 0, "Output sample rate in Hz (default: the input's)")
+	cmd.Flags().IntVar(&opts.NumChannels, "channels", 0, "Output channel count, 1 or 2 (default: the input's)")
+	cmd.Flags().IntVar(&opts.BitDepth, "bits", 0, "Output bits per sample for WAV: 8, 16, 24 or 32 (default: the input's)")
+	cmd.Flags().BoolVar(&opts.NoDither, "no-dither", false, "Round samples to the output bit depth without dither")
+	editOpts.register(cmd.Flags())
+	inputFormat.register(cmd.Flags())
+
+	return cmd
+}
+
+// convertOutputType returns the content type to write path as, from its
+// extension.
+func convertOutputType(path string) (string, error) {
+	contentType := detectformat.FromExtension(path)
+	switch {
+	case contentType == "audio/wav" || codec.IsRaw(contentType):
+		return contentType, nil
+	case contentType == "audio/mp3":
+		return "", fmt.Errorf("MP3 output is not supported; convert to .wav instead")
+	}
+	return "", fmt.Errorf("unsupported output file extension: %s (use .wav, .pcm, .ulaw or .alaw)", path)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func TestConvert_WritesWAV(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.wav")
+	if err := os.WriteFile(input, testhelpers.MakeValidWAV(24000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tmpDir, "out.wav")
+
+	Quiet = true
+	defer func() { Quiet = false }()
+	cmd := NewConvertCmd()
+	cmd.SetArgs([]string{input, output, "--rate", "16000", "--channels", "2", "--bits", "8"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("output not written: %v", err)
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:28]); rate != 16000 {
+		t.Errorf("sample rate = %d, want 16000", rate)
+	}
+	if ch, bits := binary.LittleEndian.Uint16(data[22:24]), binary.LittleEndian.Uint16(data[34:36]); ch != 2 || bits != 8 {
+		t.Errorf("got %d channels, %d bits; want 2, 8", ch, bits)
+	}
+	if size := len(data) - 44; size != 2*16000 {
+		t.Errorf("data size = %d, want %d", size, 2*16000)
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.wav")
+	if err := os.WriteFile(input, testhelpers.MakeValidWAV(100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{input, "out.mp3"}, "MP3 output is not supported"},
+		{[]string{input, "out.ogg"}, "unsupported output file extension"},
+		{[]string{input, "out.wav", "--bits", "12"}, "unsupported bit depth"},
+		{[]string{input, "out.ulaw", "--bits", "16"}, "always 8-bit"},
+		{[]string{input, "out.wav", "--normalize", "loud"}, "invalid loudness target"},
+		{[]string{input, "out.wav", "--trim-threshold", "-40"}, "require --trim-silence"},
+		{[]string{input, "out.wav", "--pad-start", "-1s"}, "must not be negative"},
+		{[]string{input, "out.wav", "--input-rate", "8000"}, "--input-rate and --input-channels only apply"},
+		{[]string{filepath.Join(tmpDir, "missing.wav"), "out.wav"}, "no such file"},
+	}
+	for _, tt := range tests {
+		cmd := NewConvertCmd()
+		cmd.SetArgs(tt.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
+
+func TestConvert_Normalize(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.wav")
+	if err := os.WriteFile(input, testhelpers.MakeToneWAV(24000, 440, 0.05), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	JSONOutput = true
+	defer func() { JSONOutput = false }()
+	cmd := NewConvertCmd()
+	cmd.SetArgs([]string{input, filepath.Join(tmpDir, "out.wav"), "--normalize", "-16LUFS"})
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+
+	out, _ := io.ReadAll(r)
+	var res convertResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if res.Loudness == nil {
+		t.Fatalf("expected loudness in the result: %s", out)
+	}
+	if got := float64(res.Loudness.Output.Integrated); got < -16.2 || got > -15.8 {
+		t.Errorf("output loudness = %.2f LUFS, want -16", got)
+	}
+}
+
+func TestConvert_TrimSilence(t *testing.T) {
+	tmpDir := t.TempDir()
+	// Silence with a tone from 250 ms to 750 ms.
+	wav := testhelpers.MakeValidWAV(24000)
+	tone := testhelpers.MakeToneWAV(12000, 440, 0.5)
+	copy(wav[44+2*6000:], tone[44:])
+	input := filepath.Join(tmpDir, "in.wav")
+	if err := os.WriteFile(input, wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tmpDir, "out.wav")
+
+	Quiet = true
+	defer func() { Quiet = false }()
+	cmd := NewConvertCmd()
+	cmd.SetArgs([]string{input, output, "--trim-silence", "--pad-start", "100ms"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("output not written: %v", err)
+	}
+	if size := binary.LittleEndian.Uint32(data[40:44]); size != 2*(2400+12000) {
+		t.Errorf("data size = %d, want %d", size, 2*(2400+12000))
+	}
+}
@@ -192,6 +192,7 @@ Or provide your own text:
 	cmd.Flags().StringVar(&apiURL, "api-url", "", "API URL (default: $RIME_API_URL or https://users.rime.ai/v1/rime-tts)")
 
 	modelParams.register(cmd.Flags())
+	registerTTSCompletions(cmd, "model-id")
 
 	return cmd
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+)
+
+func NewDevicesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "devices",
+		Short: "List audio output devices",
+		Long: `Lists the audio output devices tts, play and hello can play through.
+
+Choose one with --device NAME, or for every command with output_device in the
+config file. The null device discards audio in real time, so playback can be
+exercised without sound hardware, as in CI.
+
+Outputs can only be chosen on Linux; elsewhere the system default is listed.
+Headless builds list only the null device.`,
+		Example: `  rime devices
+  rime play --device null prompt.wav`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			devices, err := playback.ListDevices()
+			if err != nil {
+				return err
+			}
+
+			if JSONOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(devices)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\t%s\t%s\n", "NAME", "DESCRIPTION", "DEFAULT")
+			for _, d := range devices {
+				def := ""
+				if d.Default {
+					def = "yes"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", d.Name, d.Description, def)
+			}
+			return w.Flush()
+		},
+	}
+}
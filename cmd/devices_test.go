@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDevices_ListsNullDevice(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+
+	cmd := NewDevicesCmd()
+	cmd.SetArgs(nil)
+	runErr := cmd.Execute()
+
+	w.Close()
+	os.Stdout = old
+	if runErr != nil {
+		t.Fatalf("devices failed: %v", runErr)
+	}
+	out, _ := io.ReadAll(r)
+	if !strings.Contains(string(out), "null") {
+		t.Errorf("devices output should list the null device on every platform and build:\n%s", out)
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+// Process exit codes, so scripts can tell failure types apart.
+const (
+	ExitError          = 1
+	ExitAuth           = 2
+	ExitRateLimited    = 3
+	ExitInvalidRequest = 4
+	ExitEmptyResponse  = 5
+	ExitAPIError       = 6
+	// ExitInterrupted follows the shell convention of 128 + SIGINT.
+	ExitInterrupted = 130
+)
+
+// ExitCode returns the process exit code for an error returned by a command.
+func ExitCode(err error) int {
+	var apiErr *api.APIError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, api.ErrUnauthorized):
+		return ExitAuth
+	case errors.Is(err, api.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, api.ErrInvalidRequest):
+		return ExitInvalidRequest
+	case errors.Is(err, api.ErrEmptyResponse):
+		return ExitEmptyResponse
+	case errors.As(err, &apiErr):
+		return ExitAPIError
+	default:
+		return ExitError
+	}
+}
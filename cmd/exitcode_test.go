@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"generic", errors.New("boom"), ExitError},
+		{"auth", &api.APIError{StatusCode: http.StatusUnauthorized}, ExitAuth},
+		{"rate limited", fmt.Errorf("chunk 2 of 3: %w", &api.APIError{StatusCode: http.StatusTooManyRequests}), ExitRateLimited},
+		{"invalid request", &api.APIError{StatusCode: http.StatusBadRequest}, ExitInvalidRequest},
+		{"empty response", fmt.Errorf("invalid request: %w", api.ErrEmptyResponse), ExitEmptyResponse},
+		{"server error", &api.APIError{StatusCode: http.StatusBadGateway}, ExitAPIError},
+		{"interrupted", fmt.Errorf("interrupted: %w", context.Canceled), ExitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: ExitCode() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
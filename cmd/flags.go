@@ -1,7 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+	"github.com/rimelabs/rime-cli/internal/config"
+	"github.com/rimelabs/rime-cli/internal/tts"
 	"github.com/spf13/pflag"
 )
 
@@ -78,3 +88,146 @@ func (f *modelParamFlags) applyChanged(flags *pflag.FlagSet, opts *api.TTSOption
 		opts.SaveOovs = &f.SaveOovs
 	}
 }
+
+// rawFormatFlags say how to read headerless PCM, μ-law or A-law audio,
+// which can't be recognized from the file itself. Commands that also
+// describe their output set prefix, e.g. "input-" for --input-format.
+type rawFormatFlags struct {
+	prefix   string
+	format   string
+	rate     int
+	channels int
+}
+
+func (f *rawFormatFlags) register(flags *pflag.FlagSet) {
+	shorthand := ""
+	if f.prefix == "" {
+		shorthand = "f"
+	}
+	flags.StringVarP(&f.format, f.prefix+"format", shorthand, "", "Audio format: wav, mp3, pcm, mulaw or alaw (default: detected from the file)")
+	flags.IntVar(&f.rate, f.prefix+"rate", 0, "Sample rate of pcm, mulaw or alaw audio (default: 24000 for pcm, 8000 otherwise)")
+	flags.IntVar(&f.channels, f.prefix+"channels", 0, "Channel count of pcm, mulaw or alaw audio (default: 1)")
+}
+
+// contentType returns the content type to read path as, or "" to detect it
+// from the file. Without --format, a headerless extension such as .ulaw
+// selects the encoding.
+func (f *rawFormatFlags) contentType(path string) (string, error) {
+	if f.rate < 0 || f.channels < 0 {
+		return "", fmt.Errorf("--%srate and --%schannels must not be negative", f.prefix, f.prefix)
+	}
+
+	var raw codec.Format
+	var isRaw bool
+	switch f.format {
+	case "":
+		raw, isRaw = codec.ParseContentType(detectformat.FromExtension(path))
+	case "wav", "mp3":
+	default:
+		enc, ok := codec.ParseEncoding(f.format)
+		if !ok {
+			return "", fmt.Errorf("unsupported format: %s (supported: wav, mp3, pcm, mulaw, alaw)", f.format)
+		}
+		raw, isRaw = codec.Format{Encoding: enc, SampleRate: enc.DefaultRate(), NumChannels: 1}, true
+	}
+
+	if !isRaw {
+		if f.rate != 0 || f.channels != 0 {
+			return "", fmt.Errorf("--%srate and --%schannels only apply to pcm, mulaw and alaw audio", f.prefix, f.prefix)
+		}
+		if f.format == "" {
+			return "", nil
+		}
+		return "audio/" + f.format, nil
+	}
+	if f.rate > 0 {
+		raw.SampleRate = f.rate
+	}
+	if f.channels > 0 {
+		raw.NumChannels = f.channels
+	}
+	return raw.ContentType(), nil
+}
+
+// editFlags describe changes made to audio once all of it is at hand:
+// loudness normalization, and trimming and padding silence.
+type editFlags struct {
+	normalize       string
+	trimSilence     bool
+	trimThreshold   float64
+	trimMinDuration time.Duration
+	padStart        time.Duration
+	padEnd          time.Duration
+}
+
+func (f *editFlags) register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.normalize, "normalize", "", "Normalize loudness to a target, e.g. -16LUFS (EBU R128)")
+	flags.BoolVar(&f.trimSilence, "trim-silence", false, "Trim silence from the start and end of the audio")
+	flags.Float64Var(&f.trimThreshold, "trim-threshold", -50, "Level in dBFS at or below which audio counts as silence (with --trim-silence)")
+	flags.DurationVar(&f.trimMinDuration, "trim-min-duration", 100*time.Millisecond, "Shortest silence to trim; shorter pauses are kept (with --trim-silence)")
+	flags.DurationVar(&f.padStart, "pad-start", 0, "Silence to add before the audio, e.g. 200ms")
+	flags.DurationVar(&f.padEnd, "pad-end", 0, "Silence to add after the audio, e.g. 200ms")
+}
+
+func (f *editFlags) edits(flags *pflag.FlagSet) (tts.Edits, error) {
+	var edits tts.Edits
+	if f.normalize != "" {
+		target, err := loudness.ParseTarget(f.normalize)
+		if err != nil {
+			return edits, err
+		}
+		edits.Normalize = &target
+	}
+	if f.trimSilence {
+		if f.trimThreshold >= 0 {
+			return edits, fmt.Errorf("--trim-threshold must be below 0 dBFS, got %g", f.trimThreshold)
+		}
+		if f.trimMinDuration < 0 {
+			return edits, fmt.Errorf("--trim-min-duration must not be negative, got %s", f.trimMinDuration)
+		}
+		edits.TrimSilence = &convert.TrimOptions{Threshold: f.trimThreshold, MinDuration: f.trimMinDuration}
+	} else if flags.Changed("trim-threshold") || flags.Changed("trim-min-duration") {
+		return edits, fmt.Errorf("--trim-threshold and --trim-min-duration require --trim-silence")
+	}
+	if f.padStart < 0 || f.padEnd < 0 {
+		return edits, fmt.Errorf("--pad-start and --pad-end must not be negative")
+	}
+	edits.PadStart, edits.PadEnd = f.padStart, f.padEnd
+	return edits, nil
+}
+
+// outputDeviceFlags choose the audio output device commands play through.
+type outputDeviceFlags struct {
+	device string
+}
+
+func (f *outputDeviceFlags) register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.device, "device", "", "Audio output device to play through, as listed by 'rime devices' (default: output_device from the config, else the system default)")
+}
+
+// apply selects --device, or without it the config's output_device. The
+// config is only read in builds that can play audio, so a key left in it
+// doesn't break headless builds.
+func (f *outputDeviceFlags) apply(flags *pflag.FlagSet) error {
+	name := f.device
+	if !flags.Changed("device") {
+		if !playback.IsPlaybackEnabled() {
+			return nil
+		}
+		var cfg *config.Config
+		var err error
+		if ConfigFile != "" {
+			cfg, err = config.LoadConfigFromPath(ConfigFile)
+		} else {
+			cfg, err = config.LoadConfig()
+		}
+		if err != nil {
+			return err
+		}
+		if cfg == nil || cfg.OutputDevice == "" {
+			return nil
+		}
+		name = cfg.OutputDevice
+	}
+	return playback.SelectDevice(name)
+}
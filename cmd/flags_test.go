@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+)
+
+func TestRawFormatFlags_ContentType(t *testing.T) {
+	tests := []struct {
+		flags   rawFormatFlags
+		path    string
+		want    string
+		wantErr string
+	}{
+		{rawFormatFlags{}, "clip.wav", "", ""},
+		{rawFormatFlags{format: "mp3"}, "clip", "audio/mp3", ""},
+		{rawFormatFlags{}, "prompt.ulaw", "audio/x-mulaw; channels=1; rate=8000", ""},
+		{rawFormatFlags{rate: 16000}, "prompt.alaw", "audio/x-alaw; channels=1; rate=16000", ""},
+		{rawFormatFlags{format: "pcm", rate: 48000, channels: 2}, "clip.bin", "audio/pcm; channels=2; rate=48000", ""},
+		{rawFormatFlags{format: "ogg"}, "clip.ogg", "", "unsupported format"},
+		{rawFormatFlags{rate: 8000}, "clip.wav", "", "only apply to pcm"},
+		{rawFormatFlags{format: "pcm", channels: -1}, "clip.pcm", "", "must not be negative"},
+	}
+	for _, tt := range tests {
+		got, err := tt.flags.contentType(tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%+v %s: expected error containing %q, got %v", tt.flags, tt.path, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%+v %s: got %q, %v; want %q", tt.flags, tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestOutputDeviceFlags_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rime.toml")
+	if err := os.WriteFile(path, []byte("api_key = \"test-key\"\noutput_device = \"null\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	oldConfig := ConfigFile
+	ConfigFile = path
+	t.Cleanup(func() {
+		ConfigFile = oldConfig
+		playback.SelectDevice("")
+	})
+
+	// In headless builds the config key is ignored.
+	wantConfig := ""
+	if playback.IsPlaybackEnabled() {
+		wantConfig = playback.NullDevice
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"config key", nil, wantConfig, false},
+		{"flag wins", []string{"--device", "default"}, "", false},
+		{"null flag", []string{"--device", "null"}, playback.NullDevice, false},
+		{"unknown device", []string{"--device", "no-such-device"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playback.SelectDevice("")
+			var f outputDeviceFlags
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			f.register(flags)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := f.apply(flags)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("apply should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+			if got := playback.SelectedDevice(); got != tt.want {
+				t.Errorf("selected device = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"golang.org/x/term"
 
 	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
 	"github.com/rimelabs/rime-cli/internal/output/styles"
 	"github.com/rimelabs/rime-cli/internal/output/ui"
 	"github.com/rimelabs/rime-cli/internal/tts"
@@ -30,6 +31,7 @@ func getGreeting() string {
 func NewHelloCmd() *cobra.Command {
 	var output string
 	var apiURL string
+	var outputDevice outputDeviceFlags
 
 	cmd := &cobra.Command{
 		Use:   "hello",
@@ -37,6 +39,9 @@ func NewHelloCmd() *cobra.Command {
 		Long:  "Plays a quick TTS demo using the Vespera voice",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := outputDevice.apply(cmd.Flags()); err != nil {
+				return err
+			}
 			text := fmt.Sprintf("good %s, this is Vespera speaking", getGreeting())
 
 			opts := &api.TTSOptions{
@@ -45,7 +50,11 @@ func NewHelloCmd() *cobra.Command {
 				Lang:    "eng",
 			}
 
-			shouldPlay := output == ""
+			// Headless builds only play through the null device.
+			shouldPlay := output == "" && playback.CanPlay()
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
 
 			if Quiet || JSONOutput || !term.IsTerminal(int(os.Stdout.Fd())) {
 				runOpts := tts.RunOptions{
@@ -60,14 +69,14 @@ func NewHelloCmd() *cobra.Command {
 					ConfigEnv:  ConfigEnv,
 					ConfigFile: ConfigFile,
 				}
-				return tts.RunNonInteractive(runOpts)
+				return tts.RunNonInteractive(ctx, runOpts)
 			}
 
 			if shouldPlay {
 				fmt.Fprintln(os.Stderr, styles.Dim("Playing audio (use -o to save)"))
 			}
 
-			p := tea.NewProgram(ui.NewTTSModel(text, opts, output, shouldPlay, Version, apiURL, ConfigEnv, ConfigFile, false))
+			p := tea.NewProgram(ui.NewTTSModel(ctx, text, opts, output, shouldPlay, Version, apiURL, ConfigEnv, ConfigFile, nil, 0, nil, false))
 			m, err := p.Run()
 			if err != nil {
 				return err
@@ -84,6 +93,7 @@ func NewHelloCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file path (plays by default)")
 	cmd.Flags().StringVar(&apiURL, "api-url", "", "API URL (default: $RIME_API_URL or https://users.rime.ai/v1/rime-tts)")
+	outputDevice.register(cmd.Flags())
 
 	return cmd
 }
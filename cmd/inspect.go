@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/output/formatters"
+)
+
+// inspectResult is the --json output of rime inspect.
+type inspectResult struct {
+	File        string `json:"file"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int    `json:"size_bytes"`
+	// HeaderDurationMs is the duration the WAV header, the MP3 Xing, Info
+	// or VBRI header, or the MP3 frame count gives. DecodedDurationMs is
+	// the length of the audio once decoded.
+	HeaderDurationMs  int64           `json:"header_duration_ms"`
+	DecodedDurationMs int64           `json:"decoded_duration_ms"`
+	Levels            *analyze.Levels `json:"levels,omitempty"`
+	// DecodeError is set when the audio could not be decoded, in which
+	// case there are no levels.
+	DecodeError string             `json:"decode_error,omitempty"`
+	WAV         *metadata.WavInfo  `json:"wav,omitempty"`
+	ID3         *metadata.ID3Tag   `json:"id3,omitempty"`
+	MP3         *metadata.MP3Stats `json:"mp3,omitempty"`
+}
+
+func NewInspectCmd() *cobra.Command {
+	var inputFormat rawFormatFlags
+
+	cmd := &cobra.Command{
+		Use:   "inspect FILE",
+		Short: "Show the structure, duration and levels of an audio file",
+		Long: `Show detailed diagnostics for a WAV, MP3 or headerless audio file.
+
+For WAV files, inspect lists the chunks with their offsets and sizes, the fmt
+chunk, and whether the header still has the placeholder sizes of a streaming
+response. For MP3 files, it lists the ID3 frames and summarizes the MPEG
+frames: bitrates, whether the stream is VBR, and any Xing, Info or VBRI header.
+
+The duration the header gives is shown next to the duration of the decoded
+audio, so truncated or mislabeled files stand out, along with the peak and RMS
+levels, loudness (EBU R128), the number of clipped samples and the DC offset:
+  rime inspect prompt.wav
+  rime inspect prompt.mp3 --json
+
+Headerless input is recognized by its extension or by --format, with --rate
+and --channels giving its sample rate and channel count.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			contentType, err := inputFormat.contentType(path)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if contentType == "" {
+				contentType = detectformat.DetectFile(path, data)
+				if contentType == "" {
+					return fmt.Errorf("unrecognized audio format: %s (use --format)", path)
+				}
+			}
+
+			res, err := inspect(path, data, contentType)
+			if err != nil {
+				return err
+			}
+			if JSONOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(res)
+			}
+			return writeInspect(os.Stdout, res)
+		},
+	}
+
+	inputFormat.register(cmd.Flags())
+
+	return cmd
+}
+
+// inspect examines data, audio of contentType read from path.
+func inspect(path string, data []byte, contentType string) (*inspectResult, error) {
+	res := &inspectResult{File: path, ContentType: contentType, SizeBytes: len(data)}
+	decodeData := data
+
+	switch {
+	case codec.IsRaw(contentType):
+		f, _ := codec.ParseContentType(contentType)
+		res.HeaderDurationMs = f.Duration(len(data)).Milliseconds()
+	case contentType == "audio/wav":
+		info, ok := metadata.InspectWav(data)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a WAV file", path)
+		}
+		res.WAV = info
+		res.HeaderDurationMs = info.Duration().Milliseconds()
+		if info.Placeholder {
+			decodeData = metadata.FixWavHeader(data)
+		}
+	default:
+		if tag, ok := metadata.ReadID3Tag(data); ok {
+			res.ID3 = tag
+		}
+		stats, ok := metadata.ScanMP3(data)
+		if !ok {
+			return nil, fmt.Errorf("%s has no MP3 frames", path)
+		}
+		res.MP3 = &stats
+		res.HeaderDurationMs = stats.Duration.Milliseconds()
+		if stats.InfoFrames > 0 {
+			res.HeaderDurationMs = stats.InfoDuration.Milliseconds()
+		}
+	}
+
+	levels, err := analyze.AnalyzeLevels(bytes.NewReader(decodeData), contentType)
+	if err != nil {
+		res.DecodeError = err.Error()
+		return res, nil
+	}
+	res.Levels = levels
+	res.DecodedDurationMs = levels.Duration.Milliseconds()
+	return res, nil
+}
+
+// writeInspect writes res as text.
+func writeInspect(out io.Writer, res *inspectResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	ms := func(n int64) string {
+		return (time.Duration(n) * time.Millisecond).String()
+	}
+
+	fmt.Fprintf(w, "File:\t%s (%s)\n", res.File, formatters.FormatBytes(res.SizeBytes))
+	fmt.Fprintf(w, "Format:\t%s\n", describeInspected(res))
+	fmt.Fprintf(w, "Duration:\t%s header, %s decoded\n", ms(res.HeaderDurationMs), ms(res.DecodedDurationMs))
+	if res.DecodeError != "" {
+		fmt.Fprintf(w, "Levels:\tcould not decode: %s\n", res.DecodeError)
+	} else if l := res.Levels; l != nil {
+		fmt.Fprintf(w, "Levels:\tpeak %s dBFS, RMS %s dBFS\n", l.Peak, l.RMS)
+		fmt.Fprintf(w, "Loudness:\t%s LUFS integrated, %s LU range, %s dBTP true peak\n",
+			l.Loudness.Integrated, l.Loudness.Range, l.Loudness.TruePeak)
+		fmt.Fprintf(w, "Clipping:\t%d samples\n", l.Clipped)
+		fmt.Fprintf(w, "DC offset:\t%.5f\n", l.DCOffset)
+	}
+
+	if info := res.WAV; info != nil {
+		fmt.Fprintf(w, "\nWAV chunks:\n")
+		for _, c := range info.Chunks {
+			fmt.Fprintf(w, "  %q\toffset %d\tsize %d\n", c.ID, c.Offset, c.Size)
+		}
+		header := "sizes match the file"
+		switch {
+		case info.Placeholder:
+			header = "placeholder sizes from a streaming response, not fixed"
+		case !info.SizesMatch:
+			header = "sizes don't match the file length"
+		}
+		fmt.Fprintf(w, "Header:\t%s\n", header)
+		if m := info.Metadata; m != (metadata.WavMetadata{}) {
+			fmt.Fprintf(w, "\nMetadata:\n")
+			fmt.Fprintf(w, "  Artist:\t%s\n", m.Artist)
+			fmt.Fprintf(w, "  Name:\t%s\n", m.Name)
+			fmt.Fprintf(w, "  Comment:\t%s\n", m.Comment)
+		}
+	}
+
+	if tag := res.ID3; tag != nil {
+		if tag.Version != "" {
+			fmt.Fprintf(w, "\nID3v%s tag (%d bytes):\n", tag.Version, tag.Size)
+			for _, f := range tag.Frames {
+				fmt.Fprintf(w, "  %s\t%s\n", f.ID, f.Value)
+			}
+		}
+		if tag.V1 {
+			fmt.Fprintf(w, "\nID3v1 tag at end of file\n")
+		}
+	}
+
+	if s := res.MP3; s != nil {
+		fmt.Fprintf(w, "\nMP3 frames:\n")
+		fmt.Fprintf(w, "  Frames:\t%d\n", s.Frames)
+		if s.MinBitrate == s.MaxBitrate {
+			fmt.Fprintf(w, "  Bitrate:\t%d kbps\n", s.AvgBitrate)
+		} else {
+			fmt.Fprintf(w, "  Bitrate:\t%d-%d kbps, %d average\n", s.MinBitrate, s.MaxBitrate, s.AvgBitrate)
+		}
+		mode := "CBR"
+		if s.VBR {
+			mode = "VBR"
+		}
+		fmt.Fprintf(w, "  Mode:\t%s\n", mode)
+		if s.InfoTag != "" {
+			fmt.Fprintf(w, "  %s header:\t%d frames\n", s.InfoTag, s.InfoFrames)
+		} else {
+			fmt.Fprintf(w, "  Info header:\tnone\n")
+		}
+		if s.Junk > 0 {
+			fmt.Fprintf(w, "  Junk:\t%d bytes between frames\n", s.Junk)
+		}
+	}
+
+	return w.Flush()
+}
+
+// describeInspected describes the sample format of res, e.g. "audio/wav,
+// 24000 Hz mono, 16-bit PCM".
+func describeInspected(res *inspectResult) string {
+	rate, channels := 0, 0
+	detail := ""
+	switch {
+	case res.WAV != nil && res.WAV.Format != nil:
+		f := res.WAV.Format
+		rate, channels = f.SampleRate, f.Channels
+		detail = fmt.Sprintf(", %d-bit %s", f.BitsPerSample, wavFormatName(f.AudioFormat))
+		if f.AudioFormat == 0xFFFE && f.SubFormat != 0 {
+			detail = fmt.Sprintf(", %d-bit %s (extensible)", f.BitsPerSample, wavFormatName(f.SubFormat))
+		}
+	case res.MP3 != nil:
+		rate, channels = res.MP3.SampleRate, res.MP3.Channels
+		detail = ", " + res.MP3.Version
+	case res.Levels != nil:
+		rate, channels = res.Levels.SampleRate, res.Levels.Channels
+	}
+	if rate == 0 {
+		return res.ContentType
+	}
+	layout := fmt.Sprintf("%d-channel", channels)
+	switch channels {
+	case 1:
+		layout = "mono"
+	case 2:
+		layout = "stereo"
+	}
+	return fmt.Sprintf("%s, %d Hz %s%s", res.ContentType, rate, layout, detail)
+}
+
+// wavFormatName names a WAV fmt chunk's audio format tag.
+func wavFormatName(tag uint16) string {
+	switch tag {
+	case 1:
+		return "PCM"
+	case 3:
+		return "float"
+	case 6:
+		return "A-law"
+	case 7:
+		return "μ-law"
+	case 0xFFFE:
+		return "extensible"
+	}
+	return fmt.Sprintf("format 0x%04X", tag)
+}
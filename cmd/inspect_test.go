@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+// runInspect runs rime inspect with args and returns what it wrote to
+// stdout.
+func runInspect(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := NewInspectCmd()
+	cmd.SetArgs(args)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestInspect_WAVJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	data := metadata.EmbedMetadata(testhelpers.MakeToneWAV(2400, 440, 0.5), metadata.WavMetadata{Comment: "[astra-arcana-eng]: Hello"})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	JSONOutput = true
+	defer func() { JSONOutput = false }()
+	out := runInspect(t, path)
+
+	var res inspectResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if res.ContentType != "audio/wav" || res.SizeBytes != len(data) {
+		t.Errorf("content type, size = %q, %d", res.ContentType, res.SizeBytes)
+	}
+	if res.HeaderDurationMs != 100 || res.DecodedDurationMs != 100 {
+		t.Errorf("durations = %d ms header, %d ms decoded; want 100, 100", res.HeaderDurationMs, res.DecodedDurationMs)
+	}
+	if res.WAV == nil || len(res.WAV.Chunks) != 3 || res.WAV.Placeholder || !res.WAV.SizesMatch {
+		t.Fatalf("wav = %+v", res.WAV)
+	}
+	if res.WAV.Metadata.Comment != "[astra-arcana-eng]: Hello" {
+		t.Errorf("comment = %q", res.WAV.Metadata.Comment)
+	}
+	if res.Levels == nil || res.Levels.Peak > -5 || res.Levels.Peak < -7 {
+		t.Errorf("levels = %+v, want a peak near -6 dBFS", res.Levels)
+	}
+}
+
+func TestInspect_MP3Text(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp3")
+	data, err := metadata.EmbedMP3Metadata(testhelpers.MakeMinimalMP3(), metadata.MP3Metadata{Artist: "Rime AI TTS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runInspect(t, path)
+	for _, want := range []string{"audio/mp3, 44100 Hz stereo, MPEG-1", "ID3v2.3 tag", "TPE1", "Rime AI TTS", "Bitrate:", "128 kbps", "CBR"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestInspect_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	unknown := filepath.Join(tmpDir, "notes.txt")
+	os.WriteFile(unknown, []byte("not audio"), 0644)
+	wav := filepath.Join(tmpDir, "a.wav")
+	os.WriteFile(wav, testhelpers.MakeValidWAV(100), 0644)
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{}, "accepts 1 arg(s)"},
+		{[]string{unknown}, "unrecognized audio format"},
+		{[]string{wav, "--rate", "8000"}, "only apply to pcm, mulaw and alaw"},
+		{[]string{filepath.Join(tmpDir, "missing.wav")}, "no such file"},
+	}
+	for _, tt := range tests {
+		cmd := NewInspectCmd()
+		cmd.SetArgs(tt.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
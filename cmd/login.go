@@ -1,9 +1,8 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"strings"
-
 	"os"
 
 	"github.com/spf13/cobra"
@@ -16,11 +15,6 @@ import (
 	"github.com/rimelabs/rime-cli/internal/output/ui"
 )
 
-func isAuthError(err error) bool {
-	return strings.Contains(err.Error(), "authentication failed") ||
-		strings.Contains(err.Error(), "invalid API key")
-}
-
 func NewLoginCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "login",
@@ -46,7 +40,7 @@ func NewLoginCmd() *cobra.Command {
 			})
 			if err := client.ValidateAPIKey(); err != nil {
 				// 401 means the key itself is bad — don't save it
-				if isAuthError(err) {
+				if errors.Is(err, api.ErrUnauthorized) {
 					return fmt.Errorf("API key appears to be invalid: %w", err)
 				}
 				// Network or other transient error — save the key and warn
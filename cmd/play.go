@@ -5,7 +5,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
@@ -16,33 +15,102 @@ import (
 )
 
 func NewPlayCmd() *cobra.Command {
+	var rawFormat rawFormatFlags
+	var shuffle, loop bool
+	var speed float64
+	var outputDevice outputDeviceFlags
+
 	cmd := &cobra.Command{
-		Use:   "play FILE",
-		Short: "Play a WAV file",
-		Long:  "Play a WAV audio file with waveform visualization",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			fpath := args[0]
+		Use:   "play FILE...",
+		Short: "Play WAV or MP3 files",
+		Long: `Play WAV or MP3 audio files with waveform visualization.
 
-			if _, err := os.Stat(fpath); err != nil {
-				if !os.IsNotExist(err) {
-					return err
-				}
-				absPath, absErr := filepath.Abs(fpath)
-				if absErr != nil {
-					return fmt.Errorf("file not found: %s", fpath)
+Several files, or a directory of them, play one after another, with a list
+showing each file's speaker, model and language, so a batch of generated
+prompts can be auditioned in one go:
+  rime play prompts/
+  rime play --shuffle --loop *.wav
+
+--speed plays faster or slower, from 0.5 to 2 times normal speed, without
+changing the pitch, so long narrations can be skimmed:
+  rime play --speed 1.5 narration.wav
+
+While it plays, space pauses and resumes, the left and right arrows seek 5
+seconds back and forward, r restarts, + and - change the volume and [ and ]
+the speed. With several files, n and p move to the next and previous file,
+s turns shuffle on and off and l loop.
+
+"-" plays audio piped to stdin and an HTTP or HTTPS URL plays a remote file,
+both as they arrive rather than once fully read, with the format recognized
+from the first bytes:
+  rime tts "Your balance is ready." -o - | rime play -
+  rime play https://example.com/clip.mp3
+Audio played this way can't seek.
+
+--device plays through an output other than the system default, as listed
+by 'rime devices':
+  rime play --device alsa_output.usb-headset.analog-stereo prompt.wav
+
+Headerless PCM, μ-law and A-law files are recognized by their extension
+(.pcm, .raw, .ulaw, .mulaw, .alaw) or by --format. Since they carry no header,
+give their sample rate and channel count with --rate and --channels:
+  rime play prompt.ulaw
+  rime play --format pcm --rate 16000 clip.raw`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if speed < playback.MinSpeed || speed > playback.MaxSpeed {
+				return fmt.Errorf("--speed must be between %g and %g", playback.MinSpeed, playback.MaxSpeed)
+			}
+			if err := outputDevice.apply(cmd.Flags()); err != nil {
+				return err
+			}
+			paths, err := playback.ExpandPaths(args)
+			if err != nil {
+				return err
+			}
+			stdin := false
+			tracks := make([]playback.Track, len(paths))
+			for i, path := range paths {
+				if path == "-" {
+					stdin = true
 				}
-				if _, err2 := os.Stat(absPath); err2 != nil {
-					return fmt.Errorf("file not found: %s", fpath)
+				contentType, err := rawFormat.contentType(path)
+				if err != nil {
+					return err
 				}
-				fpath = absPath
+				tracks[i] = playback.NewTrack(path, contentType)
+			}
+			if stdin && loop {
+				return fmt.Errorf("--loop can't replay stdin")
+			}
+			playlist := playback.NewPlaylist(tracks)
+			playlist.Loop = loop
+			if shuffle {
+				playlist.SetShuffle(true)
 			}
 
 			if Quiet || !term.IsTerminal(int(os.Stdout.Fd())) {
-				return playback.RunNonInteractivePlay(fpath)
+				for {
+					track := playlist.Track()
+					if err := playback.RunNonInteractivePlayFormat(track.Path, track.ContentType, speed); err != nil {
+						if len(tracks) > 1 {
+							return fmt.Errorf("%s: %w", track.Path, err)
+						}
+						return err
+					}
+					if !playlist.Next() {
+						return nil
+					}
+				}
 			}
 
-			p := tea.NewProgram(ui.NewPlayModel(fpath))
+			var opts []tea.ProgramOption
+			if stdin {
+				// Stdin carries the audio, so keys are read from the
+				// terminal instead.
+				opts = append(opts, tea.WithInputTTY())
+			}
+			p := tea.NewProgram(ui.NewPlaylistModel(playlist, speed), opts...)
 			m, err := p.Run()
 			if err != nil {
 				return err
@@ -57,5 +125,11 @@ func NewPlayCmd() *cobra.Command {
 		},
 	}
 
+	rawFormat.register(cmd.Flags())
+	cmd.Flags().BoolVar(&shuffle, "shuffle", false, "Play the files in random order")
+	cmd.Flags().BoolVar(&loop, "loop", false, "Start over after the last file")
+	cmd.Flags().Float64Var(&speed, "speed", 1, "Playback speed, from 0.5 to 2, keeping the pitch")
+	outputDevice.register(cmd.Flags())
+
 	return cmd
 }
@@ -19,9 +19,19 @@ var ConfigFile string
 func NewRootCmd(version string) *cobra.Command {
 	Version = version
 	root := &cobra.Command{
-		Use:           "rime",
-		Short:         "Rime TTS CLI",
-		Long:          "Command-line interface for Rime text-to-speech synthesis",
+		Use:   "rime",
+		Short: "Rime TTS CLI",
+		Long: `Command-line interface for Rime text-to-speech synthesis
+
+Exit codes:
+  0  success
+  1  general error
+  2  authentication failed
+  3  rate limited
+  4  invalid request
+  5  empty response (check speaker, language and model)
+  6  other API error
+  130  interrupted (Ctrl+C)`,
 		Version:       version,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -43,12 +53,22 @@ func NewRootCmd(version string) *cobra.Command {
 	root.AddCommand(NewCurlCmd())
 	root.AddCommand(NewKeyCmd())
 	root.AddCommand(NewTTSCmd())
+	root.AddCommand(NewBatchCmd())
 	root.AddCommand(NewHelloCmd())
 	root.AddCommand(NewPlayCmd())
+	root.AddCommand(NewDevicesCmd())
+	root.AddCommand(NewConvertCmd())
+	root.AddCommand(NewConcatCmd())
+	root.AddCommand(NewInspectCmd())
 	root.AddCommand(NewUninstallCmd())
 	root.AddCommand(NewConfigCmd())
 	root.AddCommand(NewSpeedtestCmd())
 	root.AddCommand(NewUsageCmd())
+	root.AddCommand(NewVoicesCmd())
+	root.AddCommand(NewCacheCmd())
+	addCompletionInstallCmd(root)
+
+	root.RegisterFlagCompletionFunc("env", completeEnvironments)
 
 	return root
 }
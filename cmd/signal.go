@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// signalContext returns a context derived from cmd's that is cancelled on
+// SIGINT or SIGTERM, so long-running commands can stop early and clean up.
+// Once it is cancelled the default signal behaviour is restored, so a second
+// Ctrl+C terminates the process straight away.
+func signalContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -32,6 +32,7 @@ func NewSpeedtestCmd() *cobra.Command {
 	var modelParams modelParamFlags
 	var runs int
 	var timeout time.Duration
+	var retries int
 
 	cmd := &cobra.Command{
 		Use:   "speedtest",
@@ -113,6 +114,12 @@ func NewSpeedtestCmd() *cobra.Command {
 			if runs < 1 {
 				return fmt.Errorf("--runs must be at least 1")
 			}
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative, got %d", retries)
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
 
 			ttfbHeader := "TTFB"
 			if runs > 1 {
@@ -124,6 +131,9 @@ func NewSpeedtestCmd() *cobra.Command {
 			}
 
 			for _, entry := range entries {
+				if ctx.Err() != nil {
+					break
+				}
 				if entry.err != nil {
 					result := SpeedtestResult{
 						Environment: entry.name,
@@ -143,12 +153,13 @@ func NewSpeedtestCmd() *cobra.Command {
 					AuthHeaderPrefix: getAuthPrefix(env),
 					Version:          Version,
 					Timeout:          timeout,
+					Retry:            api.DefaultRetryPolicy(retries),
 				})
 
 				var ttfbs []time.Duration
 				var lastErr error
-				for i := 0; i < runs; i++ {
-					streamResult, err := client.TTSStream(text, opts)
+				for i := 0; i < runs && ctx.Err() == nil; i++ {
+					streamResult, err := client.TTSStreamContext(ctx, text, opts)
 					if err != nil {
 						lastErr = err
 						continue
@@ -157,6 +168,10 @@ func NewSpeedtestCmd() *cobra.Command {
 					ttfbs = append(ttfbs, streamResult.TTFB)
 				}
 
+				if ctx.Err() != nil {
+					break
+				}
+
 				if len(ttfbs) == 0 {
 					result := SpeedtestResult{
 						Environment: entry.name,
@@ -197,6 +212,10 @@ func NewSpeedtestCmd() *cobra.Command {
 				}
 			}
 
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("interrupted: %w", err)
+			}
+
 			if JSONOutput {
 				encoder := json.NewEncoder(os.Stdout)
 				encoder.SetIndent("", "  ")
@@ -220,7 +239,10 @@ func NewSpeedtestCmd() *cobra.Command {
 	cmd.Flags().StringArrayVar(&envFilter, "env", nil, "Only test these named environments from config (repeatable)")
 	cmd.Flags().IntVar(&runs, "runs", 1, "Number of requests per endpoint (reports mean/min/max when >1)")
 	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Per-request timeout (0 disables timeout)")
+	cmd.Flags().IntVar(&retries, "retries", 0, "Retry failed requests (network errors, 429 and 5xx) up to N times")
 	modelParams.register(cmd.Flags())
+	registerTTSCompletions(cmd, "model")
+	cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
 
 	return cmd
 }
@@ -1,20 +1,25 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
 	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
 	"github.com/rimelabs/rime-cli/internal/audio/metadata"
 	"github.com/rimelabs/rime-cli/internal/audio/playback"
-	"github.com/rimelabs/rime-cli/internal/config"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
 	"github.com/rimelabs/rime-cli/internal/output/ui"
 	"github.com/rimelabs/rime-cli/internal/tts"
+	"github.com/rimelabs/rime-cli/internal/voices"
 )
 
 func NewTTSCmd() *cobra.Command {
@@ -25,10 +30,25 @@ func NewTTSCmd() *cobra.Command {
 	var lang string
 	var format string
 	var apiURL string
+	var textFile string
+	var chunk bool
+	var chunkSize int
+	var chunkSilence time.Duration
+	var retries int
 	var modelParams modelParamFlags
+	var cacheOpts cacheFlags
+	var useWS bool
+	var timestamps bool
+	var stdinStream bool
+	var subtitlesPath string
+	var subtitleLineLength int
+	var subtitleMaxDuration time.Duration
+	var resample int
+	var editOpts editFlags
+	var outputDevice outputDeviceFlags
 
 	cmd := &cobra.Command{
-		Use:   "tts TEXT",
+		Use:   "tts [TEXT | -]",
 		Short: "Synthesize text to speech",
 		Long: `Convert text to speech audio.
 
@@ -36,20 +56,103 @@ Supports both WAV and MP3 formats. The format is automatically selected based on
 - mistv2 model outputs MP3 format
 - All other models output WAV format
 
-Use --format to override the default format selection.
+Use --format to override the default format selection. For telephony and
+other raw pipelines, --format pcm, mulaw or alaw writes headerless mono audio:
+16-bit little-endian PCM at 24 kHz, or G.711 μ-law or A-law at 8 kHz, or at
+--sampling-rate if given. The API is asked for the format directly where it
+supports it; otherwise the audio is transcoded locally.
+
+Use --resample for a sample rate the model doesn't offer. The audio is
+synthesized at the model's rate and resampled locally, for WAV and the
+headerless formats:
+  rime tts "Hello" -s astra -m arcana -o hello.wav --resample 11025
+
+Use --normalize to bring the audio to a loudness target such as -16LUFS (EBU
+R128), with true peaks limited to -1 dBTP, so clips from different voices
+play back at the same level. The audio is measured once it has fully
+arrived, so it plays afterwards rather than as it streams. --json output
+includes the loudness before and after.
+
+Use --trim-silence to cut silence from the start and end of the audio, so a
+prompt starts playing straight away, and --pad-start and --pad-end to add a
+set amount of silence back. Silence is audio at or below --trim-threshold
+(-50 dBFS) for at least --trim-min-duration (100ms). MP3 is trimmed by
+decoding it, so it is saved as WAV:
+  rime tts "Please hold." -s astra -m arcana -o hold.wav --trim-silence --pad-start 50ms
+
+Text can be given as an argument, read from stdin with "-", or read from a file
+with --file:
+  cat script.txt | rime tts - -s astra -m arcana -o out.wav
+  rime tts --file script.txt -s astra -m arcana -o out.wav
+
+Use --chunk for long text. It is split at paragraph and sentence boundaries
+into requests of at most --chunk-size characters, which are synthesized in
+order (the next ones prefetched while earlier audio plays) and joined into a
+single WAV or MP3, with optional --chunk-silence between chunks.
+
+Use --timestamps to request word timings with the audio. The transcript is
+then highlighted in step with the actual speech, and --json output includes
+a "words" array with each word's start_ms and end_ms. The audio arrives in
+one piece instead of streaming.
+
+Use --ws to synthesize over a WebSocket connection instead of an HTTP
+request. With --stdin-stream, text is read from stdin line by line and each
+line is sent as soon as it arrives, so output from an LLM or another program
+can be spoken while it is still being written; a blank line flushes the text
+sent so far. Audio plays continuously as it arrives:
+  llm-chat | rime tts --ws --stdin-stream -s astra -m arcana
+
+Use --subtitles to write SRT or WebVTT captions next to the audio, chosen by
+the file extension. Cues follow the word timings with --timestamps; otherwise
+the audio's length is shared out between sentences by their length:
+  rime tts --file script.txt -s astra -m arcana -o clip.wav --subtitles clip.srt
+
+Use --cache (or set RIME_CACHE=1) to answer repeated requests from the local
+response cache; see 'rime cache'.
 
 The CLI handles format detection, metadata embedding, and playback for both formats.`,
-		Args: cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if stdinStream {
+				if len(args) > 0 || cmd.Flags().Changed("file") {
+					return fmt.Errorf("cannot use TEXT or --file with --stdin-stream")
+				}
+				return nil
+			}
+			if cmd.Flags().Changed("file") {
+				if len(args) > 0 {
+					return fmt.Errorf("cannot use both TEXT and --file")
+				}
+				return nil
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			text := args[0]
+			if stdinStream && !useWS {
+				return fmt.Errorf("--stdin-stream requires --ws")
+			}
+			var text string
+			if !stdinStream {
+				var arg string
+				if len(args) > 0 {
+					arg = args[0]
+				}
+				var err error
+				text, err = readTextInput(arg, textFile, os.Stdin)
+				if err != nil {
+					return err
+				}
+			}
 
-			if !playback.IsPlaybackEnabled() {
+			if err := outputDevice.apply(cmd.Flags()); err != nil {
+				return err
+			}
+			if !playback.CanPlay() {
 				if output == "" {
-					return fmt.Errorf("output file required in headless build (use -o FILE or -o - for stdout)")
+					return fmt.Errorf("output file required in headless build (use -o FILE, -o - for stdout or --device null)")
 				}
 			}
 
-			shouldPlay := playback.IsPlaybackEnabled() && (play || output == "")
+			shouldPlay := playback.CanPlay() && (play || output == "")
 
 			if spk == "" {
 				return fmt.Errorf("--speaker is required. You can use --speaker astra")
@@ -63,51 +166,174 @@ The CLI handles format detection, metadata embedding, and playback for both form
 			if !api.IsValidLang(lang, modelId) {
 				return fmt.Errorf("invalid language %q for model %s (valid: %s)", lang, modelId, strings.Join(api.ValidLangsForModel(modelId), ", "))
 			}
+			// Check against the cached voice catalog, if any, without
+			// fetching it on every run.
+			if catalog, err := voices.Cached(); err == nil {
+				if err := catalog.CheckSpeaker(spk, modelId); err != nil {
+					return err
+				}
+			}
 
+			_, rawFormat := codec.ParseEncoding(format)
 			modelIdLower := strings.ToLower(modelId)
-			if (modelIdLower == api.ModelIDMist || modelIdLower == api.ModelIDMistV2) && format != "mp3" {
+			if (modelIdLower == api.ModelIDMist || modelIdLower == api.ModelIDMistV2) && format != "mp3" && !rawFormat {
 				return fmt.Errorf("%s and %s models require --format mp3. Please specify --format mp3", api.ModelIDMist, api.ModelIDMistV2)
 			}
 
-			var audioFormat string
+			opts := &api.TTSOptions{
+				Speaker: spk,
+				ModelID: modelId,
+				Lang:    lang,
+			}
+			modelParams.applyChanged(cmd.Flags(), opts)
 			if format != "" {
-				switch format {
-				case "wav":
-					audioFormat = "audio/wav"
-				case "mp3":
-					audioFormat = "audio/mp3"
-				default:
-					return fmt.Errorf("unsupported format: %s (supported: wav, mp3)", format)
+				audioFormat, err := tts.FormatContentType(format, opts.SamplingRate)
+				if err != nil {
+					return err
 				}
+				opts.AudioFormat = audioFormat
 			}
-
-			opts := &api.TTSOptions{
-				Speaker:     spk,
-				ModelID:     modelId,
-				Lang:        lang,
-				AudioFormat: audioFormat,
+			if cmd.Flags().Changed("resample") {
+				contentType := opts.AudioFormat
+				if contentType == "" {
+					contentType = api.GetAudioFormat(opts.ModelID)
+				}
+				audioFormat, err := tts.WithResample(contentType, resample)
+				if err != nil {
+					return err
+				}
+				opts.AudioFormat = audioFormat
 			}
-			modelParams.applyChanged(cmd.Flags(), opts)
+			opts.Timestamps = timestamps
 			if err := api.ValidateModelParams(opts); err != nil {
 				return err
 			}
 
+			edits, err := editOpts.edits(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if !edits.IsZero() {
+				contentType := opts.AudioFormat
+				if contentType == "" {
+					contentType = api.GetAudioFormat(opts.ModelID)
+				}
+				if err := edits.Check(contentType); err != nil {
+					return err
+				}
+				if output != "-" {
+					if err := edits.CheckOutput(contentType, output); err != nil {
+						return err
+					}
+				}
+				if useWS {
+					return fmt.Errorf("--normalize, --trim-silence, --pad-start and --pad-end cannot be used with --ws")
+				}
+			}
+
+			var chunkOpts *tts.ChunkOptions
+			if chunk {
+				if chunkSize < 1 {
+					return fmt.Errorf("--chunk-size must be at least 1, got %d", chunkSize)
+				}
+				if chunkSilence < 0 {
+					return fmt.Errorf("--chunk-silence must not be negative, got %s", chunkSilence)
+				}
+				chunkOpts = &tts.ChunkOptions{MaxChars: chunkSize, Silence: chunkSilence}
+			} else if cmd.Flags().Changed("chunk-size") || cmd.Flags().Changed("chunk-silence") {
+				return fmt.Errorf("--chunk-size and --chunk-silence require --chunk")
+			}
+			if timestamps && (chunk || useWS) {
+				return fmt.Errorf("--timestamps cannot be used with --chunk or --ws")
+			}
+
+			var subs *tts.SubtitleOptions
+			if subtitlesPath != "" {
+				if _, err := tts.SubtitleFormat(subtitlesPath); err != nil {
+					return err
+				}
+				if subtitleLineLength < 1 {
+					return fmt.Errorf("--subtitle-line-length must be at least 1, got %d", subtitleLineLength)
+				}
+				if subtitleMaxDuration <= 0 {
+					return fmt.Errorf("--subtitle-max-duration must be positive, got %s", subtitleMaxDuration)
+				}
+				if useWS {
+					return fmt.Errorf("--subtitles cannot be used with --ws")
+				}
+				subs = &tts.SubtitleOptions{Path: subtitlesPath, LineLength: subtitleLineLength, MaxDuration: subtitleMaxDuration}
+			} else if cmd.Flags().Changed("subtitle-line-length") || cmd.Flags().Changed("subtitle-max-duration") {
+				return fmt.Errorf("--subtitle-line-length and --subtitle-max-duration require --subtitles")
+			}
+
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative, got %d", retries)
+			}
+
+			respCache, err := cacheOpts.open()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			if useWS {
+				if chunkOpts != nil {
+					return fmt.Errorf("--chunk cannot be used with --ws")
+				}
+				if cacheOpts.use {
+					return fmt.Errorf("--cache cannot be used with --ws")
+				}
+				if rawFormat {
+					return fmt.Errorf("--format %s cannot be used with --ws", format)
+				}
+				if cmd.Flags().Changed("resample") {
+					return fmt.Errorf("--resample cannot be used with --ws")
+				}
+				runOpts := tts.RunOptions{
+					Text:       text,
+					TTSOptions: opts,
+					Output:     output,
+					Play:       shouldPlay,
+					Quiet:      Quiet,
+					JSON:       JSONOutput,
+					Version:    Version,
+					BaseURL:    apiURL,
+					ConfigEnv:  ConfigEnv,
+					ConfigFile: ConfigFile,
+				}
+				var input io.Reader
+				if stdinStream {
+					input = os.Stdin
+				}
+				return tts.RunWebSocket(ctx, runOpts, input)
+			}
+
 			if output == "-" {
-				resolved, err := config.ResolveConfigWithOptions(config.ResolveOptions{
-					EnvName:        ConfigEnv,
-					APIURLOverride: apiURL,
-					ConfigFile:     ConfigFile,
-				})
+				client, err := tts.NewClient(Version, apiURL, ConfigEnv, ConfigFile, retries, respCache)
 				if err != nil {
 					return err
 				}
-				client := api.NewClient(api.ClientOptions{
-					APIKey:           resolved.APIKey,
-					APIURL:           resolved.APIURL,
-					AuthHeaderPrefix: resolved.AuthHeaderPrefix,
-					Version:          Version,
-				})
-				audioData, err := client.TTS(text, opts)
+
+				if chunkOpts != nil || subs != nil || rawFormat || cmd.Flags().Changed("resample") || !edits.IsZero() {
+					audio, err := tts.SynthesizeChunked(ctx, client, text, opts, chunkOpts)
+					if err != nil {
+						return err
+					}
+					if _, err := edits.Apply(audio); err != nil {
+						return err
+					}
+					if _, err := os.Stdout.Write(audio.Data); err != nil {
+						return err
+					}
+					if subs != nil {
+						return tts.WriteSubtitles(subs, audio, text)
+					}
+					return nil
+				}
+
+				audioData, err := client.TTSContext(ctx, text, opts)
 				if err != nil {
 					return err
 				}
@@ -122,7 +348,9 @@ The CLI handles format detection, metadata embedding, and playback for both form
 				return err
 			}
 
-			if Quiet || JSONOutput || !term.IsTerminal(int(os.Stdout.Fd())) {
+			// Editing needs the whole clip, so it skips the streaming
+			// player.
+			if Quiet || JSONOutput || !edits.IsZero() || !term.IsTerminal(int(os.Stdout.Fd())) {
 				runOpts := tts.RunOptions{
 					Text:       text,
 					TTSOptions: opts,
@@ -134,11 +362,16 @@ The CLI handles format detection, metadata embedding, and playback for both form
 					BaseURL:    apiURL,
 					ConfigEnv:  ConfigEnv,
 					ConfigFile: ConfigFile,
+					Chunk:      chunkOpts,
+					Retries:    retries,
+					Cache:      respCache,
+					Subtitles:  subs,
+					Edits:      edits,
 				}
-				return tts.RunNonInteractive(runOpts)
+				return tts.RunNonInteractive(ctx, runOpts)
 			}
 
-			p := tea.NewProgram(ui.NewTTSModel(text, opts, output, shouldPlay, Version, apiURL, ConfigEnv, ConfigFile, true))
+			p := tea.NewProgram(ui.NewTTSModel(ctx, text, opts, output, shouldPlay, Version, apiURL, ConfigEnv, ConfigFile, chunkOpts, retries, respCache, true))
 			m, err := p.Run()
 			if err != nil {
 				return err
@@ -148,6 +381,16 @@ The CLI handles format detection, metadata embedding, and playback for both form
 			if ttsM.Err() != nil {
 				return ttsM.Err()
 			}
+			if subs != nil {
+				audio := ttsM.Audio()
+				if audio == nil {
+					return fmt.Errorf("no audio received to write subtitles for")
+				}
+				if err := tts.WriteSubtitles(subs, audio, text); err != nil {
+					return err
+				}
+				fmt.Fprintln(os.Stderr, styles.Successf("Subtitles saved to %s", subtitlesPath))
+			}
 
 			return nil
 		},
@@ -162,10 +405,55 @@ The CLI handles format detection, metadata embedding, and playback for both form
 	cmd.Flags().StringVar(&modelId, "modelId", "", "")
 	cmd.Flags().MarkHidden("modelId")
 	cmd.Flags().StringVarP(&lang, "lang", "l", "eng", "Language code (e.g., eng, es, fra). Valid codes depend on model.")
-	cmd.Flags().StringVarP(&format, "format", "f", "", "Audio format: wav or mp3 (overrides model default)")
+	cmd.Flags().StringVarP(&format, "format", "f", "", "Audio format: wav, mp3, pcm, mulaw or alaw (overrides model default)")
+	cmd.Flags().IntVar(&resample, "resample", 0, "Resample the audio locally to this rate in Hz, for rates the model doesn't offer")
 	cmd.Flags().StringVar(&apiURL, "api-url", "", "API URL (default: $RIME_API_URL or https://users.rime.ai/v1/rime-tts)")
+	cmd.Flags().StringVarP(&textFile, "file", "i", "", "Read text from a file (or - for stdin)")
+	cmd.Flags().BoolVar(&chunk, "chunk", false, "Split long text into several requests and join the audio")
+	cmd.Flags().IntVar(&chunkSize, "chunk-size", tts.DefaultChunkChars, "Maximum characters per chunk (with --chunk)")
+	cmd.Flags().DurationVar(&chunkSilence, "chunk-silence", 0, "Silence to insert between chunks, e.g. 300ms (with --chunk)")
+	cmd.Flags().IntVar(&retries, "retries", 0, "Retry failed requests (network errors, 429 and 5xx) up to N times")
 
 	modelParams.register(cmd.Flags())
+	cacheOpts.register(cmd.Flags())
+	editOpts.register(cmd.Flags())
+	outputDevice.register(cmd.Flags())
+	cmd.Flags().BoolVar(&timestamps, "timestamps", false, "Request word timings, used to sync the transcript and included in --json output")
+	cmd.Flags().BoolVar(&useWS, "ws", false, "Synthesize over a streaming WebSocket connection")
+	cmd.Flags().BoolVar(&stdinStream, "stdin-stream", false, "Read text from stdin line by line and speak it as it arrives (with --ws)")
+	cmd.Flags().StringVar(&subtitlesPath, "subtitles", "", "Also write captions to a .srt or .vtt file")
+	cmd.Flags().IntVar(&subtitleLineLength, "subtitle-line-length", tts.DefaultSubtitleLineLength, "Maximum characters per caption line (with --subtitles)")
+	cmd.Flags().DurationVar(&subtitleMaxDuration, "subtitle-max-duration", tts.DefaultSubtitleMaxDuration, "Maximum time a caption stays on screen (with --subtitles)")
+	registerTTSCompletions(cmd, "model-id")
 
 	return cmd
 }
+
+// readTextInput returns the text to synthesize from the TEXT argument or the
+// --file flag. "-" in either place reads from stdin. Text read from stdin or a
+// file has any UTF-8 byte order mark and surrounding whitespace removed.
+func readTextInput(arg string, file string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case file == "-" || (file == "" && arg == "-"):
+		data, err = io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read text from stdin: %w", err)
+		}
+	case file != "":
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read text file: %w", err)
+		}
+	default:
+		return arg, nil
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("no text to synthesize")
+	}
+	return text, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -103,7 +104,7 @@ func TestTTS_SaveToFile(t *testing.T) {
 		JSON:    false,
 		Version: Version,
 	}
-	err := tts.RunNonInteractive(runOpts)
+	err := tts.RunNonInteractive(context.Background(), runOpts)
 	if err != nil {
 		t.Fatalf("runNonInteractiveTTS failed: %v", err)
 	}
@@ -257,3 +258,161 @@ func TestTTSCmd_ValidParamsAccepted(t *testing.T) {
 		t.Errorf("expected valid arcana params to be accepted, got: %v", err)
 	}
 }
+
+func TestReadTextInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	textFile := filepath.Join(tmpDir, "script.txt")
+	if err := os.WriteFile(textFile, []byte("\ufeff  Hello from a file.\n\n"), 0644); err != nil {
+		t.Fatalf("failed to write text file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		arg   string
+		file  string
+		stdin string
+		want  string
+	}{
+		{"argument", "Hello there", "", "", "Hello there"},
+		{"argument is not trimmed", " spaced ", "", "", " spaced "},
+		{"dash reads stdin", "-", "", "\ufeffPiped text\n", "Piped text"},
+		{"file flag", "", textFile, "", "Hello from a file."},
+		{"file flag dash reads stdin", "", "-", "  from stdin  ", "from stdin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTextInput(tt.arg, tt.file, strings.NewReader(tt.stdin))
+			if err != nil {
+				t.Fatalf("readTextInput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readTextInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTextInput_Errors(t *testing.T) {
+	if _, err := readTextInput("-", "", strings.NewReader("\ufeff \n")); err == nil || !strings.Contains(err.Error(), "no text") {
+		t.Errorf("expected empty stdin error, got %v", err)
+	}
+	if _, err := readTextInput("", filepath.Join(t.TempDir(), "missing.txt"), strings.NewReader("")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestTTS_FileFlagArgs(t *testing.T) {
+	cmd := NewTTSCmd()
+	if err := cmd.Flags().Set("file", "script.txt"); err != nil {
+		t.Fatalf("failed to set --file: %v", err)
+	}
+
+	if err := cmd.ValidateArgs([]string{}); err != nil {
+		t.Errorf("expected no args to be accepted with --file, got %v", err)
+	}
+	if err := cmd.ValidateArgs([]string{"hello"}); err == nil {
+		t.Error("expected error when both TEXT and --file are given")
+	}
+}
+
+func TestTTS_ChunkFlagsRequireChunk(t *testing.T) {
+	cmd := NewTTSCmd()
+	cmd.SetArgs([]string{"hello", "-s", "astra", "-m", api.ModelIDArcana, "-o", filepath.Join(t.TempDir(), "out.wav"), "--chunk-silence", "200ms"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "require --chunk") {
+		t.Errorf("expected --chunk required error, got %v", err)
+	}
+}
+
+func TestTTS_StdinStreamFlags(t *testing.T) {
+	cmd := NewTTSCmd()
+	if err := cmd.Flags().Set("stdin-stream", "true"); err != nil {
+		t.Fatalf("failed to set --stdin-stream: %v", err)
+	}
+	if err := cmd.ValidateArgs([]string{}); err != nil {
+		t.Errorf("expected no args to be accepted with --stdin-stream, got %v", err)
+	}
+	if err := cmd.ValidateArgs([]string{"hello"}); err == nil {
+		t.Error("expected error when both TEXT and --stdin-stream are given")
+	}
+
+	cmd = NewTTSCmd()
+	cmd.SetArgs([]string{"--stdin-stream", "-s", "astra", "-m", api.ModelIDArcana, "-o", filepath.Join(t.TempDir(), "out.wav")})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "requires --ws") {
+		t.Errorf("expected --ws required error, got %v", err)
+	}
+}
+
+func TestTTS_SubtitleFlags(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.wav")
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--subtitles", "clip.txt"}, "unsupported subtitle format"},
+		{[]string{"--subtitle-line-length", "30"}, "require --subtitles"},
+		{[]string{"--subtitles", "clip.srt", "--subtitle-max-duration", "0s"}, "must be positive"},
+		{[]string{"--subtitles", "clip.vtt", "--ws"}, "cannot be used with --ws"},
+	}
+	for _, tt := range tests {
+		cmd := NewTTSCmd()
+		cmd.SetArgs(append([]string{"hello", "-s", "astra", "-m", api.ModelIDArcana, "-o", out}, tt.args...))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
+
+func TestTTS_ResampleFlag(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.wav")
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--resample", "0"}, "must be greater than 0"},
+		{[]string{"--resample", "11025", "--format", "mp3"}, "MP3 can't be re-encoded"},
+		{[]string{"--resample", "11025", "--ws"}, "cannot be used with --ws"},
+	}
+	for _, tt := range tests {
+		cmd := NewTTSCmd()
+		cmd.SetArgs(append([]string{"hello", "-s", "astra", "-m", api.ModelIDArcana, "-o", out}, tt.args...))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
+
+func TestTTS_EditFlags(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.wav")
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--normalize", "loud"}, "invalid loudness target"},
+		{[]string{"--normalize", "-16LUFS", "--format", "mp3"}, "MP3 can't be re-encoded"},
+		{[]string{"--normalize", "-16LUFS", "--ws"}, "cannot be used with --ws"},
+		{[]string{"--trim-silence", "--ws"}, "cannot be used with --ws"},
+		{[]string{"--trim-silence", "--trim-threshold", "6"}, "must be below 0 dBFS"},
+		{[]string{"--trim-min-duration", "1s"}, "require --trim-silence"},
+		{[]string{"--pad-end", "1s", "--format", "mp3", "-o", "out.mp3"}, "use a .wav output file"},
+	}
+	for _, tt := range tests {
+		cmd := NewTTSCmd()
+		cmd.SetArgs(append([]string{"hello", "-s", "astra", "-m", api.ModelIDArcana, "-o", out}, tt.args...))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
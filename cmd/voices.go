@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+	"github.com/rimelabs/rime-cli/internal/voices"
+)
+
+func NewVoicesCmd() *cobra.Command {
+	var filter voices.Filter
+	var refresh bool
+	var ttl time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "voices",
+		Short: "List available speakers",
+		Long: `Lists the speakers available for each model, with language and descriptive tags.
+
+The catalog is cached in ~/.rime/voices.json and refreshed once it is older than --ttl.`,
+		Example: `  rime voices --model arcana
+  rime voices --model arcana --lang spa --tag conversational
+  rime voices --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if filter.ModelID != "" && !api.IsValidModelID(strings.ToLower(filter.ModelID)) {
+				return fmt.Errorf("invalid model: %s (valid options: %s, %s, %s, %s)", filter.ModelID, api.ModelIDArcana, api.ModelIDArcanaV2, api.ModelIDMistV2, api.ModelIDMist)
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			catalog, err := voices.Load(ctx, api.NewVoicesClient(Version), voices.LoadOptions{TTL: ttl, Refresh: refresh})
+			if err != nil {
+				return err
+			}
+			if catalog.Stale && !Quiet && !JSONOutput {
+				fmt.Fprintln(os.Stderr, styles.Dim(fmt.Sprintf("Could not refresh the voice catalog; showing the copy from %s", catalog.FetchedAt.Local().Format("2006-01-02 15:04"))))
+			}
+
+			list := catalog.Filter(filter)
+
+			if JSONOutput {
+				if list == nil {
+					list = []api.Voice{}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(list)
+			}
+
+			if len(list) == 0 {
+				return fmt.Errorf("no voices match the given filters")
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "SPEAKER", "MODEL", "LANG", "GENDER", "AGE", "TAGS")
+			for _, v := range list {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", v.Speaker, v.ModelID, v.Lang, v.Gender, v.Age, strings.Join(v.Genre, ", "))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if !Quiet {
+				fmt.Fprintln(os.Stderr, styles.Dim(fmt.Sprintf("%d voices", len(list))))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&filter.ModelID, "model", "m", "", "Only show speakers for this model")
+	cmd.Flags().StringVarP(&filter.Lang, "lang", "l", "", "Only show speakers for this language (e.g. eng, spa)")
+	cmd.Flags().StringVar(&filter.Gender, "gender", "", "Only show speakers of this gender")
+	cmd.Flags().StringArrayVarP(&filter.Tags, "tag", "t", nil, "Only show speakers with this tag, e.g. conversational (repeatable)")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "Fetch the catalog even if the cache is fresh")
+	cmd.Flags().DurationVar(&ttl, "ttl", voices.DefaultTTL, "Maximum age of the cached catalog")
+	registerTTSCompletions(cmd, "model")
+
+	return cmd
+}
+
+// speakerCompletion completes --speaker from the cached voice catalog,
+// limited to the model given in modelFlag if one has been typed. It never
+// fetches the catalog, so TAB can't hang on a slow or unreachable API; run
+// 'rime voices' to fill the cache.
+func speakerCompletion(modelFlag string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		catalog, err := voices.Cached()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		modelID, _ := cmd.Flags().GetString(modelFlag)
+		var names []string
+		for _, name := range catalog.Speakers(modelID) {
+			if strings.HasPrefix(name, toComplete) {
+				names = append(names, name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+func TestVoices_FilterAndValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]api.Voice{
+			{Speaker: "astra", ModelID: "arcana", Lang: "eng", Genre: []string{"Conversational"}},
+			{Speaker: "cove", ModelID: "mistv2", Lang: "eng"},
+		})
+	}))
+	defer server.Close()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(api.EnvVoicesURL, server.URL)
+	Quiet = true
+	defer func() { Quiet = false }()
+
+	cmd := NewVoicesCmd()
+	cmd.SetArgs([]string{"--model", "arcana", "--tag", "conversational"})
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("voices failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	out := string(data)
+	if !strings.Contains(out, "astra") || strings.Contains(out, "cove") {
+		t.Errorf("unexpected listing:\n%s", out)
+	}
+
+	// The catalog is now cached, so tts rejects a speaker used with the
+	// wrong model before making a request.
+	ttsCmd := NewTTSCmd()
+	ttsCmd.SetArgs([]string{"hello", "-s", "astra", "-m", "mistv2", "-f", "mp3", "-o", "out.mp3"})
+	err = ttsCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "not available for model mistv2") {
+		t.Errorf("expected speaker/model error, got %v", err)
+	}
+}
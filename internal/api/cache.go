@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ResponseCache stores complete TTS responses. Implementations must be safe
+// for concurrent use.
+type ResponseCache interface {
+	Get(key string) (data []byte, contentType string, ok bool)
+	Put(key string, req *TTSRequest, contentType string, data []byte) error
+}
+
+// CacheKey identifies a response by the marshalled request body and the
+// requested audio format.
+func CacheKey(jsonBody []byte, audioFormat string) string {
+	h := sha256.New()
+	h.Write(jsonBody)
+	h.Write([]byte{0})
+	h.Write([]byte(audioFormat))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Client) storeResponse(key string, req *TTSRequest, contentType string, data []byte) {
+	if err := c.cache.Put(key, req, contentType, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache response: %v\n", err)
+	}
+}
+
+// cachingBody passes a streamed response through and stores it once it has
+// been read to the end. Responses that are closed early or fail part way are
+// not stored.
+type cachingBody struct {
+	io.ReadCloser
+	store  func(data []byte)
+	buf    bytes.Buffer
+	failed bool
+	stored bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF && !b.failed && !b.stored {
+		b.stored = true
+		b.store(b.buf.Bytes())
+	} else if err != nil && err != io.EOF {
+		b.failed = true
+	}
+	return n, err
+}
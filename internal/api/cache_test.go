@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (m *memoryCache) Get(key string) ([]byte, string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.entries[key]
+	return data, "audio/wav", ok
+}
+
+func (m *memoryCache) Put(key string, req *TTSRequest, contentType string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func newCachingTestClient(t *testing.T, calls *int) (*Client, *memoryCache) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("fake-audio-data"))
+	}))
+	t.Cleanup(server.Close)
+
+	cache := &memoryCache{entries: make(map[string][]byte)}
+	return NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL, Cache: cache}), cache
+}
+
+func TestTTSStream_CacheHit(t *testing.T) {
+	var calls int
+	client, _ := newCachingTestClient(t, &calls)
+	opts := &TTSOptions{Speaker: "astra", ModelID: "arcana"}
+
+	for i := 0; i < 2; i++ {
+		result, err := client.TTSStreamContext(context.Background(), "hello", opts)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+		data, err := io.ReadAll(result.Body)
+		result.Body.Close()
+		if err != nil {
+			t.Fatalf("read %d failed: %v", i+1, err)
+		}
+		if string(data) != "fake-audio-data" {
+			t.Errorf("request %d body = %q", i+1, data)
+		}
+		if result.Cached != (i == 1) {
+			t.Errorf("request %d Cached = %v", i+1, result.Cached)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 API call, got %d", calls)
+	}
+
+	// A different request is a miss.
+	if _, err := client.TTS("goodbye", opts); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected a second API call for different text, got %d calls", calls)
+	}
+}
+
+func TestTTSStream_PartialReadNotCached(t *testing.T) {
+	var calls int
+	client, cache := newCachingTestClient(t, &calls)
+	opts := &TTSOptions{Speaker: "astra", ModelID: "arcana"}
+
+	result, err := client.TTSStreamContext(context.Background(), "hello", opts)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	buf := make([]byte, 4)
+	io.ReadFull(result.Body, buf)
+	result.Body.Close()
+
+	if len(cache.entries) != 0 {
+		t.Errorf("expected a partly read response not to be cached, got %d entries", len(cache.entries))
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	body := []byte(`{"text":"hello"}`)
+	if CacheKey(body, "audio/wav") == CacheKey(body, "audio/mp3") {
+		t.Error("expected the audio format to change the key")
+	}
+	if CacheKey(body, "audio/wav") != CacheKey([]byte(`{"text":"hello"}`), "audio/wav") {
+		t.Error("expected identical requests to share a key")
+	}
+}
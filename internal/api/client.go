@@ -2,12 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -97,12 +99,27 @@ func ValidLangsForModel(modelID string) []string {
 	return langs
 }
 
+// CanonicalLang returns the three-letter form of a language code, so "es"
+// and "spa" compare equal. Unknown codes are returned lowercased.
+func CanonicalLang(lang string) string {
+	lang = strings.ToLower(lang)
+	for _, l := range allLangs {
+		if l.iso1 == lang {
+			return l.iso3
+		}
+	}
+	return lang
+}
+
 type Client struct {
 	baseURL          string
 	apiKey           string
 	authHeaderPrefix string
 	userAgent        string
 	client           *http.Client
+	retry            RetryPolicy
+	sleep            func(ctx context.Context, d time.Duration) error
+	cache            ResponseCache
 }
 
 type TTSRequest struct {
@@ -127,6 +144,10 @@ type TTSRequest struct {
 	InlineSpeedAlpha         *string `json:"inlineSpeedAlpha,omitempty"`
 	NoTextNormalization      *bool   `json:"noTextNormalization,omitempty"`
 	SaveOovs                 *bool   `json:"saveOovs,omitempty"`
+
+	// Timestamps mode only; see TTSOptions.Timestamps
+	AudioFormat string `json:"audioFormat,omitempty"`
+	Timestamps  bool   `json:"timestamps,omitempty"`
 }
 
 type TTSOptions struct {
@@ -151,12 +172,27 @@ type TTSOptions struct {
 	InlineSpeedAlpha         *string
 	NoTextNormalization      *bool
 	SaveOovs                 *bool
+
+	// Timestamps requests word timings with the audio. The response then
+	// arrives in one piece rather than streaming, and the response cache is
+	// not used.
+	Timestamps bool
 }
 
 func IsValidModelID(modelID string) bool {
 	return validModelIDs[modelID]
 }
 
+// ValidModelIDs returns the supported model IDs, sorted.
+func ValidModelIDs() []string {
+	ids := make([]string, 0, len(validModelIDs))
+	for id := range validModelIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func IsArcanaModel(modelID string) bool {
 	return modelID == ModelIDArcana || modelID == ModelIDArcanaV2
 }
@@ -274,6 +310,9 @@ type ClientOptions struct {
 	AuthHeaderPrefix string
 	Version          string
 	Timeout          time.Duration
+	Retry            RetryPolicy
+	// Cache, if set, answers repeated requests without calling the API.
+	Cache ResponseCache
 }
 
 func NewClient(opts ClientOptions) *Client {
@@ -300,10 +339,19 @@ func NewClient(opts ClientOptions) *Client {
 		authHeaderPrefix: authPrefix,
 		userAgent:        userAgent,
 		client:           httpClient,
+		retry:            opts.Retry,
+		sleep:            sleepContext,
+		cache:            opts.Cache,
 	}
 }
 
 func (c *Client) TTS(text string, opts *TTSOptions) ([]byte, error) {
+	return c.TTSContext(context.Background(), text, opts)
+}
+
+// TTSContext is TTS with a context that cancels the request, any retry
+// waits, and reading of the response.
+func (c *Client) TTSContext(ctx context.Context, text string, opts *TTSOptions) ([]byte, error) {
 	if opts == nil || opts.Speaker == "" {
 		return nil, fmt.Errorf("speaker is required")
 	}
@@ -346,43 +394,28 @@ func (c *Client) TTS(text string, opts *TTSOptions) ([]byte, error) {
 		audioFormat = GetAudioFormat(opts.ModelID)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", audioFormat)
-	if c.apiKey != "" && c.authHeaderPrefix != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("%s %s", c.authHeaderPrefix, c.apiKey))
+	var cacheKey string
+	if c.cache != nil {
+		cacheKey = CacheKey(jsonBody, audioFormat)
+		if data, _, ok := c.cache.Get(cacheKey); ok {
+			return data, nil
+		}
 	}
-	req.Header.Set("User-Agent", c.userAgent)
 
-	resp, err := c.client.Do(req)
+	resp, _, err := c.send(ctx, jsonBody, audioFormat)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, fmt.Errorf("authentication failed: invalid API key")
-		case http.StatusBadRequest:
-			return nil, fmt.Errorf("invalid request: %s", string(body))
-		case http.StatusTooManyRequests:
-			return nil, fmt.Errorf("rate limited: too many requests")
-		default:
-			return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-		}
-	}
-
 	audio, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if c.cache != nil {
+		c.storeResponse(cacheKey, &reqBody, resp.Header.Get("Content-Type"), audio)
+	}
 	return audio, nil
 }
 
@@ -390,9 +423,19 @@ type TTSStreamResult struct {
 	Body        io.ReadCloser
 	ContentType string
 	TTFB        time.Duration
+	// Cached is set when the response came from the client's ResponseCache.
+	Cached bool
+	// Words holds the word timings when TTSOptions.Timestamps was set.
+	Words []WordTiming
 }
 
 func (c *Client) TTSStream(text string, opts *TTSOptions) (*TTSStreamResult, error) {
+	return c.TTSStreamContext(context.Background(), text, opts)
+}
+
+// TTSStreamContext is TTSStream with a context. Cancelling it aborts the
+// request or, once streaming, makes reads from Body fail promptly.
+func (c *Client) TTSStreamContext(ctx context.Context, text string, opts *TTSOptions) (*TTSStreamResult, error) {
 	if opts == nil || opts.Speaker == "" {
 		return nil, fmt.Errorf("speaker is required")
 	}
@@ -430,44 +473,30 @@ func (c *Client) TTSStream(text string, opts *TTSOptions) (*TTSStreamResult, err
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
 	audioFormat := opts.AudioFormat
 	if audioFormat == "" {
 		audioFormat = GetAudioFormat(opts.ModelID)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", audioFormat)
-	if c.apiKey != "" && c.authHeaderPrefix != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("%s %s", c.authHeaderPrefix, c.apiKey))
+	if opts.Timestamps {
+		return c.ttsWithTimestamps(ctx, reqBody, audioFormat)
 	}
-	req.Header.Set("User-Agent", c.userAgent)
 
-	start := time.Now()
-	resp, err := c.client.Do(req)
-	ttfb := time.Since(start)
-
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	var cacheKey string
+	if c.cache != nil {
+		cacheKey = CacheKey(jsonBody, audioFormat)
+		if data, contentType, ok := c.cache.Get(cacheKey); ok {
+			return &TTSStreamResult{
+				Body:        io.NopCloser(bytes.NewReader(data)),
+				ContentType: contentType,
+				Cached:      true,
+			}, nil
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, fmt.Errorf("authentication failed: invalid API key")
-		case http.StatusBadRequest:
-			return nil, fmt.Errorf("invalid request: %s", string(body))
-		case http.StatusTooManyRequests:
-			return nil, fmt.Errorf("rate limited: too many requests")
-		default:
-			return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-		}
+	resp, ttfb, err := c.send(ctx, jsonBody, audioFormat)
+	if err != nil {
+		return nil, err
 	}
 
 	// To detect empty responses to streaming TTS requests, we can't just check the
@@ -479,19 +508,84 @@ func (c *Client) TTSStream(text string, opts *TTSOptions) (*TTSStreamResult, err
 	if err == io.EOF && n == 0 {
 		resp.Body.Close()
 		// formatted to say that speaker {speaker} and language {language} are valid for modelId {modelId}
-		return nil, fmt.Errorf("invalid request: server returned empty response. Please double-check that speaker '%s' and language '%s' are valid for modelId '%s'", opts.Speaker, opts.Lang, opts.ModelID)
+		return nil, fmt.Errorf("invalid request: %w. Please double-check that speaker '%s' and language '%s' are valid for modelId '%s'", ErrEmptyResponse, opts.Speaker, opts.Lang, opts.ModelID)
 	}
 
 	contentType := resp.Header.Get("Content-Type")
+	// Since we've consumed a byte, we reconstruct the stream using MultiReader
+	// so downstream code can read the full response including the peeked byte.
+	var body io.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peekBuf[:n]), resp.Body), resp.Body}
+	if c.cache != nil {
+		body = &cachingBody{ReadCloser: body, store: func(data []byte) {
+			c.storeResponse(cacheKey, &reqBody, contentType, data)
+		}}
+	}
 	return &TTSStreamResult{
-		// Since we've consumed a byte, we reconstruct the stream using MultiReader
-		// so downstream code can read the full response including the peeked byte.
-		Body:        io.NopCloser(io.MultiReader(bytes.NewReader(peekBuf[:n]), resp.Body)),
+		Body:        body,
 		ContentType: contentType,
 		TTFB:        ttfb,
 	}, nil
 }
 
+// send POSTs a TTS request body and returns the successful response, retrying
+// network errors, 429 and 5xx responses according to the client's retry
+// policy. The returned duration is the time to first byte of the final
+// attempt.
+func (c *Client) send(ctx context.Context, jsonBody []byte, audioFormat string) (*http.Response, time.Duration, error) {
+	for attempt := 1; ; attempt++ {
+		canRetry := attempt < c.retry.MaxAttempts
+
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", audioFormat)
+		if c.apiKey != "" && c.authHeaderPrefix != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("%s %s", c.authHeaderPrefix, c.apiKey))
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+
+		start := time.Now()
+		resp, err := c.client.Do(req)
+		ttfb := time.Since(start)
+
+		if err != nil {
+			if canRetry && ctx.Err() == nil {
+				d, _ := c.retry.delay(attempt, "")
+				if err := c.sleep(ctx, d); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+			return nil, 0, fmt.Errorf("request failed: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp, ttfb, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if canRetry && isRetryableStatus(resp.StatusCode) {
+			// A server asking for a longer wait than MaxDelay gets its error
+			// returned rather than a retry that would be rejected again.
+			if d, ok := c.retry.delay(attempt, resp.Header.Get("Retry-After")); ok {
+				if err := c.sleep(ctx, d); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+		}
+
+		return nil, 0, newAPIError(resp, body)
+	}
+}
+
 // ValidateAPIKey confirms the API key is valid using the lightweight OOV
 // (out-of-vocabulary) endpoint. No TTS credits are consumed.
 func (c *Client) ValidateAPIKey() error {
@@ -514,12 +608,9 @@ func (c *Client) ValidateAPIKey() error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("authentication failed: invalid API key")
-	}
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return newAPIError(resp, respBody)
 	}
 
 	return nil
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the failure classes callers branch on. Test for them
+// with errors.Is; an *APIError matches the sentinel for its status code.
+var (
+	ErrUnauthorized   = errors.New("authentication failed")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrEmptyResponse  = errors.New("server returned empty response")
+)
+
+// requestIDHeaders are checked in order for an ID to quote in bug reports.
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Amzn-Requestid", "X-Amz-Cf-Id", "Cf-Ray"}
+
+// APIError is a non-200 response from the Rime API.
+type APIError struct {
+	StatusCode int
+	// Body is the raw response body.
+	Body []byte
+	// Message is the server's error message, taken from a JSON "message",
+	// "error" or "detail" field when present and the trimmed body otherwise.
+	Message   string
+	RequestID string
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		Message:    parseErrorMessage(body),
+	}
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	return e
+}
+
+func parseErrorMessage(body []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		for _, key := range []string{"message", "error", "detail"} {
+			raw, ok := fields[key]
+			if !ok {
+				continue
+			}
+			var s string
+			if json.Unmarshal(raw, &s) == nil && s != "" {
+				return s
+			}
+			var nested struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(raw, &nested) == nil && nested.Message != "" {
+				return nested.Message
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func (e *APIError) Error() string {
+	var msg string
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		msg = "authentication failed: invalid API key"
+	case e.StatusCode == http.StatusTooManyRequests:
+		msg = "rate limited: too many requests"
+	case e.isValidation():
+		msg = fmt.Sprintf("invalid request: %s", e.Message)
+	default:
+		msg = fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request ID: %s)", e.RequestID)
+	}
+	return msg
+}
+
+func (e *APIError) isValidation() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
+
+// Is reports whether the error belongs to one of the sentinel classes.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrInvalidRequest:
+		return e.isValidation()
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"message": "speaker not found"}`, "speaker not found"},
+		{`{"error": "bad lang"}`, "bad lang"},
+		{`{"error": {"message": "nested"}}`, "nested"},
+		{`{"detail": "too long"}`, "too long"},
+		{"  plain text body\n", "plain text body"},
+		{`{"unrelated": 1}`, `{"unrelated": 1}`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseErrorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("parseErrorMessage(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAPIError_FromResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "unknown speaker"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	_, err := client.TTSStream("hello", &TTSOptions{Speaker: "nobody", ModelID: ModelIDArcana})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("StatusCode = %d", apiErr.StatusCode)
+	}
+	if string(apiErr.Body) != `{"message": "unknown speaker"}` {
+		t.Errorf("Body = %q", apiErr.Body)
+	}
+	if apiErr.Message != "unknown speaker" || apiErr.RequestID != "req-123" {
+		t.Errorf("Message = %q, RequestID = %q", apiErr.Message, apiErr.RequestID)
+	}
+	if want := "invalid request: unknown speaker (request ID: req-123)"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrUnauthorized) {
+		t.Error("expected error to match only ErrInvalidRequest")
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusBadRequest, ErrInvalidRequest},
+		{http.StatusUnprocessableEntity, ErrInvalidRequest},
+	}
+	sentinels := []error{ErrUnauthorized, ErrRateLimited, ErrInvalidRequest, ErrEmptyResponse}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: tt.status})
+		for _, s := range sentinels {
+			if got, want := errors.Is(err, s), s == tt.target; got != want {
+				t.Errorf("status %d: errors.Is(%v) = %v, want %v", tt.status, s, got, want)
+			}
+		}
+	}
+
+	err := &APIError{StatusCode: http.StatusInternalServerError, Message: "boom"}
+	for _, s := range sentinels {
+		if errors.Is(err, s) {
+			t.Errorf("500 should not match %v", s)
+		}
+	}
+	if err.Error() != "API error 500: boom" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestTTSStream_EmptyResponseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	_, err := client.TTSStream("hello", &TTSOptions{Speaker: "astra", ModelID: ModelIDArcana})
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Fatalf("expected ErrEmptyResponse, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "speaker 'astra'") {
+		t.Errorf("error should mention the speaker, got %v", err)
+	}
+}
@@ -63,12 +63,7 @@ func (c *OptimizeClient) GetRecentUsage() (*UsageHistory, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, fmt.Errorf("authentication failed: invalid API key")
-		default:
-			return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-		}
+		return nil, newAPIError(resp, body)
 	}
 
 	var history UsageHistory
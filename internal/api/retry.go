@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 10 * time.Second
+	defaultRetryJitter    = 0.2
+)
+
+// RetryPolicy controls how failed TTS requests are retried. Network errors,
+// 429 and 5xx responses are retried; other errors are returned immediately.
+// Retries only happen before any audio has been returned to the caller, so a
+// stream that fails part way through is never restarted.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles on each
+	// further retry, up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts (10s if unset). A Retry-After
+	// asking for longer ends the retries instead.
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction, e.g. 0.2 for ±20%.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the policy used by the CLI's --retries flag:
+// up to retries additional attempts with exponential backoff from 500ms.
+func DefaultRetryPolicy(retries int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: retries + 1,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		Jitter:      defaultRetryJitter,
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// delay returns how long to wait before retry number n (starting at 1). A
+// valid Retry-After header value takes precedence over the backoff schedule;
+// ok is false if it asks for a longer wait than MaxDelay.
+func (p RetryPolicy) delay(n int, retryAfter string) (d time.Duration, ok bool) {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		return d, d <= maxDelay
+	}
+
+	d = p.BaseDelay
+	for i := 1; i < n && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return d, true
+}
+
+// parseRetryAfter accepts both forms of Retry-After: a number of seconds or
+// an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx's error in
+// the latter case.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
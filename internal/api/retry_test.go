@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// retryServer fails the first failures requests with status, then succeeds.
+func retryServer(failures int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte("try again"))
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("RIFF audio"))
+	}))
+	return server, &calls
+}
+
+func newRetryClient(url string, attempts int) (*Client, *[]time.Duration) {
+	client := NewClient(ClientOptions{
+		APIKey: "test-key",
+		APIURL: url,
+		Retry:  RetryPolicy{MaxAttempts: attempts, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+	})
+	var delays []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return client, &delays
+}
+
+var retryOpts = &TTSOptions{Speaker: "astra", ModelID: ModelIDArcana}
+
+func TestRetry_ServerErrorThenSuccess(t *testing.T) {
+	server, calls := retryServer(2, http.StatusServiceUnavailable, "")
+	defer server.Close()
+
+	client, delays := newRetryClient(server.URL, 3)
+	audio, err := client.TTS("hello", retryOpts)
+	if err != nil {
+		t.Fatalf("TTS() error = %v", err)
+	}
+	if string(audio) != "RIFF audio" {
+		t.Errorf("audio = %q", audio)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+	if want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}; !equalDurations(*delays, want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestRetry_HonoursRetryAfter(t *testing.T) {
+	server, calls := retryServer(1, http.StatusTooManyRequests, "3")
+	defer server.Close()
+
+	client, delays := newRetryClient(server.URL, 2)
+	client.retry.MaxDelay = 5 * time.Second
+	result, err := client.TTSStream("hello", retryOpts)
+	if err != nil {
+		t.Fatalf("TTSStream() error = %v", err)
+	}
+	result.Body.Close()
+
+	if *calls != 2 {
+		t.Errorf("calls = %d, want 2", *calls)
+	}
+	if want := []time.Duration{3 * time.Second}; !equalDurations(*delays, want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestRetry_RetryAfterBeyondMaxDelay(t *testing.T) {
+	server, calls := retryServer(1, http.StatusTooManyRequests, "86400")
+	defer server.Close()
+
+	client, delays := newRetryClient(server.URL, 3)
+	_, err := client.TTS("hello", retryOpts)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	if *calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %v; want 1 call and no wait", *calls, *delays)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := retryServer(10, http.StatusTooManyRequests, "")
+	defer server.Close()
+
+	client, _ := newRetryClient(server.URL, 3)
+	_, err := client.TTS("hello", retryOpts)
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+}
+
+func TestRetry_ClientErrorsNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		server, calls := retryServer(10, status, "")
+
+		client, _ := newRetryClient(server.URL, 3)
+		if _, err := client.TTS("hello", retryOpts); err == nil {
+			t.Errorf("status %d: expected error", status)
+		}
+		if *calls != 1 {
+			t.Errorf("status %d: calls = %d, want 1", status, *calls)
+		}
+		server.Close()
+	}
+}
+
+func TestRetry_DisabledByDefault(t *testing.T) {
+	server, calls := retryServer(1, http.StatusBadGateway, "")
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	if _, err := client.TTS("hello", retryOpts); err == nil || !strings.Contains(err.Error(), "API error 502") {
+		t.Errorf("expected API error 502, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestRetry_NetworkError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	url := "http://" + ln.Addr().String()
+	ln.Close()
+
+	client, delays := newRetryClient(url, 3)
+	_, err = client.TTSStream("hello", retryOpts)
+	if err == nil || !strings.Contains(err.Error(), "request failed") {
+		t.Errorf("expected request failed error, got %v", err)
+	}
+	if len(*delays) != 2 {
+		t.Errorf("expected 2 retries, got %d", len(*delays))
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got, _ := p.delay(i+1, ""); got != w {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	if d, ok := p.delay(1, "5"); !ok || d != 5*time.Second {
+		t.Errorf("delay with Retry-After at the cap = %v, %v", d, ok)
+	}
+	if _, ok := p.delay(1, "6"); ok {
+		t.Error("Retry-After beyond MaxDelay should end retries")
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d, _ := p.delay(1, ""); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jittered delay %v outside ±50%%", d)
+		}
+	}
+
+	// Without MaxDelay the default cap applies, however many attempts.
+	unbounded := RetryPolicy{BaseDelay: time.Second}
+	if d, _ := unbounded.delay(200, ""); d != defaultRetryMaxDelay {
+		t.Errorf("delay(200) without MaxDelay = %v, want %v", d, defaultRetryMaxDelay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("7"); !ok || d != 7*time.Second {
+		t.Errorf("parseRetryAfter(7) = %v, %v", d, ok)
+	}
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d < 28*time.Second || d > 30*time.Second {
+		t.Errorf("parseRetryAfter(date) = %v, %v", d, ok)
+	}
+	for _, v := range []string{"", "soon", "-1"} {
+		if _, ok := parseRetryAfter(v); ok {
+			t.Errorf("parseRetryAfter(%q) should fail", v)
+		}
+	}
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRetry_ContextCancelsWait(t *testing.T) {
+	server, calls := retryServer(10, http.StatusServiceUnavailable, "5")
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL, Retry: DefaultRetryPolicy(3)})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.TTSStreamContext(ctx, "hello", retryOpts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("retry wait was not interrupted (took %v)", elapsed)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/rimelabs/rime-cli/internal/ws"
+)
+
+const (
+	defaultWebSocketURL = "wss://users.rime.ai/ws2"
+	EnvWebSocketURL     = "RIME_WS_URL"
+
+	// DefaultStreamingSampleRate is the PCM sample rate requested from the
+	// streaming API when TTSOptions.SamplingRate is unset.
+	DefaultStreamingSampleRate = 24000
+)
+
+// StreamingSession is a WebSocket connection to the streaming TTS API for
+// text that arrives incrementally, such as LLM output. Text passed to Send
+// is buffered by the server and synthesized as complete clauses arrive;
+// Flush forces synthesis of whatever is buffered. The audio is delivered on
+// Audio in order.
+type StreamingSession struct {
+	// Audio receives audio as it is synthesized: raw 16-bit mono
+	// little-endian PCM at SampleRate when ContentType is "audio/pcm", MP3
+	// frames otherwise. It is closed when the session ends, after which Err
+	// reports why.
+	Audio <-chan []byte
+	// ContentType is "audio/pcm" or "audio/mp3".
+	ContentType string
+	// SampleRate is the PCM sample rate; zero for MP3.
+	SampleRate int
+
+	conn *ws.Conn
+	done chan struct{}
+	err  error
+	stop func() bool
+}
+
+// streamingMessage is a message from the server.
+type streamingMessage struct {
+	Type    string `json:"type"`
+	Data    string `json:"data"`
+	Message string `json:"message"`
+}
+
+// StreamingURL returns the WebSocket endpoint: $RIME_WS_URL if set,
+// otherwise the /ws2 path on the client's API host.
+func (c *Client) StreamingURL() string {
+	if u := os.Getenv(EnvWebSocketURL); u != "" {
+		return u
+	}
+	u, err := url.Parse(c.baseURL)
+	if err != nil || u.Host == "" {
+		return defaultWebSocketURL
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	default:
+		u.Scheme = "wss"
+	}
+	u.Path = "/ws2"
+	u.RawQuery = ""
+	return u.String()
+}
+
+// NewStreamingSession opens a streaming session for the speaker, model and
+// parameters in opts. Cancelling ctx closes the connection immediately;
+// Audio is then closed and Err returns ctx's error.
+func (c *Client) NewStreamingSession(ctx context.Context, opts *TTSOptions) (*StreamingSession, error) {
+	if opts == nil || opts.Speaker == "" {
+		return nil, fmt.Errorf("speaker is required")
+	}
+	if opts.ModelID == "" {
+		return nil, fmt.Errorf("modelId is required")
+	}
+	if !IsValidModelID(opts.ModelID) {
+		return nil, fmt.Errorf("invalid modelId: %s (valid options: %s, %s, %s, %s)", opts.ModelID, ModelIDArcana, ModelIDArcanaV2, ModelIDMistV2, ModelIDMist)
+	}
+	if err := ValidateModelParams(opts); err != nil {
+		return nil, err
+	}
+
+	s := &StreamingSession{done: make(chan struct{})}
+	audioFormat := opts.AudioFormat
+	if audioFormat == "" {
+		audioFormat = GetAudioFormat(opts.ModelID)
+	}
+	query := url.Values{}
+	if audioFormat == "audio/wav" {
+		s.ContentType = "audio/pcm"
+		s.SampleRate = DefaultStreamingSampleRate
+		if opts.SamplingRate != nil {
+			s.SampleRate = *opts.SamplingRate
+		}
+		query.Set("audioFormat", "pcm")
+	} else {
+		s.ContentType = "audio/mp3"
+		query.Set("audioFormat", "mp3")
+	}
+	if err := addRequestParams(query, opts, s.SampleRate); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(c.StreamingURL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket URL: %w", err)
+	}
+	u.RawQuery = query.Encode()
+
+	header := http.Header{}
+	header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		header.Set("Authorization", c.authHeaderPrefix+" "+c.apiKey)
+	}
+
+	conn, err := ws.Dial(ctx, u.String(), header)
+	if err != nil {
+		var hsErr *ws.HandshakeError
+		if errors.As(err, &hsErr) {
+			return nil, newAPIError(hsErr.Response, hsErr.Body)
+		}
+		return nil, fmt.Errorf("websocket connection failed: %w", err)
+	}
+	s.conn = conn
+	s.stop = context.AfterFunc(ctx, func() { conn.Close() })
+
+	audio := make(chan []byte, 64)
+	s.Audio = audio
+	go s.readLoop(ctx, audio)
+	return s, nil
+}
+
+// addRequestParams sets the request parameters in opts as query values,
+// using the same names as the HTTP API's JSON body.
+func addRequestParams(query url.Values, opts *TTSOptions, sampleRate int) error {
+	req := TTSRequest{
+		Speaker:                  opts.Speaker,
+		ModelID:                  opts.ModelID,
+		Lang:                     opts.Lang,
+		RepetitionPenalty:        opts.RepetitionPenalty,
+		Temperature:              opts.Temperature,
+		TopP:                     opts.TopP,
+		MaxTokens:                opts.MaxTokens,
+		SamplingRate:             opts.SamplingRate,
+		SpeedAlpha:               opts.SpeedAlpha,
+		PauseBetweenBrackets:     opts.PauseBetweenBrackets,
+		PhonemizeBetweenBrackets: opts.PhonemizeBetweenBrackets,
+		InlineSpeedAlpha:         opts.InlineSpeedAlpha,
+		NoTextNormalization:      opts.NoTextNormalization,
+		SaveOovs:                 opts.SaveOovs,
+	}
+	if sampleRate > 0 {
+		req.SamplingRate = &sampleRate
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	for k, v := range fields {
+		if k == "text" {
+			continue
+		}
+		query.Set(k, fmt.Sprint(v))
+	}
+	return nil
+}
+
+func (s *StreamingSession) readLoop(ctx context.Context, audio chan<- []byte) {
+	defer close(s.done)
+	defer close(audio)
+	defer s.stop()
+	defer s.conn.Close()
+
+	err := func() error {
+		for {
+			_, data, err := s.conn.ReadMessage()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			var msg streamingMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				return fmt.Errorf("invalid message from server: %w", err)
+			}
+			switch msg.Type {
+			case "chunk":
+				chunk, err := base64.StdEncoding.DecodeString(msg.Data)
+				if err != nil {
+					return fmt.Errorf("invalid audio chunk from server: %w", err)
+				}
+				select {
+				case audio <- chunk:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			case "error":
+				return fmt.Errorf("server error: %s", msg.Message)
+			case "done":
+				return nil
+			}
+		}
+	}()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	s.err = err
+}
+
+func (s *StreamingSession) send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := s.conn.WriteMessage(ws.TextMessage, data); err != nil {
+		select {
+		case <-s.done:
+			if s.err != nil {
+				return s.err
+			}
+		default:
+		}
+		return fmt.Errorf("failed to send to streaming session: %w", err)
+	}
+	return nil
+}
+
+// Send queues text for synthesis. Text is synthesized once the server sees
+// the end of a clause, on Flush, or on Close.
+func (s *StreamingSession) Send(text string) error {
+	return s.send(map[string]string{"text": text})
+}
+
+// Flush synthesizes all text sent so far without waiting for more.
+func (s *StreamingSession) Flush() error {
+	return s.send(map[string]string{"operation": "flush"})
+}
+
+// Close tells the server no more text is coming. Audio for the remaining
+// text is still delivered; Audio is closed once the server has finished.
+func (s *StreamingSession) Close() error {
+	return s.send(map[string]string{"operation": "eos"})
+}
+
+// Err waits for the session to end and returns why: nil after a normal
+// close, otherwise the connection, server or context error. Call it once
+// Audio is closed.
+func (s *StreamingSession) Err() error {
+	<-s.done
+	return s.err
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/ws"
+)
+
+// streamingStandIn answers each text message with one audio chunk holding
+// the text, and closes the connection after "eos".
+func streamingStandIn(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		conn, err := ws.Accept(w, r)
+		if err != nil {
+			t.Errorf("Accept failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg map[string]string
+			json.Unmarshal(data, &msg)
+			switch {
+			case msg["text"] != "":
+				reply, _ := json.Marshal(map[string]string{"type": "chunk", "data": base64.StdEncoding.EncodeToString([]byte(msg["text"]))})
+				conn.WriteMessage(ws.TextMessage, reply)
+			case msg["operation"] == "flush":
+				reply, _ := json.Marshal(map[string]string{"type": "chunk", "data": base64.StdEncoding.EncodeToString([]byte("|"))})
+				conn.WriteMessage(ws.TextMessage, reply)
+			case msg["operation"] == "eos":
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamingSession_SendFlushClose(t *testing.T) {
+	server := streamingStandIn(t, func(r *http.Request) {
+		if r.URL.Path != "/ws2" {
+			t.Errorf("expected path /ws2, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("expected Bearer test-key, got %q", r.Header.Get("Authorization"))
+		}
+		q := r.URL.Query()
+		if q.Get("speaker") != "astra" || q.Get("modelId") != "arcana" || q.Get("audioFormat") != "pcm" || q.Get("samplingRate") != "24000" || q.Get("temperature") != "0.3" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		if q.Has("text") {
+			t.Error("text should not be sent as a query parameter")
+		}
+	})
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL + "/v1/rime-tts"})
+	temp := 0.3
+	sess, err := client.NewStreamingSession(context.Background(), &TTSOptions{Speaker: "astra", ModelID: "arcana", Temperature: &temp})
+	if err != nil {
+		t.Fatalf("NewStreamingSession failed: %v", err)
+	}
+	if sess.ContentType != "audio/pcm" || sess.SampleRate != DefaultStreamingSampleRate {
+		t.Errorf("unexpected format %s at %d Hz", sess.ContentType, sess.SampleRate)
+	}
+
+	for _, step := range []func() error{
+		func() error { return sess.Send("Hello ") },
+		func() error { return sess.Send("world. ") },
+		sess.Flush,
+		sess.Close,
+	} {
+		if err := step(); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+
+	var got strings.Builder
+	for chunk := range sess.Audio {
+		got.Write(chunk)
+	}
+	if err := sess.Err(); err != nil {
+		t.Fatalf("session ended with error: %v", err)
+	}
+	if got.String() != "Hello world. |" {
+		t.Errorf("audio = %q, want %q", got.String(), "Hello world. |")
+	}
+}
+
+func TestStreamingSession_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Accept(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(ws.TextMessage, []byte(`{"type":"error","message":"unknown speaker"}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	sess, err := client.NewStreamingSession(context.Background(), &TTSOptions{Speaker: "nobody", ModelID: "arcana"})
+	if err != nil {
+		t.Fatalf("NewStreamingSession failed: %v", err)
+	}
+	for range sess.Audio {
+	}
+	if err := sess.Err(); err == nil || !strings.Contains(err.Error(), "unknown speaker") {
+		t.Errorf("expected the server error, got %v", err)
+	}
+}
+
+func TestStreamingSession_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "bad-key", APIURL: server.URL})
+	_, err := client.NewStreamingSession(context.Background(), &TTSOptions{Speaker: "astra", ModelID: "arcana"})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestStreamingSession_ContextCancel(t *testing.T) {
+	server := streamingStandIn(t, nil)
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sess, err := client.NewStreamingSession(ctx, &TTSOptions{Speaker: "astra", ModelID: "arcana"})
+	if err != nil {
+		t.Fatalf("NewStreamingSession failed: %v", err)
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range sess.Audio {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("session did not end after cancel")
+	}
+	if !errors.Is(sess.Err(), context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", sess.Err())
+	}
+}
+
+func TestStreamingURL(t *testing.T) {
+	t.Setenv(EnvWebSocketURL, "")
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"https://users.rime.ai/v1/rime-tts", "wss://users.rime.ai/ws2"},
+		{"http://localhost:8080/tts?x=1", "ws://localhost:8080/ws2"},
+	}
+	for _, tt := range tests {
+		client := NewClient(ClientOptions{APIURL: tt.base})
+		if got := client.StreamingURL(); got != tt.want {
+			t.Errorf("StreamingURL for %s = %s, want %s", tt.base, got, tt.want)
+		}
+	}
+
+	t.Setenv(EnvWebSocketURL, "wss://example.com/custom")
+	if got := NewClient(ClientOptions{APIURL: "https://users.rime.ai"}).StreamingURL(); got != "wss://example.com/custom" {
+		t.Errorf("expected %s to override the URL, got %s", EnvWebSocketURL, got)
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WordTiming is when one word is spoken in synthesized audio, in
+// milliseconds from the start of the audio.
+type WordTiming struct {
+	Word    string `json:"word"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+}
+
+// Start returns when the word begins.
+func (w WordTiming) Start() time.Duration {
+	return time.Duration(w.StartMs) * time.Millisecond
+}
+
+// End returns when the word ends.
+func (w WordTiming) End() time.Duration {
+	return time.Duration(w.EndMs) * time.Millisecond
+}
+
+// timestampsResponse is the JSON body returned in timestamps mode: the
+// base64 audio plus parallel arrays of words and their start and end times
+// in seconds.
+type timestampsResponse struct {
+	AudioContent   string          `json:"audioContent"`
+	Timestamps     *wordTimestamps `json:"timestamps"`
+	WordTimestamps *wordTimestamps `json:"word_timestamps"`
+}
+
+type wordTimestamps struct {
+	Words []string  `json:"words"`
+	Start []float64 `json:"start"`
+	End   []float64 `json:"end"`
+}
+
+func (w *wordTimestamps) timings() ([]WordTiming, error) {
+	if len(w.Start) != len(w.Words) || len(w.End) != len(w.Words) {
+		return nil, fmt.Errorf("word timestamps are malformed: %d words, %d start and %d end times", len(w.Words), len(w.Start), len(w.End))
+	}
+	timings := make([]WordTiming, len(w.Words))
+	for i, word := range w.Words {
+		timings[i] = WordTiming{
+			Word:    word,
+			StartMs: int64(w.Start[i]*1000 + 0.5),
+			EndMs:   int64(w.End[i]*1000 + 0.5),
+		}
+	}
+	return timings, nil
+}
+
+// ttsWithTimestamps requests the whole response as JSON with word timings
+// instead of streaming audio. The audio is returned as an in-memory body.
+func (c *Client) ttsWithTimestamps(ctx context.Context, reqBody TTSRequest, audioFormat string) (*TTSStreamResult, error) {
+	reqBody.Timestamps = true
+	if audioFormat == "audio/wav" {
+		reqBody.AudioFormat = "wav"
+	} else {
+		reqBody.AudioFormat = "mp3"
+	}
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, ttfb, err := c.send(ctx, jsonBody, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var body timestampsResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamps response: %w", err)
+	}
+	audio, err := base64.StdEncoding.DecodeString(body.AudioContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("invalid request: %w. Please double-check that speaker '%s' and language '%s' are valid for modelId '%s'", ErrEmptyResponse, reqBody.Speaker, reqBody.Lang, reqBody.ModelID)
+	}
+
+	var words []WordTiming
+	ts := body.WordTimestamps
+	if ts == nil {
+		ts = body.Timestamps
+	}
+	if ts != nil {
+		if words, err = ts.timings(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &TTSStreamResult{
+		Body:        io.NopCloser(bytes.NewReader(audio)),
+		ContentType: audioFormat,
+		TTFB:        ttfb,
+		Words:       words,
+	}, nil
+}
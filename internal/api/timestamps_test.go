@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTSStream_Timestamps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("expected Accept: application/json, got %q", r.Header.Get("Accept"))
+		}
+		var req TTSRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Timestamps || req.AudioFormat != "wav" {
+			t.Errorf("expected timestamps mode with wav audio, got %+v", req)
+		}
+		fmt.Fprintf(w, `{"audioContent":%q,"word_timestamps":{"words":["Hello","world"],"start":[0.05,0.4],"end":[0.35,0.9]}}`,
+			base64.StdEncoding.EncodeToString([]byte("fake-audio-data")))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	result, err := client.TTSStreamContext(context.Background(), "Hello world", &TTSOptions{Speaker: "astra", ModelID: "arcana", Timestamps: true})
+	if err != nil {
+		t.Fatalf("TTSStreamContext failed: %v", err)
+	}
+	data, _ := io.ReadAll(result.Body)
+	if string(data) != "fake-audio-data" {
+		t.Errorf("audio = %q", data)
+	}
+	if result.ContentType != "audio/wav" {
+		t.Errorf("content type = %q, want audio/wav", result.ContentType)
+	}
+
+	want := []WordTiming{{"Hello", 50, 350}, {"world", 400, 900}}
+	if len(result.Words) != len(want) {
+		t.Fatalf("got %d word timings, want %d", len(result.Words), len(want))
+	}
+	for i, w := range want {
+		if result.Words[i] != w {
+			t.Errorf("word %d = %+v, want %+v", i, result.Words[i], w)
+		}
+	}
+	if result.Words[1].Start() != 400*time.Millisecond {
+		t.Errorf("Start() = %v, want 400ms", result.Words[1].Start())
+	}
+}
+
+func TestTTSStream_TimestampsMalformed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"audioContent":%q,"timestamps":{"words":["Hello","world"],"start":[0.05],"end":[0.35,0.9]}}`,
+			base64.StdEncoding.EncodeToString([]byte("fake-audio-data")))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	_, err := client.TTSStreamContext(context.Background(), "Hello world", &TTSOptions{Speaker: "astra", ModelID: "arcana", Timestamps: true})
+	if err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Errorf("expected a malformed timestamps error, got %v", err)
+	}
+}
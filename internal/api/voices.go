@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultVoicesURL = "https://users.rime.ai/data/voices/voice_details.json"
+const EnvVoicesURL = "RIME_VOICES_URL"
+
+// Voice describes one speaker of one model in the public voice catalog.
+type Voice struct {
+	Speaker     string   `json:"speaker"`
+	ModelID     string   `json:"model_id"`
+	Lang        string   `json:"lang"`
+	Gender      string   `json:"gender,omitempty"`
+	Age         string   `json:"age,omitempty"`
+	Country     string   `json:"country,omitempty"`
+	Region      string   `json:"region,omitempty"`
+	Demographic string   `json:"demographic,omitempty"`
+	Genre       []string `json:"genre,omitempty"`
+	Flagship    bool     `json:"flagship,omitempty"`
+}
+
+// Tags returns the descriptive labels a voice can be filtered by: its genres
+// plus gender, age and demographic, lowercased.
+func (v Voice) Tags() []string {
+	var tags []string
+	for _, t := range append(append([]string(nil), v.Genre...), v.Gender, v.Age, v.Demographic) {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+type VoicesClient struct {
+	url       string
+	userAgent string
+	client    *http.Client
+}
+
+// NewVoicesClient returns a client for the voice catalog, which needs no API
+// key. RIME_VOICES_URL overrides the catalog location.
+func NewVoicesClient(version string) *VoicesClient {
+	url := defaultVoicesURL
+	if u := os.Getenv(EnvVoicesURL); u != "" {
+		url = u
+	}
+	return &VoicesClient{
+		url:       url,
+		userAgent: UserAgent(version),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *VoicesClient) ListVoices(ctx context.Context) ([]Voice, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, body)
+	}
+
+	var voices []Voice
+	if err := json.NewDecoder(resp.Body).Decode(&voices); err != nil {
+		return nil, fmt.Errorf("failed to decode voice catalog: %w", err)
+	}
+	return voices, nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListVoices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); !strings.HasPrefix(ua, "rime-cli/") {
+			t.Errorf("unexpected User-Agent %q", ua)
+		}
+		w.Write([]byte(`[{"speaker":"astra","model_id":"arcana","lang":"eng","gender":"Female","age":"Young Adult","genre":["Conversational"],"flagship":true}]`))
+	}))
+	defer server.Close()
+	t.Setenv(EnvVoicesURL, server.URL)
+
+	list, err := NewVoicesClient("test").ListVoices(context.Background())
+	if err != nil {
+		t.Fatalf("ListVoices() error = %v", err)
+	}
+	if len(list) != 1 || list[0].Speaker != "astra" || !list[0].Flagship {
+		t.Fatalf("unexpected voices: %+v", list)
+	}
+	want := []string{"conversational", "female", "young adult"}
+	if got := list[0].Tags(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
+
+func TestCanonicalLang(t *testing.T) {
+	for in, want := range map[string]string{"es": "spa", "SPA": "spa", "eng": "eng", "xx": "xx"} {
+		if got := CanonicalLang(in); got != want {
+			t.Errorf("CanonicalLang(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -73,3 +73,23 @@ func TestAnalyzeAmplitudes_InvalidData(t *testing.T) {
 		t.Error("AnalyzeAmplitudes() with invalid data should return error")
 	}
 }
+
+func TestSilentEnds(t *testing.T) {
+	tests := []struct {
+		amps        []float64
+		minCount    int
+		lead, trail int
+	}{
+		{[]float64{0, 0, 0.5, 0.2, 0, 0, 0}, 1, 2, 3},
+		{[]float64{0, 0, 0.5, 0.2, 0, 0, 0}, 3, 0, 3},
+		{[]float64{0.5, 0.2}, 1, 0, 0},
+		{[]float64{0, 0, 0}, 1, 0, 0},
+		{nil, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		lead, trail := SilentEnds(tt.amps, 0.01, tt.minCount)
+		if lead != tt.lead || trail != tt.trail {
+			t.Errorf("SilentEnds(%v, min %d) = %d, %d; want %d, %d", tt.amps, tt.minCount, lead, trail, tt.lead, tt.trail)
+		}
+	}
+}
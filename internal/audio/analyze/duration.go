@@ -107,7 +107,8 @@ func CalculateWavDuration(data []byte) time.Duration {
 				fmtData := data[pos+8 : pos+8+chunkSize]
 				if len(fmtData) >= 16 {
 					audioFormat := binary.LittleEndian.Uint16(fmtData[0:2])
-					if audioFormat == 1 {
+					// PCM, IEEE float or WAVE_FORMAT_EXTENSIBLE
+					if audioFormat == 1 || audioFormat == 3 || audioFormat == 0xFFFE {
 						fmtNumChannels = binary.LittleEndian.Uint16(fmtData[2:4])
 						fmtSampleRate = binary.LittleEndian.Uint32(fmtData[4:8])
 						fmtBitsPerSample = binary.LittleEndian.Uint16(fmtData[14:16])
@@ -0,0 +1,94 @@
+package analyze
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
+)
+
+// clipLevel is the magnitude at or above which a sample counts as clipped:
+// the largest positive 16-bit sample.
+const clipLevel = 32767.0 / 32768.0
+
+// Levels are the sample statistics of decoded audio.
+type Levels struct {
+	// Frames is the number of decoded sample frames.
+	Frames     int           `json:"frames"`
+	Duration   time.Duration `json:"-"`
+	SampleRate int           `json:"sample_rate"`
+	Channels   int           `json:"channels"`
+	// Peak and RMS are in dBFS.
+	Peak     loudness.Level `json:"peak_dbfs"`
+	RMS      loudness.Level `json:"rms_dbfs"`
+	Loudness loudness.Stats `json:"loudness"`
+	// Clipped counts the samples at full scale.
+	Clipped int `json:"clipped_samples"`
+	// DCOffset is the mean sample value, as a fraction of full scale.
+	DCOffset float64 `json:"dc_offset"`
+}
+
+// AnalyzeLevels decodes audio of contentType from r and measures its peak,
+// RMS and loudness, clipping and DC offset.
+func AnalyzeLevels(r io.Reader, contentType string) (*Levels, error) {
+	decoder, format, err := decode.DecodeAudio(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := decoder.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var frames [][2]float64
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := decoder.Stream(buf)
+		frames = append(frames, buf[:n]...)
+		if !ok {
+			break
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return nil, err
+	}
+	return MeasureLevels(frames, format.NumChannels, int(format.SampleRate)), nil
+}
+
+// MeasureLevels measures decoded frames of audio with the given channel
+// count and sample rate. Mono audio is measured from the first channel.
+func MeasureLevels(frames [][2]float64, channels, rate int) *Levels {
+	channels = max(1, min(channels, 2))
+	l := &Levels{
+		Frames:     len(frames),
+		SampleRate: rate,
+		Channels:   channels,
+		Loudness:   loudness.Measure(frames, channels, rate),
+	}
+	if rate > 0 {
+		l.Duration = time.Duration(len(frames)) * time.Second / time.Duration(rate)
+	}
+
+	var peak, sumSquares, sum float64
+	for _, f := range frames {
+		for c := 0; c < channels; c++ {
+			v := f[c]
+			peak = max(peak, math.Abs(v))
+			sumSquares += v * v
+			sum += v
+			if math.Abs(v) >= clipLevel {
+				l.Clipped++
+			}
+		}
+	}
+	samples := float64(len(frames) * channels)
+	l.Peak = loudness.Level(math.Inf(-1))
+	l.RMS = loudness.Level(math.Inf(-1))
+	if samples > 0 {
+		l.Peak = loudness.Level(20 * math.Log10(peak))
+		l.RMS = loudness.Level(10 * math.Log10(sumSquares/samples))
+		l.DCOffset = sum / samples
+	}
+	return l
+}
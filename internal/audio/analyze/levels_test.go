@@ -0,0 +1,45 @@
+package analyze
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureLevels(t *testing.T) {
+	rate := 8000
+	frames := make([][2]float64, rate)
+	for i := range frames {
+		v := 0.5*math.Sin(2*math.Pi*100*float64(i)/float64(rate)) + 0.1
+		frames[i] = [2]float64{v, v}
+	}
+	frames[10] = [2]float64{1, 1}
+	frames[20] = [2]float64{-1, -1}
+
+	l := MeasureLevels(frames, 1, rate)
+	if l.Frames != rate || l.Duration.Seconds() != 1 {
+		t.Errorf("Frames, Duration = %d, %v; want %d, 1s", l.Frames, l.Duration, rate)
+	}
+	if l.Peak != 0 {
+		t.Errorf("Peak = %v, want 0 dBFS", l.Peak)
+	}
+	// RMS of a 0.5 sine plus a 0.1 offset: sqrt(0.125 + 0.01) ≈ -8.7 dBFS.
+	if math.Abs(float64(l.RMS)+8.7) > 0.1 {
+		t.Errorf("RMS = %v, want about -8.7 dBFS", l.RMS)
+	}
+	if l.Clipped != 2 {
+		t.Errorf("Clipped = %d, want 2", l.Clipped)
+	}
+	if math.Abs(l.DCOffset-0.1) > 0.001 {
+		t.Errorf("DCOffset = %v, want 0.1", l.DCOffset)
+	}
+}
+
+func TestMeasureLevels_Silence(t *testing.T) {
+	l := MeasureLevels(make([][2]float64, 100), 2, 8000)
+	if !math.IsInf(float64(l.Peak), -1) || !math.IsInf(float64(l.RMS), -1) {
+		t.Errorf("Peak, RMS = %v, %v; want -inf", l.Peak, l.RMS)
+	}
+	if l.Clipped != 0 || l.DCOffset != 0 {
+		t.Errorf("Clipped, DCOffset = %d, %v; want 0, 0", l.Clipped, l.DCOffset)
+	}
+}
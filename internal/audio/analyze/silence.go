@@ -0,0 +1,23 @@
+package analyze
+
+// SilentEnds returns how many of the leading and trailing amplitudes are at
+// or below threshold. Runs of silence shorter than minCount amplitudes count
+// as none, and audio that is silent throughout has no silent ends.
+func SilentEnds(amplitudes []float64, threshold float64, minCount int) (lead, trail int) {
+	for lead < len(amplitudes) && amplitudes[lead] <= threshold {
+		lead++
+	}
+	if lead == len(amplitudes) {
+		return 0, 0
+	}
+	for trail < len(amplitudes) && amplitudes[len(amplitudes)-1-trail] <= threshold {
+		trail++
+	}
+	if lead < minCount {
+		lead = 0
+	}
+	if trail < minCount {
+		trail = 0
+	}
+	return lead, trail
+}
@@ -0,0 +1,160 @@
+// Package codec handles headerless audio: 16-bit PCM and the G.711 μ-law
+// and A-law encodings used in telephony. Such audio carries no header, so its
+// sample rate and channel count travel as content type parameters, as in
+// "audio/x-mulaw;channels=1;rate=8000".
+package codec
+
+import (
+	"mime"
+	"strconv"
+	"time"
+)
+
+// Content types of the raw encodings.
+const (
+	ContentTypePCM   = "audio/pcm"
+	ContentTypeMulaw = "audio/x-mulaw"
+	ContentTypeAlaw  = "audio/x-alaw"
+)
+
+// TelephonyRate is the sample rate of G.711 audio.
+const TelephonyRate = 8000
+
+// DefaultPCMRate is the sample rate assumed for raw PCM without a rate
+// parameter.
+const DefaultPCMRate = 24000
+
+// Encoding is how each sample of headerless audio is stored.
+type Encoding int
+
+const (
+	// PCM16 is signed 16-bit little-endian linear PCM.
+	PCM16 Encoding = iota
+	// Mulaw is G.711 μ-law, one byte per sample.
+	Mulaw
+	// Alaw is G.711 A-law, one byte per sample.
+	Alaw
+)
+
+// ParseEncoding returns the encoding for a format name: pcm, mulaw (or
+// ulaw) or alaw.
+func ParseEncoding(name string) (Encoding, bool) {
+	switch name {
+	case "pcm":
+		return PCM16, true
+	case "mulaw", "ulaw":
+		return Mulaw, true
+	case "alaw":
+		return Alaw, true
+	}
+	return 0, false
+}
+
+// String returns the encoding's format name.
+func (e Encoding) String() string {
+	switch e {
+	case Mulaw:
+		return "mulaw"
+	case Alaw:
+		return "alaw"
+	default:
+		return "pcm"
+	}
+}
+
+// ContentType returns the content type of the encoding, without parameters.
+func (e Encoding) ContentType() string {
+	switch e {
+	case Mulaw:
+		return ContentTypeMulaw
+	case Alaw:
+		return ContentTypeAlaw
+	default:
+		return ContentTypePCM
+	}
+}
+
+// DefaultRate is the sample rate usually used with the encoding.
+func (e Encoding) DefaultRate() int {
+	if e == PCM16 {
+		return DefaultPCMRate
+	}
+	return TelephonyRate
+}
+
+// BytesPerSample is the size of one sample of one channel.
+func (e Encoding) BytesPerSample() int {
+	if e == PCM16 {
+		return 2
+	}
+	return 1
+}
+
+// Format describes headerless audio.
+type Format struct {
+	Encoding    Encoding
+	SampleRate  int
+	NumChannels int
+}
+
+// ContentType returns the format as a content type with rate and channels
+// parameters.
+func (f Format) ContentType() string {
+	return mime.FormatMediaType(f.Encoding.ContentType(), map[string]string{
+		"rate":     strconv.Itoa(f.SampleRate),
+		"channels": strconv.Itoa(f.NumChannels),
+	})
+}
+
+// FrameSize is the size of one sample of every channel.
+func (f Format) FrameSize() int {
+	return f.Encoding.BytesPerSample() * f.NumChannels
+}
+
+// Duration returns the playing time of n bytes of audio in the format.
+func (f Format) Duration(n int) time.Duration {
+	if f.SampleRate <= 0 || f.FrameSize() <= 0 {
+		return 0
+	}
+	frames := n / f.FrameSize()
+	return time.Duration(frames) * time.Second / time.Duration(f.SampleRate)
+}
+
+// ParseContentType reports whether contentType names a raw encoding and, if
+// so, returns its format. A missing rate or channels parameter takes the
+// encoding's default rate or mono. The common aliases "audio/basic" and
+// "audio/pcmu" for μ-law and "audio/pcma" for A-law are accepted.
+func ParseContentType(contentType string) (Format, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Format{}, false
+	}
+
+	var f Format
+	switch mediaType {
+	case ContentTypePCM:
+		f.Encoding = PCM16
+	case ContentTypeMulaw, "audio/mulaw", "audio/pcmu", "audio/basic":
+		f.Encoding = Mulaw
+	case ContentTypeAlaw, "audio/alaw", "audio/pcma":
+		f.Encoding = Alaw
+	default:
+		return Format{}, false
+	}
+
+	f.SampleRate = f.Encoding.DefaultRate()
+	if rate, err := strconv.Atoi(params["rate"]); err == nil && rate > 0 {
+		f.SampleRate = rate
+	}
+	f.NumChannels = 1
+	if ch, err := strconv.Atoi(params["channels"]); err == nil && ch > 0 {
+		f.NumChannels = ch
+	}
+	return f, true
+}
+
+// IsRaw reports whether contentType names a raw encoding.
+func IsRaw(contentType string) bool {
+	_, ok := ParseContentType(contentType)
+	return ok
+}
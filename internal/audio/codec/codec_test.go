@@ -0,0 +1,127 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Format
+		ok          bool
+	}{
+		{"audio/pcm", Format{PCM16, 24000, 1}, true},
+		{"audio/x-mulaw", Format{Mulaw, 8000, 1}, true},
+		{"audio/basic", Format{Mulaw, 8000, 1}, true},
+		{"audio/x-alaw;rate=16000;channels=2", Format{Alaw, 16000, 2}, true},
+		{"audio/PCM; rate=44100", Format{PCM16, 44100, 1}, true},
+		{"audio/wav", Format{}, false},
+		{"", Format{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseContentType(tt.contentType)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseContentType(%q) = %+v, %v; want %+v, %v", tt.contentType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormat_ContentTypeRoundTrip(t *testing.T) {
+	f := Format{Encoding: Mulaw, SampleRate: 8000, NumChannels: 1}
+	if got := f.ContentType(); got != "audio/x-mulaw; channels=1; rate=8000" {
+		t.Errorf("ContentType() = %q", got)
+	}
+	if got, _ := ParseContentType(f.ContentType()); got != f {
+		t.Errorf("parsed back as %+v, want %+v", got, f)
+	}
+	if d := (Format{PCM16, 16000, 2}).Duration(64000); d != time.Second {
+		t.Errorf("Duration = %v, want 1s", d)
+	}
+}
+
+func TestToWAV(t *testing.T) {
+	data := []byte{MulawEncode(1000), MulawEncode(-1000), 0xFF}
+	wav := ToWAV(data, Format{Encoding: Mulaw, SampleRate: 8000, NumChannels: 1})
+	if !bytes.HasPrefix(wav, []byte("RIFF")) || len(wav) != 44+6 {
+		t.Fatalf("unexpected WAV of %d bytes", len(wav))
+	}
+	if rate := binary.LittleEndian.Uint32(wav[24:28]); rate != 8000 {
+		t.Errorf("sample rate = %d, want 8000", rate)
+	}
+	if size := binary.LittleEndian.Uint32(wav[40:44]); size != 6 {
+		t.Errorf("data size = %d, want 6", size)
+	}
+	if got := int16(binary.LittleEndian.Uint16(wav[44:])); got != MulawDecode(data[0]) {
+		t.Errorf("first sample = %d, want %d", got, MulawDecode(data[0]))
+	}
+}
+
+// constStreamer produces n stereo frames of the given left and right values.
+type constStreamer struct {
+	left, right float64
+	n           int
+}
+
+func (s *constStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.n == 0 {
+		return 0, false
+	}
+	n := min(len(samples), s.n)
+	for i := range samples[:n] {
+		samples[i] = [2]float64{s.left, s.right}
+	}
+	s.n -= n
+	return n, true
+}
+
+func (s *constStreamer) Err() error { return nil }
+
+func TestNewReader_MixesDown(t *testing.T) {
+	src := &constStreamer{left: 0.5, right: 0, n: 8000}
+	data, err := io.ReadAll(NewReader(src, Format{Encoding: PCM16, SampleRate: 8000, NumChannels: 1}))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if frames := len(data) / 2; frames != 8000 {
+		t.Errorf("got %d frames, want 8000", frames)
+	}
+	if got := int16(binary.LittleEndian.Uint16(data[len(data)/2&^1:])); got != toInt16(0.25) {
+		t.Errorf("mid sample = %d, want %d", got, toInt16(0.25))
+	}
+
+	src = &constStreamer{left: 0.5, right: 0.5, n: 100}
+	data, _ = io.ReadAll(NewReader(src, Format{Encoding: Alaw, SampleRate: 8000, NumChannels: 1}))
+	if len(data) != 100 || data[0] != AlawEncode(toInt16(0.5)) {
+		t.Errorf("A-law output: %d bytes starting %#x", len(data), data[0])
+	}
+}
+
+func TestDownmixMatrix(t *testing.T) {
+	if DownmixMatrix(2, 0) != nil || DownmixMatrix(1, 0) != nil {
+		t.Error("mono and stereo should need no mixing")
+	}
+
+	// Quad without a mask: front left, front right, center, LFE.
+	gains := DownmixMatrix(4, 0)
+	scale := 1 / (1 + centerGain)
+	want := [][2]float64{{scale, 0}, {0, scale}, {centerGain * scale, centerGain * scale}, {0, 0}}
+	for i := range want {
+		for side := 0; side < 2; side++ {
+			if math.Abs(gains[i][side]-want[i][side]) > 1e-9 {
+				t.Errorf("gains[%d] = %v, want %v", i, gains[i], want[i])
+			}
+		}
+	}
+
+	// A mask of side left and side right places the first two channels
+	// there; the third has no position.
+	gains = DownmixMatrix(3, 1<<9|1<<10)
+	if gains[0][1] != 0 || gains[1][0] != 0 || gains[2][0] != gains[2][1] {
+		t.Errorf("masked gains = %v", gains)
+	}
+}
@@ -0,0 +1,64 @@
+package codec
+
+import "math"
+
+// centerGain is the gain that keeps a signal split between two speakers as
+// loud as it was in one.
+const centerGain = math.Sqrt2 / 2
+
+// speakerGains are the left and right gains of the speaker positions of a
+// WAVE_FORMAT_EXTENSIBLE channel mask, indexed by bit: front left and
+// right, front center, low frequency, back left and right, front left and
+// right of center, back center, side left and right, top center, then the
+// front and back rows of top speakers. The low frequency channel is left
+// out, as it is when a receiver has no subwoofer.
+var speakerGains = [...][2]float64{
+	{1, 0}, {0, 1}, {centerGain, centerGain}, {0, 0},
+	{centerGain, 0}, {0, centerGain},
+	{0.924, 0.383}, {0.383, 0.924}, {0.5, 0.5},
+	{centerGain, 0}, {0, centerGain}, {0.5, 0.5},
+	{centerGain, 0}, {0.5, 0.5}, {0, centerGain},
+	{centerGain, 0}, {0.5, 0.5}, {0, centerGain},
+}
+
+// DownmixMatrix returns the left and right gains of each channel of audio
+// with more than two channels, for mixing it down to stereo, or nil for
+// mono and stereo audio. mask is the channel mask of a WAVE_FORMAT_EXTENSIBLE
+// header, which assigns speaker positions to the channels in order; with no
+// mask, the channels take the positions in order from front left. Channels
+// with no known position go to both sides equally. The gains of each side
+// are scaled to sum to at most 1, so the mix can't clip.
+func DownmixMatrix(channels int, mask uint32) [][2]float64 {
+	if channels <= 2 {
+		return nil
+	}
+	gains := make([][2]float64, channels)
+	ch := 0
+	for bit := 0; bit < 32 && ch < channels; bit++ {
+		if mask != 0 && mask&(1<<bit) == 0 {
+			continue
+		}
+		gains[ch] = [2]float64{0.5, 0.5}
+		if bit < len(speakerGains) {
+			gains[ch] = speakerGains[bit]
+		}
+		ch++
+	}
+	for ; ch < channels; ch++ {
+		gains[ch] = [2]float64{0.5, 0.5}
+	}
+
+	var sum [2]float64
+	for _, g := range gains {
+		sum[0] += g[0]
+		sum[1] += g[1]
+	}
+	for i := range gains {
+		for side := 0; side < 2; side++ {
+			if sum[side] > 1 {
+				gains[i][side] /= sum[side]
+			}
+		}
+	}
+	return gains
+}
@@ -0,0 +1,133 @@
+package codec
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+)
+
+func toInt16(v float64) int16 {
+	v = math.Max(-1, math.Min(1, v))
+	return int16(math.Round(v * 32767))
+}
+
+func (f Format) appendSample(dst []byte, v int16) []byte {
+	switch f.Encoding {
+	case Mulaw:
+		return append(dst, MulawEncode(v))
+	case Alaw:
+		return append(dst, AlawEncode(v))
+	default:
+		return binary.LittleEndian.AppendUint16(dst, uint16(v))
+	}
+}
+
+func (f Format) sample(b []byte) int16 {
+	switch f.Encoding {
+	case Mulaw:
+		return MulawDecode(b[0])
+	case Alaw:
+		return AlawDecode(b[0])
+	default:
+		return int16(binary.LittleEndian.Uint16(b))
+	}
+}
+
+// AppendFrames encodes stereo frames with samples in [-1, 1] and appends
+// them to dst. Mono output takes the average of the two channels, and
+// channels past the second repeat it.
+func (f Format) AppendFrames(dst []byte, frames [][2]float64) []byte {
+	for _, frame := range frames {
+		if f.NumChannels == 1 {
+			dst = f.appendSample(dst, toInt16((frame[0]+frame[1])/2))
+			continue
+		}
+		for ch := 0; ch < f.NumChannels; ch++ {
+			dst = f.appendSample(dst, toInt16(frame[min(ch, 1)]))
+		}
+	}
+	return dst
+}
+
+// DecodeFrames decodes the whole frames in data into dst and returns how
+// many it decoded. Mono is copied to both channels, and more than two
+// channels are mixed down to stereo as DownmixMatrix says.
+func (f Format) DecodeFrames(dst [][2]float64, data []byte) int {
+	size := f.FrameSize()
+	if size <= 0 {
+		return 0
+	}
+	n := min(len(dst), len(data)/size)
+	width := f.Encoding.BytesPerSample()
+	mix := DownmixMatrix(f.NumChannels, 0)
+	for i := 0; i < n; i++ {
+		frame := data[i*size:]
+		if mix != nil {
+			var left, right float64
+			for ch, g := range mix {
+				v := float64(f.sample(frame[ch*width:])) / 32768.0
+				left += g[0] * v
+				right += g[1] * v
+			}
+			dst[i] = [2]float64{left, right}
+			continue
+		}
+		for ch := 0; ch < f.NumChannels; ch++ {
+			dst[i][ch] = float64(f.sample(frame[ch*width:])) / 32768.0
+		}
+		if f.NumChannels == 1 {
+			dst[i][1] = dst[i][0]
+		}
+	}
+	return n
+}
+
+// ToWAV converts raw audio in format f to a 16-bit PCM WAV file.
+func ToWAV(data []byte, f Format) []byte {
+	pcm := Format{Encoding: PCM16, SampleRate: f.SampleRate, NumChannels: f.NumChannels}
+	out := metadata.StreamingWavHeader(f.SampleRate, f.NumChannels)
+	if f.Encoding == PCM16 {
+		out = append(out, data[:len(data)/f.FrameSize()*f.FrameSize()]...)
+	} else {
+		width := f.Encoding.BytesPerSample()
+		for i := 0; i+width <= len(data); i += width {
+			out = pcm.appendSample(out, f.sample(data[i:]))
+		}
+	}
+	return metadata.FixWavHeader(out)
+}
+
+// NewReader encodes the audio s produces into format f, mixing down to mono
+// as needed. s must already be at f's sample rate; the convert package
+// resamples. Errors from s are returned by Read.
+func NewReader(s beep.Streamer, f Format) io.Reader {
+	return &encodingReader{s: s, f: f, frames: make([][2]float64, 512)}
+}
+
+type encodingReader struct {
+	s       beep.Streamer
+	f       Format
+	frames  [][2]float64
+	pending []byte
+	buf     []byte
+}
+
+func (r *encodingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		n, ok := r.s.Stream(r.frames)
+		if n == 0 && !ok {
+			if err := r.s.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.buf = r.f.AppendFrames(r.buf[:0], r.frames[:n])
+		r.pending = r.buf
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,92 @@
+package codec
+
+// G.711 companding, as in the ITU reference implementation: 16-bit linear
+// samples are compressed to 8 bits with a roughly logarithmic curve.
+
+const (
+	mulawBias = 0x84
+	mulawClip = 32635
+)
+
+// MulawEncode compresses a linear sample to μ-law.
+func MulawEncode(sample int16) byte {
+	v := int(sample)
+	var sign byte
+	if v < 0 {
+		v = -v
+		sign = 0x80
+	}
+	if v > mulawClip {
+		v = mulawClip
+	}
+	v += mulawBias
+
+	exponent := 7
+	for mask := 0x4000; v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (v >> (exponent + 3)) & 0x0F
+	return ^(sign | byte(exponent<<4) | byte(mantissa))
+}
+
+// MulawDecode expands a μ-law byte to a linear sample.
+func MulawDecode(b byte) int16 {
+	b = ^b
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0F)
+	v := ((mantissa<<3)+mulawBias)<<exponent - mulawBias
+	if b&0x80 != 0 {
+		return int16(-v)
+	}
+	return int16(v)
+}
+
+// alawSegmentEnds are the upper bounds of the A-law segments, for 13-bit
+// magnitudes.
+var alawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// AlawEncode compresses a linear sample to A-law.
+func AlawEncode(sample int16) byte {
+	v := int(sample) >> 3
+	mask := byte(0xD5)
+	if v < 0 {
+		mask = 0x55
+		v = -v - 1
+	}
+
+	segment := 0
+	for segment < len(alawSegmentEnds) && v > alawSegmentEnds[segment] {
+		segment++
+	}
+	if segment >= len(alawSegmentEnds) {
+		return 0x7F ^ mask
+	}
+
+	a := byte(segment << 4)
+	if segment < 2 {
+		a |= byte(v>>1) & 0x0F
+	} else {
+		a |= byte(v>>segment) & 0x0F
+	}
+	return a ^ mask
+}
+
+// AlawDecode expands an A-law byte to a linear sample.
+func AlawDecode(b byte) int16 {
+	b ^= 0x55
+	t := int(b&0x0F) << 4
+	segment := int(b&0x70) >> 4
+	switch segment {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= segment - 1
+	}
+	if b&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
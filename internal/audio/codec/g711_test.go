@@ -0,0 +1,55 @@
+package codec
+
+import "testing"
+
+func TestG711_KnownValues(t *testing.T) {
+	tests := []struct {
+		sample     int16
+		mulaw      byte
+		alaw       byte
+		mulawValue int16
+	}{
+		{0, 0xFF, 0xD5, 0},
+		{32767, 0x80, 0xAA, 32124},
+		{-32768, 0x00, 0x2A, -32124},
+	}
+	for _, tt := range tests {
+		if got := MulawEncode(tt.sample); got != tt.mulaw {
+			t.Errorf("MulawEncode(%d) = %#x, want %#x", tt.sample, got, tt.mulaw)
+		}
+		if got := AlawEncode(tt.sample); got != tt.alaw {
+			t.Errorf("AlawEncode(%d) = %#x, want %#x", tt.sample, got, tt.alaw)
+		}
+		if got := MulawDecode(tt.mulaw); got != tt.mulawValue {
+			t.Errorf("MulawDecode(%#x) = %d, want %d", tt.mulaw, got, tt.mulawValue)
+		}
+	}
+}
+
+func TestG711_RoundTrip(t *testing.T) {
+	// Every code decodes to a value that encodes back to the same value.
+	for b := 0; b < 256; b++ {
+		if v := MulawDecode(byte(b)); MulawDecode(MulawEncode(v)) != v {
+			t.Errorf("μ-law %#x: %d does not round-trip", b, v)
+		}
+		if v := AlawDecode(byte(b)); AlawDecode(AlawEncode(v)) != v {
+			t.Errorf("A-law %#x: %d does not round-trip", b, v)
+		}
+	}
+
+	// Quantization error stays within a few percent of the signal.
+	for _, s := range []int16{100, -100, 1000, -1000, 12345, -12345, 30000, -30000} {
+		for name, got := range map[string]int16{
+			"μ-law": MulawDecode(MulawEncode(s)),
+			"A-law": AlawDecode(AlawEncode(s)),
+		} {
+			diff := int(got) - int(s)
+			if diff < 0 {
+				diff = -diff
+			}
+			if limit := int(s) / 16; diff > max(limit, -limit, 16) {
+				t.Errorf("%s: %d came back as %d", name, s, got)
+			}
+		}
+	}
+}
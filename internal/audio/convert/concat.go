@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
+)
+
+// Part is one clip to concatenate.
+type Part struct {
+	// Name identifies the part in errors, e.g. its file name.
+	Name        string
+	Data        []byte
+	ContentType string
+}
+
+// ConcatOptions describe how Concat joins parts.
+type ConcatOptions struct {
+	// Gap is silence inserted between parts.
+	Gap time.Duration
+	// Crossfade fades each part out and the next one in over this long,
+	// overlapping them by as much, so the joins don't click.
+	Crossfade time.Duration
+	// Resample converts parts whose sample rate or channel count differ
+	// from the first part's, instead of failing.
+	Resample bool
+}
+
+// Concat decodes parts and joins them one after another, encoded as
+// outContentType: "audio/wav" or a headerless format. The output has the
+// first part's sample rate, channel count and bit depth; headerless output
+// keeps its encoding's own bit depth.
+func Concat(parts []Part, outContentType string, opts ConcatOptions) (*Result, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("nothing to concatenate")
+	}
+	if opts.Gap < 0 || opts.Crossfade < 0 {
+		return nil, fmt.Errorf("gap and crossfade must not be negative")
+	}
+	raw, err := outputFormat(outContentType, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var out [][2]float64
+	var first beep.Format
+	var prevLen int
+	for i, part := range parts {
+		decoder, in, err := decode.DecodeAudio(bytes.NewReader(part.Data), part.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.Name, err)
+		}
+		var s beep.Streamer = decoder
+		if i == 0 {
+			first = in
+		} else if in.SampleRate != first.SampleRate || in.NumChannels != first.NumChannels {
+			if !opts.Resample {
+				return nil, fmt.Errorf("%s is %s but %s is %s (use --resample to convert it)",
+					part.Name, describeFormat(in), parts[0].Name, describeFormat(first))
+			}
+			s = Resample(s, in.SampleRate, first.SampleRate)
+		}
+		frames, err := readFrames(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.Name, err)
+		}
+		if i > 0 && first.NumChannels == 1 && in.NumChannels != 1 {
+			for j, f := range frames {
+				mono := (f[0] + f[1]) / 2
+				frames[j] = [2]float64{mono, mono}
+			}
+		}
+
+		if i == 0 {
+			out = frames
+		} else {
+			fade := min(first.SampleRate.N(opts.Crossfade), prevLen, len(frames))
+			out = appendPart(out, frames, fade, first.SampleRate.N(opts.Gap))
+		}
+		prevLen = len(frames)
+	}
+
+	o := Options{}.resolve(first)
+	data, contentType, err := encode(&frameStreamer{frames: out}, first, raw, o)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Data: data, ContentType: contentType}, nil
+}
+
+// appendPart appends next to out, gap frames after it ends, with the last
+// fade frames of out faded out and the first fade frames of next faded in
+// over them. Equal-power curves keep the overlap as loud as its parts.
+func appendPart(out, next [][2]float64, fade, gap int) [][2]float64 {
+	end := len(out)
+	for k := 0; k < fade; k++ {
+		g := math.Cos(math.Pi / 2 * (float64(k) + 0.5) / float64(fade))
+		out[end-fade+k][0] *= g
+		out[end-fade+k][1] *= g
+	}
+	start := end - fade + gap
+	if total := start + len(next); total > len(out) {
+		out = append(out, make([][2]float64, total-len(out))...)
+	}
+	for k, f := range next {
+		if k < fade {
+			g := math.Sin(math.Pi / 2 * (float64(k) + 0.5) / float64(fade))
+			f[0] *= g
+			f[1] *= g
+		}
+		out[start+k][0] += f[0]
+		out[start+k][1] += f[1]
+	}
+	return out
+}
+
+// describeFormat describes the sample rate and channels of f, e.g. "24000 Hz
+// mono".
+func describeFormat(f beep.Format) string {
+	channels := "mono"
+	if f.NumChannels != 1 {
+		channels = fmt.Sprintf("%d-channel", f.NumChannels)
+		if f.NumChannels == 2 {
+			channels = "stereo"
+		}
+	}
+	return fmt.Sprintf("%d Hz %s", int(f.SampleRate), channels)
+}
@@ -0,0 +1,369 @@
+// Package convert changes the sample rate, channel count and bit depth of
+// audio, and trims, pads and normalizes it. Audio is decoded to beep samples
+// by the decode package, resampled, mixed and requantized with dither, and
+// encoded again as WAV or as the headerless formats of the codec package.
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"mime"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+)
+
+// Options describe the output of a conversion. Zero fields keep the value
+// of the input.
+type Options struct {
+	SampleRate  int
+	NumChannels int
+	BitDepth    int
+	// NoDither turns off the triangular dither added when samples are
+	// rounded to the output bit depth.
+	NoDither bool
+	// Normalize, if set, is the integrated loudness in LUFS to bring the
+	// audio to; see loudness.Normalize.
+	Normalize *float64
+	// TrimSilence, if set, removes silence from the start and end of the
+	// audio.
+	TrimSilence *TrimOptions
+	// PadStart and PadEnd are silence added before and after the audio,
+	// once any silence has been trimmed.
+	PadStart, PadEnd time.Duration
+}
+
+// Validate checks that the options describe audio Convert can write.
+func (o Options) Validate() error {
+	if o.SampleRate < 0 {
+		return fmt.Errorf("sample rate must not be negative")
+	}
+	if o.NumChannels < 0 || o.NumChannels > 2 {
+		return fmt.Errorf("channels must be 1 or 2")
+	}
+	switch o.BitDepth {
+	case 0, 8, 16, 24, 32:
+	default:
+		return fmt.Errorf("unsupported bit depth: %d (supported: 8, 16, 24, 32)", o.BitDepth)
+	}
+	if o.TrimSilence != nil {
+		if o.TrimSilence.Threshold >= 0 {
+			return fmt.Errorf("silence threshold must be below 0 dBFS, got %g", o.TrimSilence.Threshold)
+		}
+		if o.TrimSilence.MinDuration < 0 {
+			return fmt.Errorf("minimum silence duration must not be negative, got %s", o.TrimSilence.MinDuration)
+		}
+	}
+	if o.PadStart < 0 || o.PadEnd < 0 {
+		return fmt.Errorf("padding must not be negative")
+	}
+	return nil
+}
+
+// resolve fills the zero fields of o from the input format.
+func (o Options) resolve(in beep.Format) Options {
+	if o.SampleRate == 0 {
+		o.SampleRate = int(in.SampleRate)
+	}
+	if o.NumChannels == 0 {
+		o.NumChannels = min(in.NumChannels, 2)
+	}
+	if o.BitDepth == 0 {
+		// 64-bit float input is written as 32-bit integers.
+		o.BitDepth = min(in.Precision*8, 32)
+		if o.BitDepth != 8 && o.BitDepth != 24 && o.BitDepth != 32 {
+			o.BitDepth = 16
+		}
+	}
+	return o
+}
+
+// quantizer rounds samples in [-1, 1] to signed integers of a given bit
+// depth. Samples that fall between two levels get TPDF dither: the sum of
+// two uniform random values one level wide, which turns rounding error into
+// steady noise instead of distortion that follows the signal. Samples that
+// are already on a level, such as unprocessed input of the same depth, pass
+// through unchanged.
+type quantizer struct {
+	scale  float64
+	dither bool
+	rng    *rand.Rand
+}
+
+func newQuantizer(bits int, dither bool) *quantizer {
+	return &quantizer{
+		scale:  float64(int64(1) << (bits - 1)),
+		dither: dither && bits < 24,
+		rng:    rand.New(rand.NewSource(1)),
+	}
+}
+
+func (q *quantizer) quantize(v float64) int64 {
+	x := v * q.scale
+	if q.dither && x != math.Trunc(x) {
+		x += q.rng.Float64() - q.rng.Float64()
+	}
+	return int64(math.Max(-q.scale, math.Min(q.scale-1, math.Round(x))))
+}
+
+// wavEncoder writes integer PCM WAV samples.
+type wavEncoder struct {
+	channels int
+	bits     int
+	q        *quantizer
+}
+
+func (e *wavEncoder) appendFrames(dst []byte, frames [][2]float64) []byte {
+	for _, frame := range frames {
+		if e.channels == 1 {
+			dst = e.appendSample(dst, (frame[0]+frame[1])/2)
+			continue
+		}
+		dst = e.appendSample(dst, frame[0])
+		dst = e.appendSample(dst, frame[1])
+	}
+	return dst
+}
+
+func (e *wavEncoder) appendSample(dst []byte, v float64) []byte {
+	s := e.q.quantize(v)
+	switch e.bits {
+	case 8:
+		return append(dst, byte(s+128))
+	case 24:
+		return append(dst, byte(s), byte(s>>8), byte(s>>16))
+	case 32:
+		return binary.LittleEndian.AppendUint32(dst, uint32(s))
+	default:
+		return binary.LittleEndian.AppendUint16(dst, uint16(s))
+	}
+}
+
+// NewWAVReader returns the audio s produces, in format in, converted as
+// opts say and encoded as a WAV stream. The header carries the placeholder
+// sizes of a streaming response; metadata.FixWavHeader corrects them.
+// Errors from s are returned by Read.
+func NewWAVReader(s beep.Streamer, in beep.Format, opts Options) io.Reader {
+	opts = opts.resolve(in)
+	enc := &wavEncoder{
+		channels: opts.NumChannels,
+		bits:     opts.BitDepth,
+		q:        newQuantizer(opts.BitDepth, !opts.NoDither),
+	}
+	return &encodingReader{
+		s:       Resample(s, in.SampleRate, beep.SampleRate(opts.SampleRate)),
+		encode:  enc.appendFrames,
+		pending: metadata.StreamingWavHeaderBits(opts.SampleRate, opts.NumChannels, opts.BitDepth),
+		frames:  make([][2]float64, 512),
+	}
+}
+
+// NewRawReader returns the audio s produces, in format in, resampled and
+// mixed to match f and encoded as headerless audio.
+func NewRawReader(s beep.Streamer, in beep.Format, f codec.Format) io.Reader {
+	return codec.NewReader(Resample(s, in.SampleRate, beep.SampleRate(f.SampleRate)), f)
+}
+
+type encodingReader struct {
+	s       beep.Streamer
+	encode  func(dst []byte, frames [][2]float64) []byte
+	frames  [][2]float64
+	pending []byte
+	buf     []byte
+}
+
+func (r *encodingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		n, ok := r.s.Stream(r.frames)
+		if n == 0 && !ok {
+			if err := r.s.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.buf = r.encode(r.buf[:0], r.frames[:n])
+		r.pending = r.buf
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Result is the output of Convert.
+type Result struct {
+	Data []byte
+	// ContentType is "audio/wav" or, for headerless audio, the encoding
+	// with rate and channels parameters.
+	ContentType string
+	// Loudness is set when the audio was normalized.
+	Loudness *loudness.Report
+	// Offset is how far the audio moved in time: the padding added at the
+	// start less the silence trimmed from it.
+	Offset time.Duration
+}
+
+// Convert decodes data, which is WAV, MP3 or headerless audio as
+// contentType says, and encodes it as outContentType: "audio/wav" or a
+// headerless format, whose own rate and channels parameters are ignored.
+// Headerless output is always at its encoding's bit depth. WAV output keeps
+// the metadata of WAV and MP3 input, and its header holds the sizes of the
+// audio as trimmed and padded.
+func Convert(data []byte, contentType, outContentType string, opts Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := outputFormat(outContentType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, in, err := decode.DecodeAudio(bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.resolve(in)
+
+	var s beep.Streamer = decoder
+	result := &Result{}
+	if opts.TrimSilence != nil || opts.PadStart > 0 || opts.PadEnd > 0 {
+		e := &edited{
+			s:        decoder,
+			remain:   -1,
+			padStart: in.SampleRate.N(opts.PadStart),
+			padEnd:   in.SampleRate.N(opts.PadEnd),
+		}
+		if opts.TrimSilence != nil {
+			start, end, err := trimBounds(data, contentType, in.SampleRate, *opts.TrimSilence)
+			if err != nil {
+				return nil, err
+			}
+			e.skip = start
+			if end >= 0 {
+				e.remain = end - start
+			}
+		}
+		result.Offset = in.SampleRate.D(e.padStart - e.skip)
+		s = e
+	}
+	if opts.Normalize != nil {
+		s, in, result.Loudness, err = normalize(s, in, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.Data, result.ContentType, err = encode(s, in, raw, opts)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		if meta := sourceMetadata(data, contentType); meta != (metadata.WavMetadata{}) {
+			result.Data = metadata.EmbedMetadata(result.Data, meta)
+		}
+	}
+	return result, nil
+}
+
+// outputFormat checks that audio can be encoded as outContentType with
+// opts. It returns the headerless format to write, or nil for WAV.
+func outputFormat(outContentType string, opts Options) (*codec.Format, error) {
+	raw, isRaw := codec.ParseContentType(outContentType)
+	if mediaType, _, _ := mime.ParseMediaType(outContentType); !isRaw && mediaType != "audio/wav" {
+		return nil, fmt.Errorf("cannot encode %s: only WAV and headerless output are supported", outContentType)
+	}
+	if !isRaw {
+		return nil, nil
+	}
+	if opts.BitDepth != 0 && opts.BitDepth != 8*raw.Encoding.BytesPerSample() {
+		return nil, fmt.Errorf("%s audio is always %d-bit", raw.Encoding, 8*raw.Encoding.BytesPerSample())
+	}
+	return &raw, nil
+}
+
+// encode reads all of s, in format in, and encodes it as WAV or, if raw is
+// set, as headerless audio of raw's encoding, converted as the resolved opts
+// say. It returns the encoded audio and its content type.
+func encode(s beep.Streamer, in beep.Format, raw *codec.Format, opts Options) ([]byte, string, error) {
+	if raw != nil {
+		f := *raw
+		f.SampleRate = opts.SampleRate
+		f.NumChannels = opts.NumChannels
+		data, err := io.ReadAll(NewRawReader(s, in, f))
+		return data, f.ContentType(), err
+	}
+	out, err := io.ReadAll(NewWAVReader(s, in, opts))
+	if err != nil {
+		return nil, "", err
+	}
+	return metadata.FixWavHeader(out), "audio/wav", nil
+}
+
+// readFrames reads s to the end.
+func readFrames(s beep.Streamer) ([][2]float64, error) {
+	var frames [][2]float64
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := s.Stream(buf)
+		frames = append(frames, buf[:n]...)
+		if !ok {
+			break
+		}
+	}
+	return frames, s.Err()
+}
+
+// normalize reads all of s, converts it to the rate and channels of opts,
+// which must be resolved, and normalizes its loudness. It returns the
+// result as a streamer together with its format.
+func normalize(s beep.Streamer, in beep.Format, opts Options) (beep.Streamer, beep.Format, *loudness.Report, error) {
+	frames, err := readFrames(Resample(s, in.SampleRate, beep.SampleRate(opts.SampleRate)))
+	if err != nil {
+		return nil, in, nil, err
+	}
+	if opts.NumChannels == 1 {
+		for i, f := range frames {
+			mono := (f[0] + f[1]) / 2
+			frames[i] = [2]float64{mono, mono}
+		}
+	}
+
+	report := loudness.Normalize(frames, opts.NumChannels, opts.SampleRate, *opts.Normalize)
+	in.SampleRate = beep.SampleRate(opts.SampleRate)
+	in.NumChannels = opts.NumChannels
+	return &frameStreamer{frames: frames}, in, &report, nil
+}
+
+// frameStreamer streams frames held in memory.
+type frameStreamer struct {
+	frames [][2]float64
+}
+
+func (f *frameStreamer) Stream(samples [][2]float64) (int, bool) {
+	n := copy(samples, f.frames)
+	f.frames = f.frames[n:]
+	return n, n > 0
+}
+
+func (f *frameStreamer) Err() error {
+	return nil
+}
+
+// sourceMetadata returns the tags of WAV or MP3 data as WAV metadata.
+func sourceMetadata(data []byte, contentType string) metadata.WavMetadata {
+	switch contentType {
+	case "audio/wav":
+		return metadata.ReadMetadata(data)
+	case "audio/mpeg", "audio/mp3":
+		m := metadata.ReadMP3Metadata(data)
+		return metadata.WavMetadata{Artist: m.Artist, Name: m.Title, Comment: m.Comment}
+	}
+	return metadata.WavMetadata{}
+}
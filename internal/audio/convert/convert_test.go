@@ -0,0 +1,293 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/stream"
+)
+
+// pcmWAV returns a 16-bit WAV holding interleaved samples.
+func pcmWAV(rate, channels int, samples ...int16) []byte {
+	var pcm bytes.Buffer
+	binary.Write(&pcm, binary.LittleEndian, samples)
+	return codec.ToWAV(pcm.Bytes(), codec.Format{Encoding: codec.PCM16, SampleRate: rate, NumChannels: channels})
+}
+
+func wavFormat(t *testing.T, data []byte) (rate, channels, bits int) {
+	t.Helper()
+	_, format, err := stream.DecodeStreaming(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output is not a valid WAV: %v", err)
+	}
+	return int(format.SampleRate), format.NumChannels, format.Precision * 8
+}
+
+func TestConvert_SameFormatIsLossless(t *testing.T) {
+	in := pcmWAV(24000, 1, 0, 1, -1, 12345, -32768, 32767)
+	res, err := Convert(in, "audio/wav", "audio/wav", Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if res.ContentType != "audio/wav" || res.Loudness != nil {
+		t.Errorf("content type = %q, loudness = %v", res.ContentType, res.Loudness)
+	}
+	if !bytes.Equal(res.Data, in) {
+		t.Errorf("16-bit copy changed the audio:\n got %x\nwant %x", res.Data, in)
+	}
+}
+
+func TestConvert_ResampleMixAndDepth(t *testing.T) {
+	samples := make([]int16, 2*2400)
+	for i := 0; i < len(samples); i += 2 {
+		samples[i], samples[i+1] = 8000, -4000
+	}
+	res, err := Convert(pcmWAV(24000, 2, samples...), "audio/wav", "audio/wav", Options{SampleRate: 16000, NumChannels: 1, BitDepth: 24})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if rate, channels, bits := wavFormat(t, res.Data); rate != 16000 || channels != 1 || bits != 24 {
+		t.Fatalf("got %d Hz, %d channels, %d bits", rate, channels, bits)
+	}
+	pcm := res.Data[44:]
+	if frames := len(pcm) / 3; frames != 1600 {
+		t.Errorf("got %d frames, want 1600", frames)
+	}
+	mid := pcm[len(pcm)/2/3*3:]
+	v := int32(mid[0]) | int32(mid[1])<<8 | int32(int8(mid[2]))<<16
+	// (8000 - 4000) / 2 at 16 bits is 2000, or 512000 at 24 bits.
+	if v < 511000 || v > 513000 {
+		t.Errorf("mid sample = %d, want about 512000", v)
+	}
+}
+
+func TestConvert_DithersReducedDepth(t *testing.T) {
+	// A constant half a 16-bit step above 1000 rounds the same way every
+	// time without dither, and to both neighbours with it.
+	samples := make([]int16, 2*1000)
+	for i := 0; i < len(samples); i += 2 {
+		samples[i], samples[i+1] = 1000, 1001
+	}
+	in := pcmWAV(8000, 2, samples...)
+
+	levels := func(opts Options) map[int16]bool {
+		opts.NumChannels = 1
+		res, err := Convert(in, "audio/wav", "audio/wav", opts)
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		seen := map[int16]bool{}
+		for i := 44; i+1 < len(res.Data); i += 2 {
+			seen[int16(binary.LittleEndian.Uint16(res.Data[i:]))] = true
+		}
+		return seen
+	}
+
+	if got := levels(Options{NoDither: true}); len(got) != 1 {
+		t.Errorf("without dither got levels %v, want one", got)
+	}
+	if got := levels(Options{}); !got[1000] || !got[1001] {
+		t.Errorf("with dither got levels %v, want 1000 and 1001", got)
+	}
+}
+
+func TestConvert_Int32(t *testing.T) {
+	res, err := Convert(pcmWAV(8000, 1, 16384, -32768), "audio/wav", "audio/wav", Options{BitDepth: 32})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, _, bits := wavFormat(t, res.Data); bits != 32 {
+		t.Fatalf("got %d bits, want 32", bits)
+	}
+	pcm := res.Data[44:]
+	if len(pcm) != 8 || int32(binary.LittleEndian.Uint32(pcm)) != 1<<30 || int32(binary.LittleEndian.Uint32(pcm[4:])) != -1<<31 {
+		t.Errorf("32-bit samples = %x", pcm)
+	}
+
+	// 32-bit input keeps its depth by default.
+	again, err := Convert(res.Data, "audio/wav", "audio/wav", Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !bytes.Equal(again.Data, res.Data) {
+		t.Errorf("32-bit copy changed the audio")
+	}
+}
+
+func TestConvert_Raw(t *testing.T) {
+	res, err := Convert(pcmWAV(8000, 1, 1000, -1000), "audio/wav", codec.ContentTypeMulaw, Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if res.ContentType != "audio/x-mulaw; channels=1; rate=8000" {
+		t.Errorf("content type = %q", res.ContentType)
+	}
+	if want := []byte{codec.MulawEncode(1000), codec.MulawEncode(-1000)}; !bytes.Equal(res.Data, want) {
+		t.Errorf("got %x, want %x", res.Data, want)
+	}
+
+	// Headerless input converts back to WAV.
+	wav, err := Convert(res.Data, res.ContentType, "audio/wav", Options{SampleRate: 16000})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if rate, channels, bits := wavFormat(t, wav.Data); rate != 16000 || channels != 1 || bits != 16 {
+		t.Errorf("got %d Hz, %d channels, %d bits", rate, channels, bits)
+	}
+}
+
+func TestConvert_KeepsMetadata(t *testing.T) {
+	meta := metadata.WavMetadata{Artist: "Rime AI TTS", Name: "astra (arcana) eng", Comment: "[astra-arcana-eng]: hello"}
+	in := metadata.EmbedMetadata(pcmWAV(24000, 1, 1, 2, 3, 4), meta)
+	res, err := Convert(in, "audio/wav", "audio/wav", Options{SampleRate: 8000})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if got := metadata.ReadMetadata(res.Data); got != meta {
+		t.Errorf("metadata = %+v, want %+v", got, meta)
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	in := pcmWAV(8000, 1, 0, 0)
+	tests := []struct {
+		outType string
+		opts    Options
+		wantErr string
+	}{
+		{"audio/wav", Options{BitDepth: 12}, "unsupported bit depth"},
+		{"audio/wav", Options{NumChannels: 6}, "channels must be 1 or 2"},
+		{"audio/mp3", Options{}, "only WAV and headerless"},
+		{codec.ContentTypeAlaw, Options{BitDepth: 16}, "always 8-bit"},
+		{"audio/wav", Options{TrimSilence: &TrimOptions{Threshold: 3}}, "below 0 dBFS"},
+		{"audio/wav", Options{PadEnd: -time.Second}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		_, err := Convert(in, "audio/wav", tt.outType, tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s %+v: expected error containing %q, got %v", tt.outType, tt.opts, tt.wantErr, err)
+		}
+	}
+}
+
+func TestConvert_Normalize(t *testing.T) {
+	// Half a second of a 440 Hz tone at about -26 dBFS.
+	samples := make([]int16, 12000)
+	for i := range samples {
+		samples[i] = int16(1600 * math.Sin(2*math.Pi*440*float64(i)/24000))
+	}
+	target := -16.0
+	res, err := Convert(pcmWAV(24000, 1, samples...), "audio/wav", "audio/wav", Options{Normalize: &target})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if res.Loudness == nil {
+		t.Fatal("expected a loudness report")
+	}
+	if got := float64(res.Loudness.Output.Integrated); math.Abs(got-target) > 0.2 {
+		t.Errorf("output loudness = %.2f LUFS, want %.1f", got, target)
+	}
+	if gain := float64(res.Loudness.Gain); gain < 5 {
+		t.Errorf("gain = %.2f dB, expected the quiet tone to be raised", gain)
+	}
+	if len(res.Data) != len(pcmWAV(24000, 1, samples...)) {
+		t.Errorf("normalizing changed the length: %d bytes", len(res.Data))
+	}
+}
+
+func TestConvert_TrimAndPad(t *testing.T) {
+	// 100 ms of silence, 200 ms of tone and 200 ms of silence.
+	samples := make([]int16, 12000)
+	for i := 2400; i < 7200; i++ {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/24000))
+	}
+	in := pcmWAV(24000, 1, samples...)
+
+	res, err := Convert(in, "audio/wav", "audio/wav", Options{
+		TrimSilence: &TrimOptions{Threshold: -50, MinDuration: 50 * time.Millisecond},
+		PadStart:    20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if frames := (len(res.Data) - 44) / 2; frames != 480+4800 {
+		t.Errorf("got %d frames, want %d", frames, 480+4800)
+	}
+	if got := binary.LittleEndian.Uint32(res.Data[40:44]); int(got) != len(res.Data)-44 {
+		t.Errorf("data chunk size = %d, want %d", got, len(res.Data)-44)
+	}
+	if res.Offset != -80*time.Millisecond {
+		t.Errorf("offset = %s, want -80ms", res.Offset)
+	}
+	if !bytes.Equal(res.Data[44+2*480:44+2*481], in[44+2*2400:44+2*2401]) {
+		t.Error("expected the tone to start right after the padding")
+	}
+
+	// Silence shorter than MinDuration is kept.
+	res, err = Convert(in, "audio/wav", "audio/wav", Options{
+		TrimSilence: &TrimOptions{Threshold: -50, MinDuration: 150 * time.Millisecond},
+		PadEnd:      time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if frames := (len(res.Data) - 44) / 2; frames != 7200+24000 {
+		t.Errorf("got %d frames, want %d", frames, 7200+24000)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	a := pcmWAV(8000, 1, 1000, 1000, 1000, 1000)
+	b := pcmWAV(8000, 1, -1000, -1000)
+	parts := []Part{{Name: "a.wav", Data: a, ContentType: "audio/wav"}, {Name: "b.wav", Data: b, ContentType: "audio/wav"}}
+
+	// 1 ms at 8 kHz is 8 frames of silence.
+	res, err := Concat(parts, "audio/wav", ConcatOptions{Gap: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	want := pcmWAV(8000, 1, 1000, 1000, 1000, 1000, 0, 0, 0, 0, 0, 0, 0, 0, -1000, -1000)
+	if !bytes.Equal(res.Data, want) {
+		t.Errorf("got  %x\nwant %x", res.Data, want)
+	}
+
+	// A crossfade of two frames overlaps the parts by two frames.
+	res, err = Concat(parts, "audio/wav", ConcatOptions{Crossfade: 250 * time.Microsecond})
+	if err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	if frames := (len(res.Data) - 44) / 2; frames != 4 {
+		t.Errorf("got %d frames, want 4", frames)
+	}
+	if first := int16(binary.LittleEndian.Uint16(res.Data[44:])); first != 1000 {
+		t.Errorf("first sample = %d, want 1000 before the crossfade", first)
+	}
+}
+
+func TestConcat_MismatchedFormats(t *testing.T) {
+	parts := []Part{
+		{Name: "a.wav", Data: pcmWAV(8000, 1, make([]int16, 800)...), ContentType: "audio/wav"},
+		{Name: "b.wav", Data: pcmWAV(16000, 2, make([]int16, 3200)...), ContentType: "audio/wav"},
+	}
+	_, err := Concat(parts, "audio/wav", ConcatOptions{})
+	if err == nil || !strings.Contains(err.Error(), "b.wav is 16000 Hz stereo but a.wav is 8000 Hz mono") {
+		t.Fatalf("expected a format mismatch error, got %v", err)
+	}
+
+	res, err := Concat(parts, "audio/wav", ConcatOptions{Resample: true})
+	if err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	if rate, channels, _ := wavFormat(t, res.Data); rate != 8000 || channels != 1 {
+		t.Errorf("got %d Hz, %d channels; want the first part's 8000 Hz mono", rate, channels)
+	}
+	if frames := (len(res.Data) - 44) / 2; frames != 1600 {
+		t.Errorf("got %d frames, want 1600", frames)
+	}
+}
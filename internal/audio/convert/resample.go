@@ -0,0 +1,162 @@
+package convert
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// The resampler is a windowed-sinc interpolator. Each output sample is the
+// sum of the input samples around it, weighted by a sinc low-pass filter
+// shaped by a Kaiser window. The filter's cutoff sits just below the lower
+// of the two Nyquist frequencies, so downsampling doesn't alias.
+const (
+	// resampleZeroCrossings is the half-width of the filter, in zero
+	// crossings of the sinc.
+	resampleZeroCrossings = 32
+	// resampleTableRes is the number of filter table entries per zero
+	// crossing. Weights between entries are interpolated linearly.
+	resampleTableRes = 512
+	// resampleBeta shapes the Kaiser window; 8.6 gives about 90 dB of
+	// stopband attenuation.
+	resampleBeta = 8.6
+	// resampleCutoff is the passband edge as a fraction of the lower
+	// Nyquist frequency.
+	resampleCutoff = 0.95
+	// resampleBlock is how many input frames are read at a time.
+	resampleBlock = 512
+)
+
+// resampleTable holds the windowed sinc from 0 to resampleZeroCrossings,
+// plus a guard entry for interpolation.
+var resampleTable = func() []float64 {
+	n := resampleZeroCrossings * resampleTableRes
+	table := make([]float64, n+2)
+	norm := bessel0(resampleBeta)
+	for i := 0; i <= n; i++ {
+		x := float64(i) / resampleTableRes
+		w := x / resampleZeroCrossings
+		table[i] = sinc(x) * bessel0(resampleBeta*math.Sqrt(1-w*w)) / norm
+	}
+	return table
+}()
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// bessel0 is the zeroth-order modified Bessel function of the first kind.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / 2) * (x / 2) / float64(k*k)
+		sum += term
+	}
+	return sum
+}
+
+// Resample returns s converted from sample rate from to sample rate to. It
+// returns s itself if the rates are equal.
+func Resample(s beep.Streamer, from, to beep.SampleRate) beep.Streamer {
+	if from == to || from <= 0 || to <= 0 {
+		return s
+	}
+	fc := resampleCutoff * math.Min(1, float64(to)/float64(from))
+	return &resampler{
+		s:     s,
+		from:  int64(from),
+		to:    int64(to),
+		fc:    fc,
+		width: int64(math.Ceil(resampleZeroCrossings / fc)),
+		block: make([][2]float64, resampleBlock),
+	}
+}
+
+type resampler struct {
+	s        beep.Streamer
+	from, to int64
+	// fc is the filter cutoff in cycles per input sample, times two.
+	fc float64
+	// width is the filter half-width in input samples.
+	width int64
+
+	// buf holds input frames from index base on.
+	buf   [][2]float64
+	base  int64
+	block [][2]float64
+	// end is the number of input frames, once the input is exhausted.
+	end int64
+	eof bool
+	err error
+
+	// pos is the position of the next output frame in input frames,
+	// times to.
+	pos int64
+}
+
+// fill reads input until buf holds frame i or the input ends.
+func (r *resampler) fill(i int64) {
+	for !r.eof && r.base+int64(len(r.buf)) <= i {
+		n, ok := r.s.Stream(r.block)
+		r.buf = append(r.buf, r.block[:n]...)
+		if !ok {
+			r.eof = true
+			r.end = r.base + int64(len(r.buf))
+			r.err = r.s.Err()
+		}
+	}
+}
+
+// discard drops buffered frames before frame i.
+func (r *resampler) discard(i int64) {
+	drop := i - r.base
+	if drop < resampleBlock || drop > int64(len(r.buf)) {
+		return
+	}
+	r.buf = r.buf[:copy(r.buf, r.buf[drop:])]
+	r.base = i
+}
+
+func (r *resampler) weight(d float64) float64 {
+	x := math.Abs(d) * r.fc * resampleTableRes
+	i := int(x)
+	if i >= resampleZeroCrossings*resampleTableRes {
+		return 0
+	}
+	frac := x - float64(i)
+	return r.fc * (resampleTable[i] + frac*(resampleTable[i+1]-resampleTable[i]))
+}
+
+func (r *resampler) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		center := r.pos / r.to
+		r.fill(center + r.width)
+		if r.eof && center >= r.end {
+			break
+		}
+
+		frac := float64(r.pos%r.to) / float64(r.to)
+		first := max(center-r.width+1, r.base)
+		last := min(center+r.width, r.base+int64(len(r.buf))-1)
+		var out [2]float64
+		for i := first; i <= last; i++ {
+			w := r.weight(float64(i-center) - frac)
+			frame := r.buf[i-r.base]
+			out[0] += w * frame[0]
+			out[1] += w * frame[1]
+		}
+		samples[n] = out
+		n++
+
+		r.pos += r.from
+		r.discard(r.pos/r.to - r.width + 1)
+	}
+	return n, n > 0
+}
+
+func (r *resampler) Err() error {
+	return r.err
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// sineStreamer produces n frames of a sine wave at freq Hz.
+type sineStreamer struct {
+	rate, freq float64
+	amp        float64
+	i, n       int
+}
+
+func (s *sineStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.i >= s.n {
+		return 0, false
+	}
+	n := min(len(samples), s.n-s.i)
+	for j := range samples[:n] {
+		v := s.amp * math.Sin(2*math.Pi*s.freq*float64(s.i+j)/s.rate)
+		samples[j] = [2]float64{v, v}
+	}
+	s.i += n
+	return n, true
+}
+
+func (s *sineStreamer) Err() error { return nil }
+
+func streamAll(s beep.Streamer) [][2]float64 {
+	var out [][2]float64
+	buf := make([][2]float64, 300)
+	for {
+		n, ok := s.Stream(buf)
+		out = append(out, buf[:n]...)
+		if !ok {
+			return out
+		}
+	}
+}
+
+// rms returns the root mean square of the left channel, ignoring edge
+// frames at each end.
+func rms(frames [][2]float64, edge int) float64 {
+	var sum float64
+	frames = frames[edge : len(frames)-edge]
+	for _, f := range frames {
+		sum += f[0] * f[0]
+	}
+	return math.Sqrt(sum / float64(len(frames)))
+}
+
+func TestResample_Length(t *testing.T) {
+	tests := []struct{ from, to, n, want int }{
+		{24000, 16000, 24000, 16000},
+		{24000, 48000, 1000, 2000},
+		{44100, 8000, 44100, 8000},
+		{8000, 11025, 8000, 11025},
+		{24000, 24000, 500, 500},
+	}
+	for _, tt := range tests {
+		src := &sineStreamer{rate: float64(tt.from), freq: 440, amp: 0.5, n: tt.n}
+		got := len(streamAll(Resample(src, beep.SampleRate(tt.from), beep.SampleRate(tt.to))))
+		if got != tt.want {
+			t.Errorf("%d -> %d Hz: got %d frames, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestResample_KeepsPassband(t *testing.T) {
+	src := &sineStreamer{rate: 24000, freq: 1000, amp: 0.5, n: 24000}
+	out := streamAll(Resample(src, 24000, 16000))
+
+	want := 0.5 / math.Sqrt2
+	if got := rms(out, 200); math.Abs(got-want) > 0.005 {
+		t.Errorf("1 kHz tone RMS = %.4f, want %.4f", got, want)
+	}
+	// Compare against the ideal tone at the new rate.
+	for i := 1000; i < 1100; i++ {
+		ideal := 0.5 * math.Sin(2*math.Pi*1000*float64(i)/16000)
+		if math.Abs(out[i][0]-ideal) > 1e-3 {
+			t.Fatalf("frame %d = %.5f, want %.5f", i, out[i][0], ideal)
+		}
+	}
+}
+
+func TestResample_RejectsAliases(t *testing.T) {
+	// 7 kHz can't be represented at 8 kHz and must be filtered out rather
+	// than folding down to 1 kHz.
+	src := &sineStreamer{rate: 24000, freq: 7000, amp: 0.5, n: 24000}
+	out := streamAll(Resample(src, 24000, 8000))
+	if got := rms(out, 200); got > 0.001 {
+		t.Errorf("7 kHz tone at 8 kHz has RMS %.5f, want near 0", got)
+	}
+}
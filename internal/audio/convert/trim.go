@@ -0,0 +1,107 @@
+package convert
+
+import (
+	"bytes"
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+)
+
+// trimWindowsPerSecond is how many RMS windows silence is measured in per
+// second of audio, giving a trimming resolution of 10 ms.
+const trimWindowsPerSecond = 100
+
+// TrimOptions describe the silence removed from the start and end of audio.
+type TrimOptions struct {
+	// Threshold is the RMS level, in dBFS, at or below which audio counts
+	// as silence.
+	Threshold float64
+	// MinDuration is the shortest stretch of silence that is removed;
+	// shorter pauses are kept.
+	MinDuration time.Duration
+}
+
+// trimBounds returns the frames of data, decoded at rate, that are kept
+// when silence is trimmed as opts say: from start up to end, or to the end
+// of the audio if end is negative.
+func trimBounds(data []byte, contentType string, rate beep.SampleRate, opts TrimOptions) (start, end int, err error) {
+	amplitudes, err := analyze.AnalyzeAmplitudesFromReader(bytes.NewReader(data), contentType, trimWindowsPerSecond)
+	if err != nil {
+		return 0, 0, err
+	}
+	minCount := int(math.Ceil(opts.MinDuration.Seconds() * trimWindowsPerSecond))
+	lead, trail := analyze.SilentEnds(amplitudes, math.Pow(10, opts.Threshold/20), max(1, minCount))
+
+	window := max(1, int(rate)/trimWindowsPerSecond)
+	end = -1
+	if trail > 0 {
+		end = (len(amplitudes) - trail) * window
+	}
+	return lead * window, end, nil
+}
+
+// edited streams the frames of s from skip onwards, up to remain of them
+// if remain isn't negative, between padStart and padEnd frames of silence.
+type edited struct {
+	s                beep.Streamer
+	skip, remain     int
+	padStart, padEnd int
+	err              error
+}
+
+func (e *edited) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) {
+		switch {
+		case e.padStart > 0:
+			k := min(e.padStart, len(samples)-n)
+			clear(samples[n : n+k])
+			e.padStart -= k
+			n += k
+		case e.s != nil && e.skip > 0:
+			k, ok := e.s.Stream(samples[n : n+min(e.skip, len(samples)-n)])
+			e.skip -= k
+			if !ok {
+				e.finish()
+			}
+		case e.s != nil && e.remain != 0:
+			buf := samples[n:]
+			if e.remain > 0 {
+				buf = buf[:min(len(buf), e.remain)]
+			}
+			k, ok := e.s.Stream(buf)
+			n += k
+			if e.remain > 0 {
+				e.remain -= k
+			}
+			if !ok {
+				e.finish()
+			}
+		case e.padEnd > 0:
+			e.s = nil
+			k := min(e.padEnd, len(samples)-n)
+			clear(samples[n : n+k])
+			e.padEnd -= k
+			n += k
+		default:
+			return n, n > 0
+		}
+	}
+	return n, true
+}
+
+// finish stops streaming from s once it has ended. Audio that ends in an
+// error isn't padded.
+func (e *edited) finish() {
+	e.err = e.s.Err()
+	e.s = nil
+	if e.err != nil {
+		e.padEnd = 0
+	}
+}
+
+func (e *edited) Err() error {
+	return e.err
+}
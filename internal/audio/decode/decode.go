@@ -5,6 +5,8 @@ import (
 	"io"
 
 	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/stream"
 )
 
 type AudioDecoder interface {
@@ -12,13 +14,17 @@ type AudioDecoder interface {
 	Err() error
 }
 
+// DecodeAudio decodes r as WAV, MP3 or, for content types such as
+// "audio/x-mulaw;rate=8000", headerless audio.
 func DecodeAudio(r io.Reader, contentType string) (AudioDecoder, beep.Format, error) {
 	switch contentType {
 	case "audio/wav":
 		return decodeWAV(r)
 	case "audio/mpeg", "audio/mp3":
 		return decodeMP3(r)
-	default:
-		return nil, beep.Format{}, fmt.Errorf("unsupported content type: %s", contentType)
 	}
+	if f, ok := codec.ParseContentType(contentType); ok {
+		return stream.DecodeRawStreaming(r, f)
+	}
+	return nil, beep.Format{}, fmt.Errorf("unsupported content type: %s", contentType)
 }
@@ -14,3 +14,18 @@ func TestDecodeAudio_UnsupportedFormat(t *testing.T) {
 		t.Error("Expected error for unsupported format")
 	}
 }
+
+func TestDecodeAudio_Raw(t *testing.T) {
+	data := []byte{0xD5, 0xD5, 0xD5, 0xD5}
+	decoder, format, err := DecodeAudio(bytes.NewReader(data), "audio/x-alaw; rate=16000")
+	if err != nil {
+		t.Fatalf("DecodeAudio failed: %v", err)
+	}
+	if format.SampleRate != 16000 || format.NumChannels != 1 {
+		t.Errorf("unexpected format %+v", format)
+	}
+	samples := make([][2]float64, 8)
+	if n, _ := decoder.Stream(samples); n != 4 {
+		t.Errorf("got %d frames, want 4", n)
+	}
+}
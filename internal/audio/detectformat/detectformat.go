@@ -1,6 +1,18 @@
 package detectformat
 
-import "bytes"
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Content types of headerless audio, as in package codec (which can't be
+// imported here without a cycle through metadata).
+const (
+	contentTypePCM   = "audio/pcm"
+	contentTypeMulaw = "audio/x-mulaw"
+	contentTypeAlaw  = "audio/x-alaw"
+)
 
 func DetectFormat(data []byte) string {
 	if len(data) >= 4 && bytes.Equal(data[0:4], []byte("RIFF")) {
@@ -14,3 +26,38 @@ func DetectFormat(data []byte) string {
 	}
 	return ""
 }
+
+// FromExtension returns the content type for a file name's extension, or ""
+// if it is not an audio extension. Headerless PCM, μ-law and A-law audio
+// can't be recognized from its bytes, so this is the only way to tell them
+// apart without being told.
+func FromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		return "audio/wav"
+	case ".mp3":
+		return "audio/mp3"
+	case ".pcm", ".raw":
+		return contentTypePCM
+	case ".ulaw", ".mulaw", ".mu", ".ul":
+		return contentTypeMulaw
+	case ".alaw", ".al":
+		return contentTypeAlaw
+	}
+	return ""
+}
+
+// DetectFile returns the content type of a file from its leading bytes and
+// its extension. A headerless extension wins over the bytes unless they are
+// a WAV header, since μ-law silence (all 0xFF) looks like an MP3 sync word.
+func DetectFile(path string, data []byte) string {
+	byExt := FromExtension(path)
+	raw := byExt == contentTypePCM || byExt == contentTypeMulaw || byExt == contentTypeAlaw
+	if raw && !bytes.HasPrefix(data, []byte("RIFF")) {
+		return byExt
+	}
+	if contentType := DetectFormat(data); contentType != "" {
+		return contentType
+	}
+	return byExt
+}
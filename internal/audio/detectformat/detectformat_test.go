@@ -47,3 +47,40 @@ func TestDetectFormat_Empty(t *testing.T) {
 		t.Errorf("Expected empty string, got %s", format)
 	}
 }
+
+func TestFromExtension(t *testing.T) {
+	tests := map[string]string{
+		"clip.wav":     "audio/wav",
+		"clip.MP3":     "audio/mp3",
+		"clip.pcm":     "audio/pcm",
+		"clip.ulaw":    "audio/x-mulaw",
+		"clip.alaw":    "audio/x-alaw",
+		"clip.txt":     "",
+		"no-extension": "",
+	}
+	for path, want := range tests {
+		if got := FromExtension(path); got != want {
+			t.Errorf("FromExtension(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	tests := []struct {
+		path string
+		data []byte
+		want string
+	}{
+		{"clip.pcm", []byte("RIFF....WAVE"), "audio/wav"},
+		// μ-law silence looks like an MP3 sync word.
+		{"clip.ulaw", []byte{0xFF, 0xFF, 0xFF, 0xFF}, "audio/x-mulaw"},
+		{"clip.alaw", []byte{0xD5, 0xD5, 0xD5}, "audio/x-alaw"},
+		{"clip.bin", []byte("ID3\x04"), "audio/mp3"},
+		{"clip.bin", []byte{0, 0, 0}, ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFile(tt.path, tt.data); got != tt.want {
+			t.Errorf("DetectFile(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package loudness
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a sine wave at freq Hz and peak level dBFS in
+// both channels, starting at phase.
+func sine(rate int, freq, level, seconds, phase float64) [][2]float64 {
+	amp := math.Pow(10, level/20)
+	frames := make([][2]float64, int(seconds*float64(rate)))
+	for i := range frames {
+		v := amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)+phase)
+		frames[i] = [2]float64{v, v}
+	}
+	return frames
+}
+
+func near(t *testing.T, name string, got Level, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(float64(got)-want) > tolerance {
+		t.Errorf("%s = %.3f, want %.3f ± %g", name, float64(got), want, tolerance)
+	}
+}
+
+func TestMeasure_Integrated(t *testing.T) {
+	// EBU Tech 3341 case 1: a stereo 1 kHz sine at -23 dBFS measures
+	// -23 LUFS, at any sample rate.
+	for _, rate := range []int{48000, 44100, 24000, 16000} {
+		stats := Measure(sine(rate, 1000, -23, 5, 0), 2, rate)
+		near(t, "stereo integrated", stats.Integrated, -23, 0.1)
+	}
+	// One channel carries half the energy.
+	stats := Measure(sine(24000, 1000, -23, 5, 0), 1, 24000)
+	near(t, "mono integrated", stats.Integrated, -26.01, 0.1)
+}
+
+func TestMeasure_GatesSilence(t *testing.T) {
+	// Silence after the tone falls below the absolute gate and doesn't
+	// pull the loudness down; only the blocks straddling the end of the
+	// tone are a little quieter.
+	frames := append(sine(24000, 1000, -23, 3, 0), make([][2]float64, 24000*3)...)
+	near(t, "integrated", Measure(frames, 2, 24000).Integrated, -23, 0.3)
+
+	stats := Measure(make([][2]float64, 24000), 2, 24000)
+	if !math.IsInf(float64(stats.Integrated), -1) || !math.IsInf(float64(stats.TruePeak), -1) {
+		t.Errorf("silence measured as %+v", stats)
+	}
+}
+
+func TestMeasure_Range(t *testing.T) {
+	// EBU Tech 3342 case 1: -20 dBFS then -30 dBFS has a range of 10 LU.
+	frames := append(sine(24000, 1000, -20, 10, 0), sine(24000, 1000, -30, 10, 0)...)
+	near(t, "loudness range", Measure(frames, 2, 24000).Range, 10, 0.5)
+
+	if r := Measure(sine(24000, 1000, -20, 2, 0), 2, 24000).Range; r != 0 {
+		t.Errorf("range of 2 s = %v, want 0", r)
+	}
+}
+
+func TestMeasure_TruePeak(t *testing.T) {
+	// A quarter-rate sine sampled 45° off its peaks has samples at -3 dBFS
+	// but a true peak of 0 dBTP.
+	frames := sine(24000, 6000, 0, 1, math.Pi/4)
+	near(t, "true peak", Measure(frames, 2, 24000).TruePeak, 0, 0.3)
+}
+
+func TestNormalize(t *testing.T) {
+	frames := sine(24000, 1000, -30, 3, 0)
+	report := Normalize(frames, 2, 24000, -16)
+	near(t, "input", report.Input.Integrated, -30, 0.1)
+	near(t, "gain", report.Gain, 14, 0.1)
+	near(t, "output", report.Output.Integrated, -16, 0.1)
+
+	// Reaching -3 LUFS with a mono sine needs a 0 dBFS peak, which the
+	// limiter holds down to the ceiling.
+	frames = sine(24000, 1000, -20, 3, 0)
+	report = Normalize(frames, 1, 24000, -3)
+	if tp := float64(report.Output.TruePeak); tp > PeakCeiling+0.1 {
+		t.Errorf("output true peak = %.2f dBTP, want at most %g", tp, PeakCeiling)
+	}
+	near(t, "limited output", report.Output.Integrated, -4, 0.2)
+
+	// Silence is left alone.
+	silence := make([][2]float64, 2400)
+	report = Normalize(silence, 2, 24000, -16)
+	if report.Gain != 0 || silence[0] != [2]float64{} {
+		t.Errorf("silence was changed: %+v", report)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, s := range []string{"-16LUFS", "-16 lufs", "-16", " -16.0LUFS "} {
+		if got, err := ParseTarget(s); err != nil || got != -16 {
+			t.Errorf("ParseTarget(%q) = %v, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"", "LUFS", "loud", "0", "3LUFS", "-90"} {
+		if _, err := ParseTarget(s); err == nil {
+			t.Errorf("ParseTarget(%q): expected an error", s)
+		}
+	}
+}
+
+func TestLevel_JSON(t *testing.T) {
+	data, err := json.Marshal(Stats{Integrated: -16.0049, Range: 0, TruePeak: Level(math.Inf(-1))})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"integrated_lufs":-16,"loudness_range_lu":0,"true_peak_dbtp":null}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
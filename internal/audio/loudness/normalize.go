@@ -0,0 +1,140 @@
+package loudness
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PeakCeiling is the highest true peak normalized audio may reach, in
+	// dBTP, leaving headroom for lossy encoding downstream as EBU R128
+	// recommends.
+	PeakCeiling = -1.0
+
+	// limiterLookahead is how far ahead the limiter starts turning the
+	// gain down before a peak.
+	limiterLookahead = 5 * time.Millisecond
+	// limiterRelease is the time constant with which the gain recovers
+	// after a peak.
+	limiterRelease = 50 * time.Millisecond
+)
+
+// ParseTarget parses a loudness target such as "-16LUFS", "-23 LUFS" or
+// "-16".
+func ParseTarget(s string) (float64, error) {
+	v := strings.TrimSpace(s)
+	if len(v) > 4 && strings.EqualFold(v[len(v)-4:], "lufs") {
+		v = strings.TrimSpace(v[:len(v)-4])
+	}
+	target, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(target) {
+		return 0, fmt.Errorf("invalid loudness target %q (e.g. -16LUFS)", s)
+	}
+	if target <= absoluteGate || target >= 0 {
+		return 0, fmt.Errorf("loudness target must be between %g and 0 LUFS, got %g", absoluteGate, target)
+	}
+	return target, nil
+}
+
+// Report describes a normalization: the loudness before and after it and
+// the gain applied on top of any limiting.
+type Report struct {
+	Target Level `json:"target_lufs"`
+	Gain   Level `json:"gain_db"`
+	Input  Stats `json:"input"`
+	Output Stats `json:"output"`
+}
+
+// String summarizes the report for display, e.g. "Loudness: -23.4 → -16.0
+// LUFS | True peak: -1.2 dBTP".
+func (r Report) String() string {
+	return fmt.Sprintf("Loudness: %s → %s LUFS | True peak: %s dBTP",
+		r.Input.Integrated, r.Output.Integrated, r.Output.TruePeak)
+}
+
+// Normalize applies gain to frames in place so that their integrated
+// loudness is target LUFS, then limits peaks above PeakCeiling. Silent
+// audio is left unchanged.
+func Normalize(frames [][2]float64, channels, rate int, target float64) Report {
+	report := Report{Target: Level(target), Input: Measure(frames, channels, rate)}
+	if math.IsInf(float64(report.Input.Integrated), -1) {
+		report.Output = report.Input
+		return report
+	}
+
+	gain := target - float64(report.Input.Integrated)
+	report.Gain = Level(gain)
+	scale := math.Pow(10, gain/20)
+	for i := range frames {
+		frames[i][0] *= scale
+		frames[i][1] *= scale
+	}
+	limit(frames, channels, rate, math.Pow(10, PeakCeiling/20))
+
+	report.Output = Measure(frames, channels, rate)
+	return report
+}
+
+// limit reduces the gain of frames wherever their true peak would exceed
+// ceiling. The gain is lowered gradually over the lookahead before each peak
+// and recovers exponentially after it, so limiting doesn't click.
+func limit(frames [][2]float64, channels, rate int, ceiling float64) {
+	if len(frames) == 0 {
+		return
+	}
+	peaks := interSamplePeaks(frames, channels)
+	gains := make([]float64, len(frames))
+	limited := false
+	for i, p := range peaks {
+		gains[i] = 1
+		if p > ceiling {
+			gains[i] = ceiling / p
+			limited = true
+		}
+	}
+	if !limited {
+		return
+	}
+
+	lookahead := max(1, int(float64(rate)*limiterLookahead.Seconds()))
+	env := forwardMin(gains, lookahead)
+	release := 1 - math.Exp(-1/(limiterRelease.Seconds()*float64(rate)))
+	for i := 1; i < len(env); i++ {
+		env[i] = math.Min(env[i], env[i-1]+(1-env[i-1])*release)
+	}
+
+	// Averaging over the lookahead smooths the gain's descent. Each window
+	// only holds minimums over spans that reach the current frame, so the
+	// average never exceeds the gain the frame needs.
+	var sum float64
+	for i := range frames {
+		sum += env[i]
+		if i >= lookahead {
+			sum -= env[i-lookahead]
+		}
+		g := sum / float64(min(i+1, lookahead))
+		frames[i][0] *= g
+		frames[i][1] *= g
+	}
+}
+
+// forwardMin returns, for each index i, the minimum of x[i:i+window].
+func forwardMin(x []float64, window int) []float64 {
+	out := make([]float64, len(x))
+	// queue holds indices whose values increase from front to back.
+	var queue []int
+	for i := len(x) - 1; i >= 0; i-- {
+		for len(queue) > 0 && x[queue[len(queue)-1]] >= x[i] {
+			queue = queue[:len(queue)-1]
+		}
+		queue = append(queue, i)
+		if queue[0] >= i+window {
+			queue = queue[1:]
+		}
+		out[i] = x[queue[0]]
+	}
+	return out
+}
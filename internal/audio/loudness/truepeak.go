@@ -0,0 +1,55 @@
+package loudness
+
+import "math"
+
+// True peak is found as BS.1770-4 Annex 2 suggests: the audio is upsampled
+// four times with an interpolating filter, and the peak taken over the
+// original and interpolated samples alike.
+const (
+	oversample = 4
+	// peakTaps is the half-width of the interpolating filter, in input
+	// samples, for 48 taps in all.
+	peakTaps = 6
+)
+
+// peakFilter holds, for each interpolated phase, the weights of the input
+// samples from peakTaps-1 before to peakTaps after it.
+var peakFilter = func() [oversample][2 * peakTaps]float64 {
+	var filter [oversample][2 * peakTaps]float64
+	for p := 1; p < oversample; p++ {
+		frac := float64(p) / oversample
+		for k := range filter[p] {
+			d := float64(k-peakTaps+1) - frac
+			// Hann-windowed sinc.
+			w := 0.5 + 0.5*math.Cos(math.Pi*d/peakTaps)
+			filter[p][k] = w * math.Sin(math.Pi*d) / (math.Pi * d)
+		}
+	}
+	return filter
+}()
+
+// interSamplePeaks returns, for each frame, the largest magnitude of any
+// channel at that frame or at the points interpolated between it and the
+// next.
+func interSamplePeaks(frames [][2]float64, channels int) []float64 {
+	channels = max(1, min(channels, 2))
+	peaks := make([]float64, len(frames))
+	for i := range frames {
+		var peak float64
+		for ch := 0; ch < channels; ch++ {
+			peak = math.Max(peak, math.Abs(frames[i][ch]))
+			for p := 1; p < oversample; p++ {
+				var v float64
+				for k, w := range peakFilter[p] {
+					j := i + k - peakTaps + 1
+					if j >= 0 && j < len(frames) {
+						v += w * frames[j][ch]
+					}
+				}
+				peak = math.Max(peak, math.Abs(v))
+			}
+		}
+		peaks[i] = peak
+	}
+	return peaks
+}
@@ -1,7 +1,9 @@
 package metadata
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
 )
@@ -28,6 +30,41 @@ func ParseComment(comment string) (*ParsedComment, bool) {
 	}, true
 }
 
+// String formats c as the comment ParseComment reads.
+func (c ParsedComment) String() string {
+	return fmt.Sprintf("[%s-%s-%s]: %s", c.Speaker, c.ModelID, c.Language, c.Text)
+}
+
+// MergeComments combines the comments of audio clips played one after
+// another. The texts are joined with spaces into one transcript; speakers,
+// models and languages that differ between clips are listed in order,
+// joined by "+".
+func MergeComments(comments []ParsedComment) ParsedComment {
+	var speakers, models, langs, texts []string
+	add := func(list []string, v string) []string {
+		for _, have := range list {
+			if have == v {
+				return list
+			}
+		}
+		return append(list, v)
+	}
+	for _, c := range comments {
+		speakers = add(speakers, c.Speaker)
+		models = add(models, c.ModelID)
+		langs = add(langs, c.Language)
+		if text := strings.TrimSpace(c.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return ParsedComment{
+		Speaker:  strings.Join(speakers, "+"),
+		ModelID:  strings.Join(models, "+"),
+		Language: strings.Join(langs, "+"),
+		Text:     strings.Join(texts, " "),
+	}
+}
+
 func GetParsedCommentFromFile(data []byte) (*ParsedComment, bool) {
 	contentType := detectformat.DetectFormat(data)
 
@@ -118,3 +118,20 @@ func TestParseComment_WithSpaces(t *testing.T) {
 		t.Errorf("Text should preserve spaces (leading whitespace consumed by regex), got %q", parsed.Text)
 	}
 }
+
+func TestMergeComments(t *testing.T) {
+	merged := MergeComments([]ParsedComment{
+		{Speaker: "astra", ModelID: "arcana", Language: "eng", Text: "Your balance is"},
+		{Speaker: "celeste", ModelID: "arcana", Language: "eng", Text: "forty two"},
+		{Speaker: "astra", ModelID: "arcana", Language: "eng", Text: "dollars."},
+	})
+	want := "[astra+celeste-arcana-eng]: Your balance is forty two dollars."
+	if got := merged.String(); got != want {
+		t.Errorf("merged comment = %q, want %q", got, want)
+	}
+
+	parsed, ok := ParseComment(merged.String())
+	if !ok || *parsed != merged {
+		t.Errorf("ParseComment(%q) = %+v, %v; want %+v", merged.String(), parsed, ok, merged)
+	}
+}
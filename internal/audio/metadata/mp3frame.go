@@ -0,0 +1,218 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// MPEG audio Layer III frame header (4 bytes, big-endian bit fields):
+//
+//	Bits   Description
+//	----   -----------
+//	11     Frame sync (all ones)
+//	2      Version (00 = MPEG 2.5, 10 = MPEG 2, 11 = MPEG 1)
+//	2      Layer (01 = Layer III)
+//	1      Protection (0 = followed by a 16-bit CRC)
+//	4      Bitrate index
+//	2      Sample rate index
+//	1      Padding (1 = one extra byte)
+//	1      Private
+//	2      Channel mode (11 = mono)
+//	6      Mode extension, copyright, original, emphasis
+//
+// The side information follows the header (and CRC). A Xing, Info or VBRI
+// header frame carries stream statistics in place of audio: Xing marks a
+// variable bitrate stream, Info a constant bitrate one.
+
+var (
+	mpeg1Bitrates = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2Bitrates = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+
+	mpeg1SampleRates  = [3]int{44100, 48000, 32000}
+	mpeg2SampleRates  = [3]int{22050, 24000, 16000}
+	mpeg25SampleRates = [3]int{11025, 12000, 8000}
+)
+
+// MP3Frame describes an MPEG audio Layer III frame header.
+type MP3Frame struct {
+	Header [4]byte
+	// Version is "MPEG-1", "MPEG-2" or "MPEG-2.5".
+	Version    string
+	MPEG1      bool
+	SampleRate int
+	// Bitrate is in kbit/s.
+	Bitrate int
+	Mono    bool
+	// Length is the size of the whole frame in bytes, header included.
+	Length int
+	// Samples is the number of samples per channel the frame decodes to.
+	Samples  int
+	SideInfo int
+	CRC      bool
+}
+
+// ParseMP3Frame parses the frame header at the start of b.
+func ParseMP3Frame(b []byte) (MP3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return MP3Frame{}, false
+	}
+	version := (b[1] >> 3) & 0x03
+	layer := (b[1] >> 1) & 0x03
+	bitrateIdx := b[2] >> 4
+	rateIdx := (b[2] >> 2) & 0x03
+	if version == 1 || layer != 1 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return MP3Frame{}, false
+	}
+
+	f := MP3Frame{MPEG1: version == 3, CRC: b[1]&0x01 == 0, Mono: b[3]>>6 == 3}
+	copy(f.Header[:], b[:4])
+	padding := int((b[2] >> 1) & 0x01)
+	switch version {
+	case 3:
+		f.Version = "MPEG-1"
+		f.SampleRate = mpeg1SampleRates[rateIdx]
+		f.Bitrate = mpeg1Bitrates[bitrateIdx]
+		f.Length = 144*f.Bitrate*1000/f.SampleRate + padding
+		f.Samples = 1152
+		f.SideInfo = 32
+		if f.Mono {
+			f.SideInfo = 17
+		}
+	default:
+		if version == 2 {
+			f.Version = "MPEG-2"
+			f.SampleRate = mpeg2SampleRates[rateIdx]
+		} else {
+			f.Version = "MPEG-2.5"
+			f.SampleRate = mpeg25SampleRates[rateIdx]
+		}
+		f.Bitrate = mpeg2Bitrates[bitrateIdx]
+		f.Length = 72*f.Bitrate*1000/f.SampleRate + padding
+		f.Samples = 576
+		f.SideInfo = 17
+		if f.Mono {
+			f.SideInfo = 9
+		}
+	}
+	return f, true
+}
+
+// InfoTag returns "Xing", "Info" or "VBRI" if frame, which f heads, is a
+// header frame carrying stream statistics instead of audio, or "" if it
+// holds audio.
+func (f MP3Frame) InfoTag(frame []byte) string {
+	offset := 4 + f.SideInfo
+	if f.CRC {
+		offset += 2
+	}
+	if len(frame) >= offset+4 {
+		if tag := string(frame[offset : offset+4]); tag == "Xing" || tag == "Info" {
+			return tag
+		}
+	}
+	if len(frame) >= 40 && string(frame[36:40]) == "VBRI" {
+		return "VBRI"
+	}
+	return ""
+}
+
+// MP3Stats summarize the frames of an MP3 stream.
+type MP3Stats struct {
+	Version    string `json:"version"`
+	SampleRate int    `json:"sample_rate"`
+	Channels   int    `json:"channels"`
+	// Frames counts the audio frames, not the Xing, Info or VBRI frame.
+	Frames int `json:"frames"`
+	// MinBitrate, MaxBitrate and AvgBitrate are in kbit/s.
+	MinBitrate int  `json:"min_bitrate_kbps"`
+	MaxBitrate int  `json:"max_bitrate_kbps"`
+	AvgBitrate int  `json:"avg_bitrate_kbps"`
+	VBR        bool `json:"vbr"`
+	// InfoTag is "Xing", "Info" or "VBRI" if the stream starts with such
+	// a header frame.
+	InfoTag string `json:"info_tag,omitempty"`
+	// InfoFrames is the frame count the header frame gives, if any.
+	InfoFrames int `json:"info_frames,omitempty"`
+	// InfoDuration is the duration InfoFrames gives, if any.
+	InfoDuration time.Duration `json:"-"`
+	// Duration is the frame count times the samples per frame.
+	Duration time.Duration `json:"-"`
+	// Junk counts bytes between frames that aren't part of one.
+	Junk int `json:"junk_bytes"`
+}
+
+// ScanMP3 walks the frames of MP3 data, skipping any ID3v2 tag and ID3v1
+// trailer, and returns their statistics. It returns false if data holds no
+// MP3 frames.
+func ScanMP3(data []byte) (MP3Stats, bool) {
+	var stats MP3Stats
+	pos := id3v2Size(data)
+	var samples, bitrateSum int
+	for pos+4 <= len(data) {
+		if bytes.HasPrefix(data[pos:], []byte("TAG")) && len(data)-pos == 128 {
+			break
+		}
+		f, ok := ParseMP3Frame(data[pos:])
+		if !ok {
+			stats.Junk++
+			pos++
+			continue
+		}
+		frame := data[pos:min(len(data), pos+f.Length)]
+		pos += f.Length
+
+		if stats.Frames == 0 && stats.InfoTag == "" {
+			stats.SampleRate = f.SampleRate
+			stats.Channels = 2
+			if f.Mono {
+				stats.Channels = 1
+			}
+			stats.Version = f.Version
+			if tag := f.InfoTag(frame); tag != "" {
+				stats.InfoTag = tag
+				stats.InfoFrames = infoFrameCount(f, frame, tag)
+				stats.InfoDuration = time.Duration(stats.InfoFrames*f.Samples) * time.Second / time.Duration(f.SampleRate)
+				continue
+			}
+		}
+
+		if stats.Frames == 0 || f.Bitrate < stats.MinBitrate {
+			stats.MinBitrate = f.Bitrate
+		}
+		stats.MaxBitrate = max(stats.MaxBitrate, f.Bitrate)
+		bitrateSum += f.Bitrate
+		samples += f.Samples
+		stats.Frames++
+	}
+	if stats.Frames == 0 {
+		return stats, false
+	}
+	stats.AvgBitrate = int(math.Round(float64(bitrateSum) / float64(stats.Frames)))
+	stats.VBR = stats.MinBitrate != stats.MaxBitrate || stats.InfoTag == "Xing" || stats.InfoTag == "VBRI"
+	stats.Duration = time.Duration(samples) * time.Second / time.Duration(stats.SampleRate)
+	return stats, true
+}
+
+// infoFrameCount returns the frame count a Xing, Info or VBRI header frame
+// records, or 0 if it has none.
+func infoFrameCount(f MP3Frame, frame []byte, tag string) int {
+	if tag == "VBRI" {
+		// The VBRI header holds the frame count at offset 14 of the tag.
+		if len(frame) >= 36+18 {
+			return int(binary.BigEndian.Uint32(frame[36+14:]))
+		}
+		return 0
+	}
+	offset := 4 + f.SideInfo
+	if f.CRC {
+		offset += 2
+	}
+	// Xing and Info headers: tag, flags, then the frame count if flag 1 is
+	// set.
+	if len(frame) < offset+12 || binary.BigEndian.Uint32(frame[offset+4:])&0x01 == 0 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(frame[offset+8:]))
+}
@@ -0,0 +1,79 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// mp3Frame returns a 417-byte frame with the given header: 128kbps
+// 44.1kHz MPEG-1 Layer III, no CRC, unless header says otherwise.
+func mp3Frame(header ...byte) []byte {
+	frame := make([]byte, 417)
+	copy(frame, header)
+	return frame
+}
+
+func TestParseMP3Frame(t *testing.T) {
+	f, ok := ParseMP3Frame([]byte{0xFF, 0xFB, 0x90, 0xC0})
+	if !ok {
+		t.Fatal("ParseMP3Frame failed")
+	}
+	if f.Version != "MPEG-1" || f.SampleRate != 44100 || f.Bitrate != 128 || !f.Mono || f.Length != 417 || f.Samples != 1152 {
+		t.Errorf("got %+v", f)
+	}
+
+	if _, ok := ParseMP3Frame([]byte{0xFF, 0xFB, 0xF0, 0x00}); ok {
+		t.Error("bitrate index 15 should not parse")
+	}
+	if _, ok := ParseMP3Frame([]byte("ID3\x03")); ok {
+		t.Error("ID3 header should not parse")
+	}
+}
+
+func TestScanMP3(t *testing.T) {
+	info := mp3Frame(0xFF, 0xFB, 0x90, 0x00)
+	copy(info[4+32:], "Xing")
+	binary.BigEndian.PutUint32(info[4+32+4:], 0x01)
+	binary.BigEndian.PutUint32(info[4+32+8:], 3)
+
+	var buf bytes.Buffer
+	buf.Write(info)
+	buf.Write(mp3Frame(0xFF, 0xFB, 0x90, 0x00))
+	buf.Write([]byte{0, 0}) // junk between frames
+	buf.Write(mp3Frame(0xFF, 0xFB, 0x90, 0x00))
+	frame := make([]byte, 313) // 96kbps: 144*96000/44100 bytes
+	copy(frame, []byte{0xFF, 0xFB, 0x70, 0x00})
+	buf.Write(frame)
+
+	data, err := EmbedMP3Metadata(buf.Bytes(), MP3Metadata{Artist: "Rime AI TTS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, ok := ScanMP3(data)
+	if !ok {
+		t.Fatal("ScanMP3 found no frames")
+	}
+	if stats.Frames != 3 || stats.InfoTag != "Xing" || stats.InfoFrames != 3 {
+		t.Errorf("Frames, InfoTag, InfoFrames = %d, %q, %d; want 3, Xing, 3", stats.Frames, stats.InfoTag, stats.InfoFrames)
+	}
+	if stats.MinBitrate != 96 || stats.MaxBitrate != 128 || stats.AvgBitrate != 117 || !stats.VBR {
+		t.Errorf("bitrates = %d/%d/%d VBR=%v; want 96/128/117 VBR", stats.MinBitrate, stats.AvgBitrate, stats.MaxBitrate, stats.VBR)
+	}
+	if stats.Junk != 2 {
+		t.Errorf("Junk = %d, want 2", stats.Junk)
+	}
+	if want := 3 * 1152 * time.Second / 44100; stats.Duration != want || stats.InfoDuration != want {
+		t.Errorf("Duration, InfoDuration = %v, %v; want %v", stats.Duration, stats.InfoDuration, want)
+	}
+	if stats.SampleRate != 44100 || stats.Channels != 2 {
+		t.Errorf("SampleRate, Channels = %d, %d; want 44100, 2", stats.SampleRate, stats.Channels)
+	}
+}
+
+func TestScanMP3_NotMP3(t *testing.T) {
+	if _, ok := ScanMP3([]byte("RIFF....WAVE")); ok {
+		t.Error("ScanMP3 should fail on non-MP3 data")
+	}
+}
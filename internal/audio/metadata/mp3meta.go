@@ -3,6 +3,9 @@ package metadata
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
 )
 
 // MP3 file structure with ID3v2.3 tags (relevant parts):
@@ -245,3 +248,124 @@ func findMP3AudioStart(data []byte) int {
 
 	return 0
 }
+
+// ID3Frame is one frame of an ID3v2 tag.
+type ID3Frame struct {
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+	// Value is the text of text (T***) and comment (COMM) frames.
+	Value string `json:"value,omitempty"`
+}
+
+// ID3Tag describes the ID3 tags of an MP3 file.
+type ID3Tag struct {
+	// Version is the ID3v2 version, e.g. "2.3", or "" if there is no
+	// ID3v2 tag.
+	Version string `json:"version,omitempty"`
+	// Size is the size of the ID3v2 tag in bytes, header included.
+	Size   int        `json:"size"`
+	Frames []ID3Frame `json:"frames,omitempty"`
+	// V1 is set when the file ends with a 128-byte ID3v1 tag.
+	V1 bool `json:"v1"`
+}
+
+// ReadID3Tag lists the frames of the ID3v2.3 or ID3v2.4 tag at the start of
+// data and notes an ID3v1 trailer. Frames of ID3v2.2 tags are not listed.
+// It returns false if data has no ID3 tag.
+func ReadID3Tag(data []byte) (*ID3Tag, bool) {
+	tag := &ID3Tag{Size: id3v2Size(data)}
+	tag.V1 = len(data) >= 128 && bytes.Equal(data[len(data)-128:len(data)-125], []byte("TAG"))
+	if tag.Size == 0 {
+		return tag, tag.V1
+	}
+	major := data[3]
+	tag.Version = fmt.Sprintf("2.%d", major)
+	if major != 3 && major != 4 {
+		return tag, true
+	}
+
+	end := min(len(data), 10+int(synchsafeDecode([4]byte{data[6], data[7], data[8], data[9]})))
+	pos := 10
+	for pos+10 <= end && data[pos] != 0 {
+		id := string(data[pos : pos+4])
+		var size int
+		if major == 4 {
+			// ID3v2.4 frame sizes are synchsafe too.
+			size = int(synchsafeDecode([4]byte{data[pos+4], data[pos+5], data[pos+6], data[pos+7]}))
+		} else {
+			size = int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		}
+		if pos+10+size > end {
+			break
+		}
+		frame := ID3Frame{ID: id, Size: size}
+		body := data[pos+10 : pos+10+size]
+		switch {
+		case id == "COMM" && len(body) > 4:
+			frame.Value = id3CommentText(body[0], body[4:])
+		case id[0] == 'T' && len(body) > 0:
+			frame.Value = id3Text(body[0], body[1:])
+		}
+		tag.Frames = append(tag.Frames, frame)
+		pos += 10 + size
+	}
+	return tag, true
+}
+
+// id3v2Size returns the size of the ID3v2 tag at the start of data, header
+// and footer included, or 0 if there is none.
+func id3v2Size(data []byte) int {
+	if len(data) < 10 || !bytes.Equal(data[0:3], []byte("ID3")) {
+		return 0
+	}
+	size := 10 + int(synchsafeDecode([4]byte{data[6], data[7], data[8], data[9]}))
+	if data[5]&0x10 != 0 {
+		// footer present
+		size += 10
+	}
+	return size
+}
+
+// id3Text decodes ID3 text in the given encoding: 0 for ISO-8859-1, 1 for
+// UTF-16 with a byte order mark, 2 for UTF-16BE and 3 for UTF-8.
+func id3Text(encoding byte, b []byte) string {
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(b) >= 2 && (b[0] == 0xFE && b[1] == 0xFF || b[0] == 0xFF && b[1] == 0xFE) {
+			bigEndian = b[0] == 0xFE
+			b = b[2:]
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(b[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(b[i:]))
+			}
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	case 3:
+		return strings.TrimRight(string(b), "\x00")
+	}
+	runes := make([]rune, 0, len(b))
+	for _, c := range bytes.TrimRight(b, "\x00") {
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
+
+// id3CommentText returns the text of a COMM frame body that follows the
+// encoding byte and language: a description, then the comment itself.
+func id3CommentText(encoding byte, b []byte) string {
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+	for i := 0; i+len(terminator) <= len(b); i += len(terminator) {
+		if bytes.Equal(b[i:i+len(terminator)], terminator) {
+			return id3Text(encoding, b[i+len(terminator):])
+		}
+	}
+	return id3Text(encoding, b)
+}
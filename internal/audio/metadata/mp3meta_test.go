@@ -87,3 +87,41 @@ func TestFindMP3AudioStart_NoID3(t *testing.T) {
 		t.Errorf("Expected 0 for MP3 without ID3, got %d", start)
 	}
 }
+
+func TestReadID3Tag(t *testing.T) {
+	embedded, err := EmbedMP3Metadata(testhelpers.MakeMinimalMP3(), MP3Metadata{
+		Artist:  "Rime AI TTS",
+		Comment: "[celeste-arcana-eng]: Hello world",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, ok := ReadID3Tag(embedded)
+	if !ok {
+		t.Fatal("ReadID3Tag found no tag")
+	}
+	if tag.Version != "2.3" || tag.Size != findMP3AudioStart(embedded) || tag.V1 {
+		t.Errorf("Version, Size, V1 = %q, %d, %v", tag.Version, tag.Size, tag.V1)
+	}
+	if len(tag.Frames) != 2 {
+		t.Fatalf("got %d frames, want 2: %+v", len(tag.Frames), tag.Frames)
+	}
+	if f := tag.Frames[0]; f.ID != "TPE1" || f.Value != "Rime AI TTS" {
+		t.Errorf("frame 0 = %+v", f)
+	}
+	if f := tag.Frames[1]; f.ID != "COMM" || f.Value != "[celeste-arcana-eng]: Hello world" {
+		t.Errorf("frame 1 = %+v", f)
+	}
+
+	if _, ok := ReadID3Tag(testhelpers.MakeMinimalMP3()); ok {
+		t.Error("ReadID3Tag should find no tag in untagged MP3")
+	}
+}
+
+func TestID3Text_UTF16(t *testing.T) {
+	b := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0, 0}
+	if got := id3Text(1, b); got != "hi" {
+		t.Errorf("id3Text = %q, want %q", got, "hi")
+	}
+}
@@ -3,6 +3,7 @@ package metadata
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 )
 
 // WAV file structure (relevant parts):
@@ -50,9 +51,9 @@ import (
 // Sub-chunks are padded to even byte boundaries.
 
 type WavMetadata struct {
-	Artist  string // IART: "Rime AI TTS"
-	Name    string // INAM: "celeste (arcana) eng"
-	Comment string // ICMT: "[celeste-arcana-eng]: The quick brown fox..."
+	Artist  string `json:"artist,omitempty"`  // IART: "Rime AI TTS"
+	Name    string `json:"name,omitempty"`    // INAM: "celeste (arcana) eng"
+	Comment string `json:"comment,omitempty"` // ICMT: "[celeste-arcana-eng]: The quick brown fox..."
 }
 
 func EmbedMetadata(data []byte, meta WavMetadata) []byte {
@@ -265,3 +266,175 @@ func FixWavHeader(data []byte) []byte {
 
 	return result
 }
+
+// TrimPartialFrame drops trailing bytes that do not make up a whole sample
+// frame, as left behind when a streamed WAV is cut off mid-sample. It assumes
+// the data chunk runs to the end of data, so call it before FixWavHeader.
+func TrimPartialFrame(data []byte) []byte {
+	if len(data) < 44 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WAVE")) {
+		return data
+	}
+
+	blockAlign := 0
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkID := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+
+		if chunkID == "fmt " && pos+8+14 <= len(data) {
+			// Block align is at offset 12 of the fmt chunk body
+			blockAlign = int(binary.LittleEndian.Uint16(data[pos+20 : pos+22]))
+		}
+		if chunkID == "data" {
+			if blockAlign <= 0 {
+				return data
+			}
+			audioLen := len(data) - (pos + 8)
+			return data[:len(data)-audioLen%blockAlign]
+		}
+
+		pos += 8 + chunkSize
+		if chunkSize%2 != 0 {
+			pos++
+		}
+	}
+	return data
+}
+
+// StreamingWavHeader returns a 44-byte header for 16-bit PCM audio of
+// unknown length, with the placeholder 0xFFFFFFFF sizes used by streaming
+// responses. Prefixing raw PCM with it makes a stream the WAV decoders
+// accept; FixWavHeader corrects the sizes once the audio is complete.
+func StreamingWavHeader(sampleRate, numChannels int) []byte {
+	return StreamingWavHeaderBits(sampleRate, numChannels, 16)
+}
+
+// StreamingWavHeaderBits is StreamingWavHeader for integer PCM of any
+// whole-byte sample size.
+func StreamingWavHeaderBits(sampleRate, numChannels, bitsPerSample int) []byte {
+	blockAlign := numChannels * bitsPerSample / 8
+
+	h := make([]byte, 44)
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], 0xFFFFFFFF)
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], 1)
+	binary.LittleEndian.PutUint16(h[22:24], uint16(numChannels))
+	binary.LittleEndian.PutUint32(h[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(h[28:32], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:36], uint16(bitsPerSample))
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], 0xFFFFFFFF)
+	return h
+}
+
+// WavChunk is one chunk of a WAV file.
+type WavChunk struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	// Size is the size the chunk header gives, which for the data chunk of
+	// an unfixed streaming response is a placeholder.
+	Size uint32 `json:"size"`
+}
+
+// WavFormat is the body of a WAV file's fmt chunk.
+type WavFormat struct {
+	// AudioFormat is 1 for integer PCM, 3 for floating point and 0xFFFE
+	// for WAVE_FORMAT_EXTENSIBLE.
+	AudioFormat   uint16 `json:"audio_format"`
+	Channels      int    `json:"channels"`
+	SampleRate    int    `json:"sample_rate"`
+	BitsPerSample int    `json:"bits_per_sample"`
+	BlockAlign    int    `json:"block_align"`
+	// SubFormat is the format tag of a WAVE_FORMAT_EXTENSIBLE sub-format
+	// GUID, and ChannelMask its speaker positions.
+	SubFormat   uint16 `json:"sub_format,omitempty"`
+	ChannelMask uint32 `json:"channel_mask,omitempty"`
+}
+
+// WavInfo describes the structure of a WAV file.
+type WavInfo struct {
+	Chunks []WavChunk `json:"chunks"`
+	Format *WavFormat `json:"format,omitempty"`
+	// DataSize is the number of bytes of audio in the data chunk, as far
+	// as the file actually holds them.
+	DataSize int `json:"data_size"`
+	// Placeholder is set when the RIFF or data size is still the 0 or
+	// 0xFFFFFFFF of a streaming response, which FixWavHeader replaces.
+	Placeholder bool `json:"placeholder_sizes"`
+	// SizesMatch is set when the RIFF and data sizes agree with the length
+	// of the file.
+	SizesMatch bool        `json:"sizes_match"`
+	Metadata   WavMetadata `json:"metadata"`
+}
+
+// InspectWav lists the chunks of a WAV file and checks its header sizes.
+// It returns false if data is not a WAV file.
+func InspectWav(data []byte) (*WavInfo, bool) {
+	if len(data) < 12 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WAVE")) {
+		return nil, false
+	}
+	info := &WavInfo{Metadata: ReadMetadata(data)}
+	riffSize := binary.LittleEndian.Uint32(data[4:8])
+	isPlaceholder := func(size uint32) bool { return size == 0 || size == 0xFFFFFFFF }
+	info.Placeholder = isPlaceholder(riffSize)
+	info.SizesMatch = int(riffSize) == len(data)-8
+
+	pos := 12
+	for pos+8 <= len(data) {
+		chunk := WavChunk{
+			ID:     string(data[pos : pos+4]),
+			Offset: pos,
+			Size:   binary.LittleEndian.Uint32(data[pos+4 : pos+8]),
+		}
+		info.Chunks = append(info.Chunks, chunk)
+		body := data[pos+8:]
+
+		switch chunk.ID {
+		case "fmt ":
+			if chunk.Size >= 16 && len(body) >= 16 {
+				info.Format = &WavFormat{
+					AudioFormat:   binary.LittleEndian.Uint16(body[0:2]),
+					Channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+					SampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+					BlockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+					BitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+				}
+				if info.Format.AudioFormat == 0xFFFE && chunk.Size >= 40 && len(body) >= 40 {
+					info.Format.ChannelMask = binary.LittleEndian.Uint32(body[20:24])
+					info.Format.SubFormat = binary.LittleEndian.Uint16(body[24:26])
+				}
+			}
+		case "data":
+			info.DataSize = min(int(chunk.Size), len(body))
+			if isPlaceholder(chunk.Size) {
+				info.Placeholder = true
+				info.DataSize = len(body)
+			}
+			if int(chunk.Size) > len(body) {
+				info.SizesMatch = false
+			}
+			if info.DataSize == len(body) {
+				return info, true
+			}
+		}
+
+		pos += 8 + int(chunk.Size)
+		if chunk.Size%2 != 0 {
+			pos++
+		}
+	}
+	return info, true
+}
+
+// Duration returns the length of the audio the header describes.
+func (i *WavInfo) Duration() time.Duration {
+	if i.Format == nil || i.Format.BlockAlign <= 0 || i.Format.SampleRate <= 0 {
+		return 0
+	}
+	frames := i.DataSize / i.Format.BlockAlign
+	return time.Duration(frames) * time.Second / time.Duration(i.Format.SampleRate)
+}
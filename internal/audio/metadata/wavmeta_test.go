@@ -115,3 +115,96 @@ func TestFixWavHeader_TooShort(t *testing.T) {
 		t.Error("short data should be returned unchanged")
 	}
 }
+
+func TestTrimPartialFrame(t *testing.T) {
+	wav := makeWav(0xFFFFFFFF, 0xFFFFFFFF, 101)
+	binary.LittleEndian.PutUint16(wav[32:34], 4) // block align
+
+	trimmed := TrimPartialFrame(wav)
+	if got, want := len(trimmed), 44+100; got != want {
+		t.Errorf("len = %d, want %d", got, want)
+	}
+
+	whole := wav[:44+100]
+	if got := TrimPartialFrame(whole); len(got) != len(whole) {
+		t.Errorf("whole frames should be kept, got len %d", len(got))
+	}
+
+	data := []byte("not a wav file at all, not even close")
+	if got := TrimPartialFrame(data); string(got) != string(data) {
+		t.Error("non-WAV data should be returned unchanged")
+	}
+}
+
+func TestStreamingWavHeader(t *testing.T) {
+	pcm := make([]byte, 100)
+	data := FixWavHeader(append(StreamingWavHeader(16000, 1), pcm...))
+
+	if len(data) != 144 {
+		t.Fatalf("expected 144 bytes, got %d", len(data))
+	}
+	if got := binary.LittleEndian.Uint32(data[24:28]); got != 16000 {
+		t.Errorf("sample rate = %d, want 16000", got)
+	}
+	if got := binary.LittleEndian.Uint32(data[28:32]); got != 32000 {
+		t.Errorf("byte rate = %d, want 32000", got)
+	}
+	if got := binary.LittleEndian.Uint32(data[40:44]); got != 100 {
+		t.Errorf("data size = %d, want 100", got)
+	}
+	if got := binary.LittleEndian.Uint32(data[4:8]); got != 136 {
+		t.Errorf("RIFF size = %d, want 136", got)
+	}
+}
+
+func TestInspectWav(t *testing.T) {
+	wav := EmbedMetadata(makeWav(100, 136, 100), WavMetadata{Artist: "Rime AI TTS"})
+	binary.LittleEndian.PutUint16(wav[20:22], 1)
+	binary.LittleEndian.PutUint16(wav[22:24], 1)
+	binary.LittleEndian.PutUint32(wav[24:28], 8000)
+	binary.LittleEndian.PutUint16(wav[32:34], 2)
+	binary.LittleEndian.PutUint16(wav[34:36], 16)
+
+	info, ok := InspectWav(wav)
+	if !ok {
+		t.Fatal("InspectWav failed")
+	}
+	var ids []string
+	for _, c := range info.Chunks {
+		ids = append(ids, c.ID)
+	}
+	if len(ids) != 3 || ids[0] != "fmt " || ids[1] != "LIST" || ids[2] != "data" {
+		t.Errorf("chunks = %q, want fmt, LIST, data", ids)
+	}
+	if info.Format == nil || info.Format.SampleRate != 8000 || info.Format.BitsPerSample != 16 {
+		t.Errorf("Format = %+v", info.Format)
+	}
+	if info.DataSize != 100 || info.Placeholder || !info.SizesMatch {
+		t.Errorf("DataSize, Placeholder, SizesMatch = %d, %v, %v; want 100, false, true", info.DataSize, info.Placeholder, info.SizesMatch)
+	}
+	if info.Metadata.Artist != "Rime AI TTS" {
+		t.Errorf("Metadata = %+v", info.Metadata)
+	}
+	if got := info.Duration(); got.Milliseconds() != 6 {
+		t.Errorf("Duration = %v, want 6.25ms", got)
+	}
+}
+
+func TestInspectWav_Placeholder(t *testing.T) {
+	info, ok := InspectWav(makeWav(0xFFFFFFFF, 0xFFFFFFFF, 100))
+	if !ok {
+		t.Fatal("InspectWav failed")
+	}
+	if !info.Placeholder || info.SizesMatch || info.DataSize != 100 {
+		t.Errorf("Placeholder, SizesMatch, DataSize = %v, %v, %d; want true, false, 100", info.Placeholder, info.SizesMatch, info.DataSize)
+	}
+
+	info, _ = InspectWav(FixWavHeader(makeWav(0, 0, 100)))
+	if info.Placeholder || !info.SizesMatch {
+		t.Errorf("fixed header: Placeholder, SizesMatch = %v, %v; want false, true", info.Placeholder, info.SizesMatch)
+	}
+
+	if _, ok := InspectWav([]byte("not a wav file")); ok {
+		t.Error("InspectWav should fail on non-WAV data")
+	}
+}
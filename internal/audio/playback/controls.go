@@ -0,0 +1,174 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
+
+	"github.com/rimelabs/rime-cli/internal/audio/stretch"
+)
+
+const (
+	// VolumeStep is how far one press of + or - changes the volume, in dB.
+	VolumeStep = 3.0
+	minVolume  = -30.0
+	maxVolume  = 12.0
+
+	// SpeedStep is how far one press of [ or ] changes the playback speed.
+	SpeedStep = 0.25
+	// MinSpeed and MaxSpeed bound the playback speed.
+	MinSpeed = 0.5
+	MaxSpeed = 2.0
+)
+
+// Controls pause, seek and change the volume and speed of audio as it
+// plays. Speed is changed by time-stretching, which keeps the pitch. The
+// streamers are changed while holding lock, which for playback is
+// OutputLock, so that playback never sees them half changed.
+type Controls struct {
+	lock    sync.Locker
+	source  beep.StreamSeeker
+	rate    beep.SampleRate
+	stretch *stretch.Stretcher
+	ctrl    *beep.Ctrl
+	volume  *effects.Volume
+	db      float64
+}
+
+// NewControls wraps source, audio at sample rate rate, in controls. Play
+// the streamer Streamer returns on a speaker running at speakerRate; audio
+// at another rate is resampled to it.
+func NewControls(source beep.StreamSeeker, rate, speakerRate beep.SampleRate, lock sync.Locker) *Controls {
+	c := &Controls{lock: lock, source: source, rate: rate}
+	c.stretch = stretch.New(source, rate, 1)
+	var s beep.Streamer = c.stretch
+	if rate != speakerRate {
+		s = beep.Resample(4, rate, speakerRate, s)
+	}
+	c.ctrl = &beep.Ctrl{Streamer: s}
+	c.volume = &effects.Volume{Streamer: c.ctrl, Base: 10}
+	return c
+}
+
+// Streamer returns the controlled audio.
+func (c *Controls) Streamer() beep.Streamer {
+	return c.volume
+}
+
+// Seekable reports whether the audio's length is known, so it can seek.
+func (c *Controls) Seekable() bool {
+	return c.source.Len() > 0
+}
+
+// TogglePause pauses or resumes playback and reports whether it is now
+// paused.
+func (c *Controls) TogglePause() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ctrl.Paused = !c.ctrl.Paused
+	return c.ctrl.Paused
+}
+
+// Paused reports whether playback is paused.
+func (c *Controls) Paused() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.ctrl.Paused
+}
+
+// Position returns how far into the audio playback is.
+func (c *Controls) Position() time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.rate.D(c.stretch.Position())
+}
+
+// Seek moves playback by d, forwards or backwards, stopping at the start
+// and end of the audio.
+func (c *Controls) Seek(d time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.seekTo(c.stretch.Position() + c.rate.N(d))
+}
+
+// Restart moves playback back to the start.
+func (c *Controls) Restart() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.seekTo(0)
+}
+
+func (c *Controls) seekTo(p int) error {
+	length := c.source.Len()
+	if length <= 0 {
+		return fmt.Errorf("seek not supported")
+	}
+	p = max(0, min(p, length))
+	if err := c.source.Seek(p); err != nil {
+		return err
+	}
+	c.stretch.Reset(p)
+	return nil
+}
+
+// ChangeVolume changes the volume by steps of VolumeStep, up or down, and
+// returns the new volume in dB.
+func (c *Controls) ChangeVolume(steps int) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.db = max(minVolume, min(maxVolume, c.db+float64(steps)*VolumeStep))
+	c.volume.Volume = c.db / 20
+	return c.db
+}
+
+// Volume returns the volume in dB, 0 being the audio's own level.
+func (c *Controls) Volume() float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.db
+}
+
+// ChangeSpeed changes the playback speed by steps of SpeedStep, up or
+// down, to the nearest whole step, and returns the new speed.
+func (c *Controls) ChangeSpeed(steps int) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	speed := math.Round(c.stretch.Speed()/SpeedStep+float64(steps)) * SpeedStep
+	return c.setSpeed(speed)
+}
+
+// SetSpeed sets the playback speed, between MinSpeed and MaxSpeed, and
+// returns it.
+func (c *Controls) SetSpeed(speed float64) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.setSpeed(speed)
+}
+
+func (c *Controls) setSpeed(speed float64) float64 {
+	speed = max(MinSpeed, min(MaxSpeed, speed))
+	c.stretch.SetSpeed(speed)
+	return speed
+}
+
+// Speed returns the playback speed, 1 being normal.
+func (c *Controls) Speed() float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stretch.Speed()
+}
+
+// Match sets the volume and speed to those of other, so they carry over
+// from one track of a playlist to the next.
+func (c *Controls) Match(other *Controls) {
+	db, speed := other.Volume(), other.Speed()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.db = db
+	c.volume.Volume = db / 20
+	c.setSpeed(speed)
+}
@@ -0,0 +1,126 @@
+package playback
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/stream"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func newTestControls(t *testing.T, samples int) *Controls {
+	t.Helper()
+	decoder, format, err := stream.DecodeStreaming(bytes.NewReader(testhelpers.MakeValidWAV(samples)))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	return NewControls(decoder, format.SampleRate, format.SampleRate, &sync.Mutex{})
+}
+
+func TestControls_Seek(t *testing.T) {
+	// 24000 samples is one second at 24 kHz.
+	c := newTestControls(t, 24000)
+	if !c.Seekable() {
+		t.Fatal("in-memory WAV should be seekable")
+	}
+
+	if err := c.Seek(500 * time.Millisecond); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if got := c.Position(); got != 500*time.Millisecond {
+		t.Errorf("Position = %v, want 500ms", got)
+	}
+	if err := c.Seek(-5 * time.Second); err != nil || c.Position() != 0 {
+		t.Errorf("seeking before the start: Position = %v, err = %v; want 0", c.Position(), err)
+	}
+	if err := c.Seek(5 * time.Second); err != nil || c.Position() != time.Second {
+		t.Errorf("seeking past the end: Position = %v, err = %v; want 1s", c.Position(), err)
+	}
+	if err := c.Restart(); err != nil || c.Position() != 0 {
+		t.Errorf("Restart: Position = %v, err = %v; want 0", c.Position(), err)
+	}
+
+	samples := make([][2]float64, 100)
+	if n, ok := c.Streamer().Stream(samples); n == 0 || !ok {
+		t.Errorf("Stream after restart = %d, %v", n, ok)
+	}
+}
+
+func TestControls_PauseVolumeSpeed(t *testing.T) {
+	c := newTestControls(t, 2400)
+
+	if !c.TogglePause() || !c.Paused() {
+		t.Error("TogglePause should pause")
+	}
+	samples := make([][2]float64, 10)
+	for i := range samples {
+		samples[i] = [2]float64{1, 1}
+	}
+	if n, ok := c.Streamer().Stream(samples); n != 10 || !ok || samples[0] != [2]float64{} {
+		t.Errorf("paused Stream = %d, %v, %v; want silence", n, ok, samples[0])
+	}
+	if c.TogglePause() {
+		t.Error("TogglePause should resume")
+	}
+
+	if got := c.ChangeVolume(2); got != 2*VolumeStep {
+		t.Errorf("ChangeVolume(2) = %v, want %v", got, 2*VolumeStep)
+	}
+	if got := c.ChangeVolume(-100); got != minVolume || c.Volume() != minVolume {
+		t.Errorf("ChangeVolume(-100) = %v, want %v", got, minVolume)
+	}
+
+	if got := c.ChangeSpeed(1); got != 1+SpeedStep {
+		t.Errorf("ChangeSpeed(1) = %v, want %v", got, 1+SpeedStep)
+	}
+	if got := c.ChangeSpeed(100); got != MaxSpeed || c.Speed() != MaxSpeed {
+		t.Errorf("ChangeSpeed(100) = %v, want %v", got, MaxSpeed)
+	}
+	if got := c.ChangeSpeed(-100); got != MinSpeed {
+		t.Errorf("ChangeSpeed(-100) = %v, want %v", got, MinSpeed)
+	}
+
+	if got := c.SetSpeed(1.6); got != 1.6 || c.Speed() != 1.6 {
+		t.Errorf("SetSpeed(1.6) = %v, want 1.6", got)
+	}
+	if got := c.ChangeSpeed(1); got != 1.75 {
+		t.Errorf("ChangeSpeed(1) from 1.6 = %v, want the next whole step, 1.75", got)
+	}
+	if got := c.SetSpeed(5); got != MaxSpeed {
+		t.Errorf("SetSpeed(5) = %v, want %v", got, MaxSpeed)
+	}
+}
+
+func TestControls_SpeakerRate(t *testing.T) {
+	decoder, _, err := stream.DecodeStreaming(bytes.NewReader(testhelpers.MakeValidWAV(2400)))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	// 2400 samples at 24 kHz play as 4800 at 48 kHz.
+	c := NewControls(decoder, 24000, 48000, &sync.Mutex{})
+	if c.Speed() != 1 {
+		t.Errorf("Speed = %v, want 1", c.Speed())
+	}
+	total := 0
+	samples := make([][2]float64, 512)
+	for {
+		n, ok := c.Streamer().Stream(samples)
+		total += n
+		if !ok {
+			break
+		}
+	}
+	if total < 4790 || total > 4810 {
+		t.Errorf("streamed %d samples, want about 4800", total)
+	}
+
+	other := newTestControls(t, 2400)
+	other.ChangeVolume(-1)
+	other.ChangeSpeed(2)
+	c.Match(other)
+	if c.Volume() != -VolumeStep || c.Speed() != 1+2*SpeedStep {
+		t.Errorf("after Match: Volume = %v, Speed = %v; want %v, %v", c.Volume(), c.Speed(), -VolumeStep, 1+2*SpeedStep)
+	}
+}
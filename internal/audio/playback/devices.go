@@ -0,0 +1,61 @@
+package playback
+
+import (
+	"regexp"
+	"strings"
+)
+
+// parsePactlSinks parses the output of "pactl list sinks" in the C locale
+// into devices, marking defaultSink as the default.
+func parsePactlSinks(out, defaultSink string) []Device {
+	var devices []Device
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Sink #"):
+			devices = append(devices, Device{Backend: "pulse"})
+		case len(devices) == 0:
+		case strings.HasPrefix(line, "Name: "):
+			d := &devices[len(devices)-1]
+			d.Name = strings.TrimPrefix(line, "Name: ")
+			d.Default = d.Name == defaultSink
+		case strings.HasPrefix(line, "Description: "):
+			devices[len(devices)-1].Description = strings.TrimPrefix(line, "Description: ")
+		}
+	}
+	return devices
+}
+
+// pactlDefaultSink returns the default sink from the output of "pactl info"
+// in the C locale.
+func pactlDefaultSink(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "Default Sink: "); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// alsaCardLine matches a card in /proc/asound/cards, e.g.
+// " 0 [PCH            ]: HDA-Intel - HDA Intel PCH".
+var alsaCardLine = regexp.MustCompile(`^\s*(\d+) \[(\S+)\s*\]: .*? - (.*)$`)
+
+// parseALSACards parses /proc/asound/cards into devices named by card ID.
+// Card 0 is ALSA's default.
+func parseALSACards(data string) []Device {
+	var devices []Device
+	for _, line := range strings.Split(data, "\n") {
+		m := alsaCardLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		devices = append(devices, Device{
+			Name:        m[2],
+			Description: strings.TrimSpace(m[3]),
+			Default:     m[1] == "0",
+			Backend:     "alsa",
+		})
+	}
+	return devices
+}
@@ -0,0 +1,33 @@
+package playback
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// listHardwareDevices lists the PulseAudio or PipeWire sinks if a sound
+// server is running, and the ALSA cards otherwise, which there are none of
+// without the ALSA kernel drivers.
+func listHardwareDevices() ([]Device, error) {
+	if out, err := pactl("list", "sinks"); err == nil {
+		info, _ := pactl("info")
+		return parsePactlSinks(out, pactlDefaultSink(info)), nil
+	}
+	data, err := os.ReadFile("/proc/asound/cards")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ALSA cards: %w", err)
+	}
+	return parseALSACards(string(data)), nil
+}
+
+// pactl runs pactl in the C locale, so its output can be parsed.
+func pactl(args ...string) (string, error) {
+	cmd := exec.Command("pactl", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	return string(out), err
+}
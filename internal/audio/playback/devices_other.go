@@ -0,0 +1,9 @@
+//go:build !linux
+
+package playback
+
+// listHardwareDevices returns the system default output alone: on this
+// system audio can't be routed to a particular device.
+func listHardwareDevices() ([]Device, error) {
+	return []Device{{Name: "default", Description: "System default output", Default: true}}, nil
+}
@@ -0,0 +1,43 @@
+package playback
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// nullSink stands in for a sound card: it pulls audio from its mixer a
+// buffer at a time, in real time, and discards it.
+type nullSink struct {
+	mu    sync.Mutex
+	mixer beep.Mixer
+}
+
+// newNullSink starts a null sink pulling bufferSize frames of audio at rate
+// each time that much would have played.
+func newNullSink(rate beep.SampleRate, bufferSize int) *nullSink {
+	n := &nullSink{}
+	buf := make([][2]float64, bufferSize)
+	ticker := time.NewTicker(rate.D(bufferSize))
+	go func() {
+		for range ticker.C {
+			n.mu.Lock()
+			n.mixer.Stream(buf)
+			n.mu.Unlock()
+		}
+	}()
+	return n
+}
+
+func (n *nullSink) play(s ...beep.Streamer) {
+	n.mu.Lock()
+	n.mixer.Add(s...)
+	n.mu.Unlock()
+}
+
+func (n *nullSink) clear() {
+	n.mu.Lock()
+	n.mixer.Clear()
+	n.mu.Unlock()
+}
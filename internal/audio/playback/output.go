@@ -0,0 +1,176 @@
+package playback
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// outputBuffer is how much audio the output buffers ahead of what is heard.
+const outputBuffer = time.Second / 10
+
+// NullDevice is the name of the output device that discards audio. It plays
+// in real time like a sound card, so tests and CI can run the whole playback
+// path without sound hardware.
+const NullDevice = "null"
+
+// Device is an audio output device.
+type Device struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Default marks the system's default output.
+	Default bool `json:"default"`
+	// Backend is how rime reaches the device: "pulse" for a PulseAudio or
+	// PipeWire sink, "alsa" for an ALSA card, or "" for the null device.
+	Backend string `json:"backend,omitempty"`
+}
+
+var nullDevice = Device{Name: NullDevice, Description: "Discards audio, for tests and CI"}
+
+var (
+	outputMu sync.Mutex
+	// device is the output selected, the system default if its Name is "".
+	device Device
+	// outputRate is the sample rate the output was started at, 0 until
+	// something plays.
+	outputRate beep.SampleRate
+	null       *nullSink
+)
+
+// ListDevices returns the audio output devices audio can be played through,
+// the null device last. Headless builds have only the null device.
+func ListDevices() ([]Device, error) {
+	var devices []Device
+	if IsPlaybackEnabled() {
+		var err error
+		if devices, err = listHardwareDevices(); err != nil {
+			return nil, err
+		}
+	}
+	return append(devices, nullDevice), nil
+}
+
+// SelectDevice chooses the output device to play through, by name: one
+// ListDevices returns, NullDevice, or "" or "default" for the system
+// default. It must be called before anything plays.
+func SelectDevice(name string) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	var d Device
+	switch name {
+	case "", "default":
+	case NullDevice:
+		d = nullDevice
+	default:
+		if !IsPlaybackEnabled() {
+			return fmt.Errorf("output device %q is not available in headless build; only %q can be used", name, NullDevice)
+		}
+		devices, err := ListDevices()
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, dev := range devices {
+			if dev.Name == name {
+				d, found = dev, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown output device %q (see 'rime devices')", name)
+		}
+	}
+
+	if outputRate != 0 && d != device {
+		return fmt.Errorf("the output device can't be changed once audio has played")
+	}
+	device = d
+	return nil
+}
+
+// CanPlay reports whether audio can be played: always in builds with audio
+// support, and in headless builds through the null device.
+func CanPlay() bool {
+	return IsPlaybackEnabled() || SelectedDevice() == NullDevice
+}
+
+// SelectedDevice returns the name of the selected output device, "" for the
+// system default.
+func SelectedDevice() string {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return device.Name
+}
+
+// InitSpeaker starts the selected output for audio at rate and returns the
+// rate it plays at. The output can only be started once, so later calls
+// return the first call's rate, and audio at other rates must be resampled
+// to it, as when a playlist mixes sample rates.
+func InitSpeaker(rate beep.SampleRate) (beep.SampleRate, error) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if outputRate == 0 {
+		if device.Name == NullDevice {
+			null = newNullSink(rate, rate.N(outputBuffer))
+		} else if err := initHardware(device, rate); err != nil {
+			return 0, err
+		}
+		outputRate = rate
+	}
+	return outputRate, nil
+}
+
+// Play starts playing streamers through the output, alongside anything
+// already playing.
+func Play(s ...beep.Streamer) {
+	if n := nullOutput(); n != nil {
+		n.play(s...)
+		return
+	}
+	playHardware(s...)
+}
+
+// Clear stops everything playing.
+func Clear() {
+	if n := nullOutput(); n != nil {
+		n.clear()
+		return
+	}
+	clearHardware()
+}
+
+// Lock stops the output pulling audio from the streamers playing, so they
+// can be changed safely. Hold it as briefly as possible.
+func Lock() {
+	if n := nullOutput(); n != nil {
+		n.mu.Lock()
+		return
+	}
+	lockHardware()
+}
+
+// Unlock lets the output pull audio again after Lock.
+func Unlock() {
+	if n := nullOutput(); n != nil {
+		n.mu.Unlock()
+		return
+	}
+	unlockHardware()
+}
+
+// OutputLock is a sync.Locker calling Lock and Unlock, as Controls take.
+var OutputLock sync.Locker = outputLock{}
+
+type outputLock struct{}
+
+func (outputLock) Lock()   { Lock() }
+func (outputLock) Unlock() { Unlock() }
+
+func nullOutput() *nullSink {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return null
+}
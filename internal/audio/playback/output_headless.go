@@ -0,0 +1,22 @@
+//go:build headless
+
+package playback
+
+import (
+	"fmt"
+
+	"github.com/gopxl/beep/v2"
+)
+
+func initHardware(d Device, rate beep.SampleRate) error {
+	return fmt.Errorf("audio playback not available in headless build")
+}
+
+func IsPlaybackEnabled() bool {
+	return false
+}
+
+func playHardware(s ...beep.Streamer) {}
+func clearHardware()                  {}
+func lockHardware()                   {}
+func unlockHardware()                 {}
@@ -0,0 +1,32 @@
+//go:build !headless
+
+package playback
+
+import (
+	"os"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// initHardware starts the speaker on d. The audio library always opens the
+// system default output, so other devices are chosen through the variables
+// PulseAudio, PipeWire and ALSA read to route it.
+func initHardware(d Device, rate beep.SampleRate) error {
+	switch d.Backend {
+	case "pulse":
+		os.Setenv("PULSE_SINK", d.Name)
+	case "alsa":
+		os.Setenv("ALSA_CARD", d.Name)
+	}
+	return speaker.Init(rate, rate.N(outputBuffer))
+}
+
+func IsPlaybackEnabled() bool {
+	return true
+}
+
+func playHardware(s ...beep.Streamer) { speaker.Play(s...) }
+func clearHardware()                  { speaker.Clear() }
+func lockHardware()                   { speaker.Lock() }
+func unlockHardware()                 { speaker.Unlock() }
@@ -0,0 +1,118 @@
+package playback
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// resetOutput restores the output to its state before anything played.
+func resetOutput(t *testing.T) {
+	t.Cleanup(func() {
+		outputMu.Lock()
+		defer outputMu.Unlock()
+		device, outputRate, null = Device{}, 0, nil
+	})
+}
+
+func TestParsePactlSinks(t *testing.T) {
+	out := `Sink #0
+	State: SUSPENDED
+	Name: alsa_output.pci-0000_00_1f.3.analog-stereo
+	Description: Built-in Audio Analog Stereo
+	Driver: module-alsa-card.c
+
+Sink #1
+	State: RUNNING
+	Name: alsa_output.usb-headset.analog-stereo
+	Description: USB Headset Analog Stereo
+`
+	info := "Server Name: PulseAudio (on PipeWire 1.0.5)\nDefault Sink: alsa_output.usb-headset.analog-stereo\nDefault Source: none\n"
+
+	got := parsePactlSinks(out, pactlDefaultSink(info))
+	want := []Device{
+		{Name: "alsa_output.pci-0000_00_1f.3.analog-stereo", Description: "Built-in Audio Analog Stereo", Backend: "pulse"},
+		{Name: "alsa_output.usb-headset.analog-stereo", Description: "USB Headset Analog Stereo", Default: true, Backend: "pulse"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePactlSinks = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseALSACards(t *testing.T) {
+	data := ` 0 [PCH            ]: HDA-Intel - HDA Intel PCH
+                      HDA Intel PCH at 0xf7f10000 irq 32
+ 1 [Headset        ]: USB-Audio - USB Headset
+                      Generic USB Headset at usb-0000:00:14.0-2, full speed
+`
+	got := parseALSACards(data)
+	want := []Device{
+		{Name: "PCH", Description: "HDA Intel PCH", Default: true, Backend: "alsa"},
+		{Name: "Headset", Description: "USB Headset", Backend: "alsa"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseALSACards = %+v, want %+v", got, want)
+	}
+	if got := parseALSACards("--- no soundcards ---\n"); got != nil {
+		t.Errorf("parseALSACards(no cards) = %+v, want none", got)
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	resetOutput(t)
+
+	if err := SelectDevice(NullDevice); err != nil || SelectedDevice() != NullDevice {
+		t.Fatalf("SelectDevice(null): %v, selected %q", err, SelectedDevice())
+	}
+	if err := SelectDevice("default"); err != nil || SelectedDevice() != "" {
+		t.Fatalf("SelectDevice(default): %v, selected %q", err, SelectedDevice())
+	}
+
+	wantErr := "unknown output device"
+	if !IsPlaybackEnabled() {
+		wantErr = "not available in headless build"
+	}
+	if err := SelectDevice("no-such-device"); err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("SelectDevice(no-such-device) error = %v, want %q", err, wantErr)
+	}
+	if devices, err := ListDevices(); !IsPlaybackEnabled() && (err != nil || !reflect.DeepEqual(devices, []Device{nullDevice})) {
+		t.Errorf("headless ListDevices = %+v, %v; want the null device alone", devices, err)
+	}
+}
+
+func TestNullDevice_PlaysInRealTime(t *testing.T) {
+	resetOutput(t)
+	if err := SelectDevice(NullDevice); err != nil {
+		t.Fatal(err)
+	}
+
+	const rate = beep.SampleRate(8000)
+	if got, err := InitSpeaker(rate); err != nil || got != rate {
+		t.Fatalf("InitSpeaker = %v, %v; want %v", got, err, rate)
+	}
+	if got, _ := InitSpeaker(16000); got != rate {
+		t.Errorf("second InitSpeaker = %v, want the first rate %v", got, rate)
+	}
+	if err := SelectDevice("default"); err == nil {
+		t.Error("SelectDevice after playing should fail")
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	Play(beep.Seq(beep.Silence(rate.N(300*time.Millisecond)), beep.Callback(func() { close(done) })))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("audio played through the null device never finished")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("300ms of audio finished after %v, want it played in real time", elapsed)
+	}
+
+	Lock()
+	Unlock()
+	Clear()
+}
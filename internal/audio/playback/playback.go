@@ -1,35 +1,69 @@
-//go:build !headless
-
 package playback
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"time"
 
 	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/speaker"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
 	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
 	"github.com/rimelabs/rime-cli/internal/audio/stream"
+	"github.com/rimelabs/rime-cli/internal/audio/stretch"
 )
 
 func RunNonInteractivePlay(filepath string) error {
+	return RunNonInteractivePlayFormat(filepath, "", 1)
+}
+
+// RunNonInteractivePlayFormat plays a file whose content type is given, as
+// for headerless audio, at speed times its normal speed without changing
+// its pitch. An empty contentType is detected from the file. Stdin ("-")
+// and URLs are played as they arrive.
+func RunNonInteractivePlayFormat(filepath string, contentType string, speed float64) error {
+	ctx := context.Background()
+	if IsStream(filepath) {
+		s, format, err := OpenStream(ctx, filepath, contentType)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		return playStreamer(ctx, s, format, speed)
+	}
+
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		return err
 	}
 
-	contentType := detectformat.DetectFormat(data)
+	if contentType == "" {
+		contentType = detectformat.DetectFile(filepath, data)
+	}
 	if contentType == "" {
 		return fmt.Errorf("unsupported audio format")
 	}
 
-	return PlayAudioData(data, contentType)
+	return playAudioData(ctx, data, contentType, speed)
 }
 
 func PlayAudioData(data []byte, contentType string) error {
+	return PlayAudioDataContext(context.Background(), data, contentType)
+}
+
+// PlayAudioDataContext plays data to completion, or stops playback and
+// returns ctx's error once ctx is done.
+func PlayAudioDataContext(ctx context.Context, data []byte, contentType string) error {
+	return playAudioData(ctx, data, contentType, 1)
+}
+
+func playAudioData(ctx context.Context, data []byte, contentType string, speed float64) error {
+	if f, ok := codec.ParseContentType(contentType); ok {
+		data, contentType = codec.ToWAV(data, f), "audio/wav"
+	}
+
 	var streamer beep.StreamSeekCloser
 	var format beep.Format
 
@@ -44,7 +78,7 @@ func PlayAudioData(data []byte, contentType string) error {
 		format = f
 	case "audio/mpeg", "audio/mp3":
 		reader := bytes.NewReader(data)
-		s, f, err := stream.DecodeMP3Streaming(io.NopCloser(reader))
+		s, f, err := stream.DecodeMP3Streaming(stream.NopSeekCloser(reader))
 		if err != nil {
 			return err
 		}
@@ -55,20 +89,73 @@ func PlayAudioData(data []byte, contentType string) error {
 	}
 	defer streamer.Close()
 
-	err := speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
+	s, err := speakerStreamer(streamer, format.SampleRate, speed)
 	if err != nil {
 		return err
 	}
 
 	done := make(chan struct{})
-	speaker.Play(beep.Seq(streamer, beep.Callback(func() {
+	Play(beep.Seq(s, beep.Callback(func() {
 		close(done)
 	})))
-	<-done
 
-	return nil
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		Clear()
+		return ctx.Err()
+	}
 }
 
-func IsPlaybackEnabled() bool {
-	return true
+// PlayStreamContext plays audio from r as it arrives, for streams with no
+// known end such as a WebSocket session. It returns once r is exhausted and
+// the audio has played, or stops playback and returns ctx's error once ctx
+// is done.
+func PlayStreamContext(ctx context.Context, r io.Reader, contentType string) error {
+	decoder, format, err := decode.DecodeAudio(r, contentType)
+	if err != nil {
+		return err
+	}
+	return playStreamer(ctx, decoder, format, 1)
+}
+
+// playStreamer plays decoder, audio of format that may still be arriving,
+// at speed to its end, or stops playback and returns ctx's error once ctx
+// is done.
+func playStreamer(ctx context.Context, decoder beep.Streamer, format beep.Format, speed float64) error {
+	s, err := speakerStreamer(decoder, format.SampleRate, speed)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	Play(beep.Seq(s, beep.Callback(func() {
+		close(done)
+	})))
+
+	select {
+	case <-done:
+		return decoder.Err()
+	case <-ctx.Done():
+		Clear()
+		return ctx.Err()
+	}
+}
+
+// speakerStreamer starts the output and returns s, audio at rate,
+// time-stretched to speed and resampled to the output's rate if they
+// differ.
+func speakerStreamer(s beep.Streamer, rate beep.SampleRate, speed float64) (beep.Streamer, error) {
+	out, err := InitSpeaker(rate)
+	if err != nil {
+		return nil, err
+	}
+	if speed != 1 {
+		s = stretch.New(s, rate, speed)
+	}
+	if out != rate {
+		return beep.Resample(4, rate, out, s), nil
+	}
+	return s, nil
 }
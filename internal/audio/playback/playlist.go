@@ -0,0 +1,209 @@
+package playback
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+)
+
+// commentHeadSize is how much of a file is read for its embedded transcript.
+// rime tts writes the WAV LIST chunk before the audio and the ID3 tag opens
+// an MP3, so the transcript is near the start.
+const commentHeadSize = 64 << 10
+
+// Track is a file in a playlist.
+type Track struct {
+	Path string
+	// ContentType is the file's content type, or "" to detect it when it
+	// is played.
+	ContentType string
+	// Comment holds the speaker, model, language and text rime tts embedded
+	// in the file, or nil if it has none.
+	Comment *metadata.ParsedComment
+}
+
+// ExpandPaths replaces each directory in paths with the audio files directly
+// in it, sorted by name. Files named explicitly are kept whatever their
+// extension, as are streams: stdin ("-"), which may be given once, and URLs.
+func ExpandPaths(paths []string) ([]string, error) {
+	var files []string
+	stdin := false
+	for _, path := range paths {
+		if IsStream(path) {
+			if path == "-" {
+				if stdin {
+					return nil, fmt.Errorf("stdin (-) can only be played once")
+				}
+				stdin = true
+			}
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("file not found: %s", path)
+			}
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		n := len(files)
+		for _, e := range entries {
+			if !e.IsDir() && detectformat.FromExtension(e.Name()) != "" {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+		if len(files) == n {
+			return nil, fmt.Errorf("no audio files in %s", path)
+		}
+	}
+	return files, nil
+}
+
+// NewTrack returns the track for the file at path, read as contentType, or
+// detected if it is "". It reads the transcript rime tts embedded in the
+// file, if any; a stream's is read once it plays.
+func NewTrack(path, contentType string) Track {
+	t := Track{Path: path, ContentType: contentType}
+	if IsStream(path) || codec.IsRaw(contentType) || codec.IsRaw(detectformat.FromExtension(path)) {
+		return t
+	}
+	head, err := readHead(path, commentHeadSize)
+	if err != nil {
+		return t
+	}
+	if comment, ok := metadata.GetParsedCommentFromFile(head); ok {
+		t.Comment = comment
+	}
+	return t
+}
+
+// readHead reads up to n bytes from the start of the file at path.
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// Playlist is the order tracks play in, which may be shuffled, and the
+// track playing.
+type Playlist struct {
+	Tracks []Track
+	// Loop starts the playlist over after its last track.
+	Loop bool
+
+	order    []int
+	pos      int
+	shuffled bool
+	rand     *rand.Rand
+}
+
+// NewPlaylist returns a playlist of tracks in order, at the first track.
+func NewPlaylist(tracks []Track) *Playlist {
+	p := &Playlist{Tracks: tracks, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	p.order = make([]int, len(tracks))
+	for i := range p.order {
+		p.order[i] = i
+	}
+	return p
+}
+
+// Current returns the index in Tracks of the track playing.
+func (p *Playlist) Current() int {
+	return p.order[p.pos]
+}
+
+// Track returns the track playing.
+func (p *Playlist) Track() Track {
+	return p.Tracks[p.Current()]
+}
+
+// Position returns how many tracks have played before the current one, in
+// the play order.
+func (p *Playlist) Position() int {
+	return p.pos
+}
+
+// Next moves to the next track, or to the first once the last has played
+// if the playlist loops, and reports whether there was one. A shuffled
+// playlist is shuffled again each time it starts over.
+func (p *Playlist) Next() bool {
+	if p.pos+1 < len(p.order) {
+		p.pos++
+		return true
+	}
+	if !p.Loop {
+		return false
+	}
+	p.pos = 0
+	if p.shuffled {
+		p.rand.Shuffle(len(p.order), func(i, j int) {
+			p.order[i], p.order[j] = p.order[j], p.order[i]
+		})
+	}
+	return true
+}
+
+// Previous moves to the previous track, or to the last from the first if
+// the playlist loops, and reports whether there was one.
+func (p *Playlist) Previous() bool {
+	if p.pos > 0 {
+		p.pos--
+		return true
+	}
+	if !p.Loop {
+		return false
+	}
+	p.pos = len(p.order) - 1
+	return true
+}
+
+// Shuffled reports whether the playlist is shuffled.
+func (p *Playlist) Shuffled() bool {
+	return p.shuffled
+}
+
+// SetShuffle shuffles the tracks or puts them back in order. The current
+// track keeps playing: shuffled, the other tracks follow it in random order;
+// unshuffled, the tracks after it in Tracks follow.
+func (p *Playlist) SetShuffle(on bool) {
+	current := p.Current()
+	p.shuffled = on
+	for i := range p.order {
+		p.order[i] = i
+	}
+	if !on {
+		p.pos = current
+		return
+	}
+	p.order[0], p.order[current] = current, 0
+	rest := p.order[1:]
+	p.rand.Shuffle(len(rest), func(i, j int) {
+		rest[i], rest[j] = rest[j], rest[i]
+	})
+	p.pos = 0
+}
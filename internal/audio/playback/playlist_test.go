@@ -0,0 +1,137 @@
+package playback
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.wav", "a.mp3", "c.ulaw", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.wav"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(dir, "notes.txt")
+
+	got, err := ExpandPaths([]string{notes, dir})
+	if err != nil {
+		t.Fatalf("ExpandPaths failed: %v", err)
+	}
+	want := []string{notes, filepath.Join(dir, "a.mp3"), filepath.Join(dir, "b.wav"), filepath.Join(dir, "c.ulaw")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandPaths = %v, want %v", got, want)
+	}
+
+	streams := []string{"-", "https://example.com/clip.mp3"}
+	if got, err := ExpandPaths(streams); err != nil || !reflect.DeepEqual(got, streams) {
+		t.Errorf("ExpandPaths(%v) = %v, %v; want streams kept", streams, got, err)
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		wantErr string
+	}{
+		{"missing file", []string{filepath.Join(dir, "missing.wav")}, "file not found"},
+		{"no audio", []string{filepath.Join(dir, "sub.wav")}, "no audio files"},
+		{"stdin twice", []string{"-", "-"}, "only be played once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandPaths(tt.paths)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExpandPaths error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTrack(t *testing.T) {
+	dir := t.TempDir()
+	tagged := filepath.Join(dir, "tagged.wav")
+	data := metadata.EmbedMetadata(testhelpers.MakeValidWAV(2400), metadata.WavMetadata{
+		Comment: "[astra-arcana-eng]: Your balance is ready.",
+	})
+	if err := os.WriteFile(tagged, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain.wav")
+	if err := os.WriteFile(plain, testhelpers.MakeValidWAV(2400), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	track := NewTrack(tagged, "")
+	if track.Comment == nil || track.Comment.Speaker != "astra" || track.Comment.ModelID != "arcana" || track.Comment.Language != "eng" {
+		t.Errorf("NewTrack(tagged).Comment = %+v", track.Comment)
+	}
+	if track := NewTrack(plain, ""); track.Comment != nil {
+		t.Errorf("NewTrack(plain).Comment = %+v, want nil", track.Comment)
+	}
+}
+
+func testPlaylist(n int) *Playlist {
+	tracks := make([]Track, n)
+	for i := range tracks {
+		tracks[i] = Track{Path: string(rune('a'+i)) + ".wav"}
+	}
+	return NewPlaylist(tracks)
+}
+
+func TestPlaylist_NextPrevious(t *testing.T) {
+	p := testPlaylist(3)
+	if p.Previous() {
+		t.Error("Previous from the first track should fail without loop")
+	}
+	for want := 1; want < 3; want++ {
+		if !p.Next() || p.Current() != want {
+			t.Fatalf("Next: Current = %d, want %d", p.Current(), want)
+		}
+	}
+	if p.Next() {
+		t.Error("Next from the last track should fail without loop")
+	}
+
+	p.Loop = true
+	if !p.Next() || p.Current() != 0 {
+		t.Errorf("looping Next: Current = %d, want 0", p.Current())
+	}
+	if !p.Previous() || p.Current() != 2 {
+		t.Errorf("looping Previous: Current = %d, want 2", p.Current())
+	}
+}
+
+func TestPlaylist_Shuffle(t *testing.T) {
+	p := testPlaylist(8)
+	p.Next()
+	p.Next()
+
+	p.SetShuffle(true)
+	if !p.Shuffled() || p.Current() != 2 || p.Position() != 0 {
+		t.Fatalf("after shuffling: Current = %d, Position = %d; want track 2 first", p.Current(), p.Position())
+	}
+	seen := []int{p.Current()}
+	for p.Next() {
+		seen = append(seen, p.Current())
+	}
+	sort.Ints(seen)
+	if !reflect.DeepEqual(seen, []int{0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("shuffled playlist played %v, want each track once", seen)
+	}
+
+	current := p.Current()
+	p.SetShuffle(false)
+	if p.Shuffled() || p.Current() != current || p.Position() != current {
+		t.Errorf("after unshuffling: Current = %d, Position = %d; want %d", p.Current(), p.Position(), current)
+	}
+}
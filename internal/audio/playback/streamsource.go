@@ -0,0 +1,139 @@
+package playback
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gopxl/beep/v2"
+
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+)
+
+// IsStream reports whether path names audio that is played as it arrives
+// rather than a file: "-" for stdin, or an HTTP or HTTPS URL.
+func IsStream(path string) bool {
+	return path == "-" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// StreamSource is audio decoded as it is read from stdin or a URL. It can't
+// seek: Len is 0 and Position counts the frames streamed so far.
+type StreamSource struct {
+	// ContentType is the audio's content type, sniffed from its first
+	// bytes unless it was given.
+	ContentType string
+	// Comment holds the speaker, model, language and text rime tts embedded
+	// in the audio's header, or nil if it has none.
+	Comment *metadata.ParsedComment
+
+	decoder decode.AudioDecoder
+	body    io.Closer
+	pos     int
+}
+
+// OpenStream opens path, "-" for stdin or an HTTP or HTTPS URL, and starts
+// decoding it as contentType, or as the format its first bytes show if
+// contentType is "". Only the header is read before it returns.
+func OpenStream(ctx context.Context, path, contentType string) (*StreamSource, beep.Format, error) {
+	body, err := openStreamBody(ctx, path)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	br := bufio.NewReader(body)
+	if contentType == "" {
+		first, _ := br.Peek(4)
+		contentType = detectformat.DetectFormat(first)
+		if contentType == "" {
+			body.Close()
+			return nil, beep.Format{}, fmt.Errorf("unrecognized audio format: %s (use --format)", path)
+		}
+	}
+
+	// The header is recorded as it is decoded, so the transcript in a WAV
+	// LIST chunk or an ID3 tag can be read without buffering the audio.
+	head := &headRecorder{r: br, limit: commentHeadSize}
+	decoder, format, err := decode.DecodeAudio(head, contentType)
+	if err != nil {
+		body.Close()
+		return nil, beep.Format{}, err
+	}
+
+	s := &StreamSource{ContentType: contentType, decoder: decoder, body: body}
+	if !codec.IsRaw(contentType) {
+		if comment, ok := metadata.GetParsedCommentFromFile(head.buf); ok {
+			s.Comment = comment
+		}
+	}
+	head.limit = 0
+	return s, format, nil
+}
+
+// openStreamBody opens stdin or GETs a URL.
+func openStreamBody(ctx context.Context, path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (s *StreamSource) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = s.decoder.Stream(samples)
+	s.pos += n
+	return n, ok
+}
+
+func (s *StreamSource) Err() error {
+	return s.decoder.Err()
+}
+
+func (s *StreamSource) Len() int {
+	return 0
+}
+
+func (s *StreamSource) Position() int {
+	return s.pos
+}
+
+func (s *StreamSource) Seek(p int) error {
+	return fmt.Errorf("seek not supported")
+}
+
+// Close stops reading the audio. Stdin is left open.
+func (s *StreamSource) Close() error {
+	return s.body.Close()
+}
+
+// headRecorder keeps a copy of the first limit bytes read from r.
+type headRecorder struct {
+	r     io.Reader
+	buf   []byte
+	limit int
+}
+
+func (h *headRecorder) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	if keep := min(n, h.limit-len(h.buf)); keep > 0 {
+		h.buf = append(h.buf, p[:keep]...)
+	}
+	return n, err
+}
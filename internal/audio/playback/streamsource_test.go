@@ -0,0 +1,99 @@
+package playback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func TestIsStream(t *testing.T) {
+	for path, want := range map[string]bool{
+		"-":                         true,
+		"https://example.com/a.mp3": true,
+		"http://localhost/a.wav":    true,
+		"clip.wav":                  false,
+		"-clip.wav":                 false,
+	} {
+		if got := IsStream(path); got != want {
+			t.Errorf("IsStream(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestOpenStream_URL(t *testing.T) {
+	wav := metadata.EmbedMetadata(testhelpers.MakeValidWAV(2400), metadata.WavMetadata{
+		Comment: "[astra-arcana-eng]: Your balance is ready.",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/clip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(wav)
+	}))
+	defer server.Close()
+
+	s, format, err := OpenStream(context.Background(), server.URL+"/clip", "")
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer s.Close()
+	if s.ContentType != "audio/wav" || format.SampleRate != 24000 {
+		t.Errorf("ContentType = %q, SampleRate = %d", s.ContentType, format.SampleRate)
+	}
+	if s.Comment == nil || s.Comment.Speaker != "astra" {
+		t.Errorf("Comment = %+v, want speaker astra", s.Comment)
+	}
+
+	samples := make([][2]float64, 512)
+	for {
+		if _, ok := s.Stream(samples); !ok {
+			break
+		}
+	}
+	if s.Position() != 2400 || s.Len() != 0 || s.Seek(0) == nil {
+		t.Errorf("Position = %d, Len = %d; want 2400 frames and no seeking", s.Position(), s.Len())
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"not found", server.URL + "/missing", "404"},
+		{"bad url", "http://", "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := OpenStream(context.Background(), tt.path, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenStream error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenStream_Stdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		w.Write([]byte("not audio at all"))
+		w.Close()
+	}()
+	_, _, err = OpenStream(context.Background(), "-", "")
+	if err == nil || !strings.Contains(err.Error(), "unrecognized audio format") {
+		t.Errorf("OpenStream error = %v, want unrecognized audio format", err)
+	}
+}
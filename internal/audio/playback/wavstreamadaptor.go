@@ -1,9 +1,6 @@
-//go:build !headless
-
 package playback
 
 import (
-	"fmt"
 	"io"
 
 	"github.com/rimelabs/rime-cli/internal/audio/stream"
@@ -22,24 +19,21 @@ func (w *wavStreamerAdapter) Err() error {
 	return w.decoder.Err()
 }
 
-// Len returns -1 to indicate unknown length for streaming WAV data.
-// This is the key part that enables streaming - returning -1 signals
-// to the audio library that the stream length is not known upfront.
+// Len returns the number of sample frames, or -1 when the WAV data is a
+// stream whose length isn't known upfront.
 func (w *wavStreamerAdapter) Len() int {
-	return -1
+	return w.decoder.Len()
 }
 
-// Position returns -1 to indicate that position tracking is not
-// meaningful for streaming WAV data. This is required for streaming
-// support - returning -1 signals that position cannot be determined.
+// Position returns the number of sample frames played so far.
 func (w *wavStreamerAdapter) Position() int {
-	return -1
+	return w.decoder.Position()
 }
 
-// Seek is not supported for streaming WAV data since we cannot
-// rewind or jump to arbitrary positions in a stream.
+// Seek moves to sample frame p. Streams that can't be rewound, such as an
+// HTTP response, return an error.
 func (w *wavStreamerAdapter) Seek(p int) error {
-	return fmt.Errorf("seek not supported")
+	return w.decoder.Seek(p)
 }
 
 func (w *wavStreamerAdapter) Close() error {
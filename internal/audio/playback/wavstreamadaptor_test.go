@@ -1,5 +1,3 @@
-//go:build !headless
-
 package playback
 
 import (
@@ -56,7 +54,7 @@ func TestWavStreamerAdapter_Err(t *testing.T) {
 }
 
 func TestWavStreamerAdapter_Len(t *testing.T) {
-	wav := testhelpers.MakeMinimalWAV()
+	wav := testhelpers.MakeValidWAV(100)
 	reader := bytes.NewReader(wav)
 	decoder, _, err := stream.DecodeStreaming(reader)
 	if err != nil {
@@ -69,13 +67,29 @@ func TestWavStreamerAdapter_Len(t *testing.T) {
 	}
 	defer adapter.Close()
 
+	if adapter.Len() != 100 {
+		t.Errorf("Expected Len() to return 100, got: %d", adapter.Len())
+	}
+}
+
+func TestWavStreamerAdapter_LenUnknownForStreams(t *testing.T) {
+	wav := testhelpers.MakeValidWAV(100)
+	decoder, _, err := stream.DecodeStreaming(io.LimitReader(bytes.NewReader(wav), int64(len(wav))))
+	if err != nil {
+		t.Fatalf("Failed to decode WAV: %v", err)
+	}
+
+	adapter := &wavStreamerAdapter{decoder: decoder}
 	if adapter.Len() != -1 {
 		t.Errorf("Expected Len() to return -1, got: %d", adapter.Len())
 	}
+	if err := adapter.Seek(0); err == nil {
+		t.Error("Expected Seek to return an error for a stream")
+	}
 }
 
 func TestWavStreamerAdapter_Position(t *testing.T) {
-	wav := testhelpers.MakeMinimalWAV()
+	wav := testhelpers.MakeValidWAV(100)
 	reader := bytes.NewReader(wav)
 	decoder, _, err := stream.DecodeStreaming(reader)
 	if err != nil {
@@ -88,13 +102,17 @@ func TestWavStreamerAdapter_Position(t *testing.T) {
 	}
 	defer adapter.Close()
 
-	if adapter.Position() != -1 {
-		t.Errorf("Expected Position() to return -1, got: %d", adapter.Position())
+	if adapter.Position() != 0 {
+		t.Errorf("Expected Position() to return 0, got: %d", adapter.Position())
+	}
+	adapter.Stream(make([][2]float64, 30))
+	if adapter.Position() != 30 {
+		t.Errorf("Expected Position() to return 30, got: %d", adapter.Position())
 	}
 }
 
 func TestWavStreamerAdapter_Seek(t *testing.T) {
-	wav := testhelpers.MakeMinimalWAV()
+	wav := testhelpers.MakeValidWAV(100)
 	reader := bytes.NewReader(wav)
 	decoder, _, err := stream.DecodeStreaming(reader)
 	if err != nil {
@@ -107,9 +125,15 @@ func TestWavStreamerAdapter_Seek(t *testing.T) {
 	}
 	defer adapter.Close()
 
-	err = adapter.Seek(0)
-	if err == nil {
-		t.Error("Expected Seek to return an error")
+	if err := adapter.Seek(60); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	samples := make([][2]float64, 100)
+	if n, _ := adapter.Stream(samples); n != 40 {
+		t.Errorf("Expected 40 frames after seeking to 60, got: %d", n)
+	}
+	if err := adapter.Seek(101); err == nil {
+		t.Error("Expected Seek past the end to return an error")
 	}
 }
 
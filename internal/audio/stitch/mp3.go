@@ -0,0 +1,115 @@
+package stitch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+)
+
+// silentFrame returns a frame with the same stream parameters as f whose
+// side information is all zero, which decodes to silence.
+func silentFrame(f metadata.MP3Frame) []byte {
+	h := f.Header
+	h[1] |= 0x01  // no CRC
+	h[2] &^= 0x02 // no padding
+	padded, _ := metadata.ParseMP3Frame(h[:])
+	frame := make([]byte, padded.Length)
+	copy(frame, h[:])
+	return frame
+}
+
+func joinMP3(w io.Writer, next Next, gap time.Duration) error {
+	var first *metadata.MP3Frame
+	var silence []byte
+	for i := 1; ; i++ {
+		part, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = func() error {
+			defer part.Close()
+
+			br := bufio.NewReader(part)
+			if err := skipID3v2(br); err != nil {
+				return fmt.Errorf("part %d: %w", i, err)
+			}
+
+			frames := 0
+			for {
+				b, _ := br.Peek(4)
+				if len(b) < 4 {
+					break
+				}
+				if bytes.HasPrefix(b, []byte("TAG")) {
+					// ID3v1 trailer
+					br.Discard(128)
+					continue
+				}
+				f, ok := metadata.ParseMP3Frame(b)
+				if !ok {
+					br.Discard(1)
+					continue
+				}
+
+				frame := make([]byte, f.Length)
+				n, _ := io.ReadFull(br, frame)
+				frame = frame[:n]
+				if f.InfoTag(frame) != "" {
+					continue
+				}
+
+				if frames == 0 {
+					if first == nil {
+						first = &f
+						count := int(math.Round(gap.Seconds() * float64(f.SampleRate) / float64(f.Samples)))
+						silence = bytes.Repeat(silentFrame(f), count)
+					} else {
+						if f.SampleRate != first.SampleRate || f.MPEG1 != first.MPEG1 {
+							return fmt.Errorf("part %d: audio format differs from part 1", i)
+						}
+						if _, err := w.Write(silence); err != nil {
+							return err
+						}
+					}
+				}
+				frames++
+				if _, err := w.Write(frame); err != nil {
+					return err
+				}
+			}
+			if frames == 0 {
+				return fmt.Errorf("part %d: no MP3 frames found", i)
+			}
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func skipID3v2(br *bufio.Reader) error {
+	b, _ := br.Peek(10)
+	if len(b) < 10 || !bytes.HasPrefix(b, []byte("ID3")) {
+		return nil
+	}
+	size := int(b[6]&0x7F)<<21 | int(b[7]&0x7F)<<14 | int(b[8]&0x7F)<<7 | int(b[9]&0x7F)
+	size += 10
+	if b[5]&0x10 != 0 {
+		// footer present
+		size += 10
+	}
+	if _, err := br.Discard(size); err != nil {
+		return fmt.Errorf("failed to skip ID3 tag: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Package stitch joins consecutive WAV or MP3 responses into one continuous
+// stream, so audio synthesized in several requests plays and saves as a
+// single file.
+package stitch
+
+import (
+	"io"
+	"time"
+)
+
+// Next returns the next part to append, or io.EOF when there are no more.
+type Next func() (io.ReadCloser, error)
+
+// WAV joins WAV parts into one stream. The first part's header is kept with
+// its RIFF and data sizes set to the streaming placeholder 0xFFFFFFFF (fix
+// them with metadata.FixWavHeader once the stream is complete); later parts
+// contribute only their sample data and must have the same format. gap of
+// silence is inserted between parts.
+func WAV(next Next, gap time.Duration) io.ReadCloser {
+	return join(func(w io.Writer) error { return joinWAV(w, next, gap) })
+}
+
+// MP3 joins MP3 parts into one stream of frames. ID3 tags and Xing/Info
+// frames are dropped from every part, since they describe a single part
+// rather than the whole stream. gap is filled with silent frames.
+func MP3(next Next, gap time.Duration) io.ReadCloser {
+	return join(func(w io.Writer) error { return joinMP3(w, next, gap) })
+}
+
+// join runs fn in the background, exposing what it writes as a reader.
+// Closing the reader makes further writes fail, which stops fn.
+func join(fn func(w io.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(fn(pw))
+	}()
+	return pr
+}
@@ -0,0 +1,207 @@
+package stitch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func parts(data ...[]byte) Next {
+	i := 0
+	return func() (io.ReadCloser, error) {
+		if i >= len(data) {
+			return nil, io.EOF
+		}
+		i++
+		return io.NopCloser(bytes.NewReader(data[i-1])), nil
+	}
+}
+
+// makeWAV returns a 24kHz mono 16-bit WAV whose samples are all value.
+func makeWAV(samples int, value int16) []byte {
+	data := testhelpers.MakeValidWAV(samples)
+	for i := 44; i < len(data); i += 2 {
+		binary.LittleEndian.PutUint16(data[i:], uint16(value))
+	}
+	return data
+}
+
+func TestWAV_JoinsSampleData(t *testing.T) {
+	r := WAV(parts(makeWAV(100, 1), makeWAV(50, 2), makeWAV(25, 3)), 0)
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	if got, want := len(out), 44+(100+50+25)*2; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	if bytes.Count(out, []byte("RIFF")) != 1 {
+		t.Error("expected a single RIFF header")
+	}
+	if size := binary.LittleEndian.Uint32(out[40:44]); size != streamingSize {
+		t.Errorf("data size = %#x, want streaming placeholder", size)
+	}
+
+	fixed := metadata.FixWavHeader(out)
+	if size := binary.LittleEndian.Uint32(fixed[40:44]); size != 350 {
+		t.Errorf("fixed data size = %d, want 350", size)
+	}
+
+	samples := out[44:]
+	for i, want := range map[int]int16{0: 1, 99: 1, 100: 2, 149: 2, 150: 3, 174: 3} {
+		if got := int16(binary.LittleEndian.Uint16(samples[i*2:])); got != want {
+			t.Errorf("sample %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestWAV_InsertsSilence(t *testing.T) {
+	r := WAV(parts(makeWAV(10, 1), makeWAV(10, 2)), 10*time.Millisecond)
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	// 10ms at 24kHz is 240 samples.
+	if got, want := len(out), 44+(10+240+10)*2; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	gap := out[44+20 : 44+20+480]
+	if !bytes.Equal(gap, make([]byte, 480)) {
+		t.Error("expected zero samples between parts")
+	}
+}
+
+func TestWAV_FormatMismatch(t *testing.T) {
+	other := makeWAV(10, 1)
+	binary.LittleEndian.PutUint32(other[24:28], 44100)
+
+	r := WAV(parts(makeWAV(10, 1), other), 0)
+	defer r.Close()
+
+	_, err := io.ReadAll(r)
+	if err == nil || !strings.Contains(err.Error(), "part 2") {
+		t.Errorf("expected format mismatch error for part 2, got %v", err)
+	}
+}
+
+func TestWAV_NextError(t *testing.T) {
+	calls := 0
+	next := func() (io.ReadCloser, error) {
+		calls++
+		if calls == 1 {
+			return io.NopCloser(bytes.NewReader(makeWAV(10, 1))), nil
+		}
+		return nil, errors.New("request failed")
+	}
+
+	r := WAV(next, 0)
+	defer r.Close()
+
+	_, err := io.ReadAll(r)
+	if err == nil || err.Error() != "request failed" {
+		t.Errorf("expected next() error, got %v", err)
+	}
+}
+
+// 128kbps 44.1kHz MPEG-1 Layer III, no CRC: 417 byte frames.
+var mp3Header = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+func makeMP3Frame(fill byte) []byte {
+	frame := bytes.Repeat([]byte{fill}, 417)
+	copy(frame, mp3Header)
+	return frame
+}
+
+func makeInfoFrame() []byte {
+	frame := make([]byte, 417)
+	copy(frame, mp3Header)
+	copy(frame[4+32:], "Info")
+	return frame
+}
+
+func TestMP3_JoinsFrames(t *testing.T) {
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 4, 'j', 'u', 'n', 'k'}
+
+	first := append(append([]byte{}, id3...), makeInfoFrame()...)
+	first = append(first, makeMP3Frame(1)...)
+	first = append(first, makeMP3Frame(2)...)
+
+	second := append(append([]byte{}, id3...), makeMP3Frame(3)...)
+	second = append(second, []byte("TAG")...)
+	second = append(second, make([]byte, 125)...)
+
+	r := MP3(parts(first, second), 0)
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	want := append(append(makeMP3Frame(1), makeMP3Frame(2)...), makeMP3Frame(3)...)
+	if !bytes.Equal(out, want) {
+		t.Errorf("got %d bytes, want the 3 audio frames (%d bytes)", len(out), len(want))
+	}
+}
+
+func TestMP3_InsertsSilentFrames(t *testing.T) {
+	r := MP3(parts(makeMP3Frame(1), makeMP3Frame(2)), 130*time.Millisecond)
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	// 130ms at 44.1kHz is ~5 frames of 1152 samples.
+	if got, want := len(out), 417*7; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	silent := out[417 : 417*2]
+	if !bytes.Equal(silent[:4], mp3Header) {
+		t.Errorf("silent frame header = % x, want % x", silent[:4], mp3Header)
+	}
+	if !bytes.Equal(silent[4:], make([]byte, 413)) {
+		t.Error("expected silent frame body to be zero")
+	}
+}
+
+func TestMP3_NoFrames(t *testing.T) {
+	r := MP3(parts([]byte("not audio at all")), 0)
+	defer r.Close()
+
+	_, err := io.ReadAll(r)
+	if err == nil || !strings.Contains(err.Error(), "no MP3 frames") {
+		t.Errorf("expected no frames error, got %v", err)
+	}
+}
+
+func TestClose_StopsJoining(t *testing.T) {
+	next := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(makeWAV(10, 1))), nil
+	}
+
+	r := WAV(next, 0)
+	buf := make([]byte, 10)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	r.Close()
+
+	if _, err := r.Read(buf); err == nil {
+		t.Error("expected read after Close to fail")
+	}
+}
@@ -0,0 +1,142 @@
+package stitch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+const streamingSize = 0xFFFFFFFF
+
+// maxHeaderChunk bounds the size of chunks read before the data chunk.
+const maxHeaderChunk = 1 << 20
+
+type wavHeader struct {
+	// raw holds every byte from "RIFF" up to and including the data chunk
+	// header.
+	raw []byte
+	// fmt is the body of the fmt chunk.
+	fmt        []byte
+	dataSize   uint32
+	sampleRate uint32
+	blockAlign uint16
+	bits       uint16
+}
+
+func joinWAV(w io.Writer, next Next, gap time.Duration) error {
+	var first *wavHeader
+	var silence []byte
+	for i := 1; ; i++ {
+		part, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = func() error {
+			defer part.Close()
+
+			h, err := readWAVHeader(part)
+			if err != nil {
+				return fmt.Errorf("part %d: %w", i, err)
+			}
+
+			if first == nil {
+				first = h
+				silence = wavSilence(h, gap)
+				header := append([]byte(nil), h.raw...)
+				binary.LittleEndian.PutUint32(header[4:8], streamingSize)
+				binary.LittleEndian.PutUint32(header[len(header)-4:], streamingSize)
+				if _, err := w.Write(header); err != nil {
+					return err
+				}
+			} else {
+				if !bytes.Equal(h.fmt[:16], first.fmt[:16]) {
+					return fmt.Errorf("part %d: audio format differs from part 1", i)
+				}
+				if _, err := w.Write(silence); err != nil {
+					return err
+				}
+			}
+
+			body := io.Reader(part)
+			if h.dataSize != 0 && h.dataSize != streamingSize {
+				body = io.LimitReader(part, int64(h.dataSize))
+			}
+			_, err = io.Copy(w, body)
+			return err
+		}()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readWAVHeader(r io.Reader) (*wavHeader, error) {
+	var raw bytes.Buffer
+	tr := io.TeeReader(r, &raw)
+
+	var riff [12]byte
+	if _, err := io.ReadFull(tr, riff[:]); err != nil {
+		return nil, fmt.Errorf("failed to read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV stream")
+	}
+
+	h := &wavHeader{}
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(tr, chunk[:]); err != nil {
+			return nil, fmt.Errorf("failed to read chunk header: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		if id == "data" {
+			if h.fmt == nil {
+				return nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			h.raw = raw.Bytes()
+			h.dataSize = size
+			return h, nil
+		}
+
+		if size > maxHeaderChunk {
+			return nil, fmt.Errorf("%s chunk too large", id)
+		}
+		body := make([]byte, size+size%2)
+		if _, err := io.ReadFull(tr, body); err != nil {
+			return nil, fmt.Errorf("failed to read %s chunk: %w", id, err)
+		}
+		if id == "fmt " {
+			if size < 16 {
+				return nil, fmt.Errorf("fmt chunk too small")
+			}
+			h.fmt = body[:size]
+			h.sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			h.blockAlign = binary.LittleEndian.Uint16(body[12:14])
+			h.bits = binary.LittleEndian.Uint16(body[14:16])
+		}
+	}
+}
+
+// wavSilence returns gap worth of silent sample frames in h's format.
+func wavSilence(h *wavHeader, gap time.Duration) []byte {
+	frames := int(gap.Seconds() * float64(h.sampleRate))
+	if frames <= 0 {
+		return nil
+	}
+	silence := make([]byte, frames*int(h.blockAlign))
+	if h.bits == 8 {
+		// 8-bit PCM is unsigned, centered on 128.
+		for i := range silence {
+			silence[i] = 0x80
+		}
+	}
+	return silence
+}
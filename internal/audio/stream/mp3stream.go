@@ -13,6 +13,9 @@ type StreamingMP3Decoder struct {
 	format   beep.Format
 }
 
+// DecodeMP3Streaming decodes r as MP3. The streamer can seek only if r is
+// an io.Seeker, such as one wrapped by NopSeekCloser; its Seek panics
+// otherwise.
 func DecodeMP3Streaming(r io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
 	if r == nil {
 		return nil, beep.Format{}, errors.New("reader cannot be nil")
@@ -23,3 +26,15 @@ func DecodeMP3Streaming(r io.ReadCloser) (beep.StreamSeekCloser, beep.Format, er
 	}
 	return streamer, format, nil
 }
+
+// NopSeekCloser returns r with a Close method that does nothing, keeping it
+// seekable where io.NopCloser would hide its Seek method.
+func NopSeekCloser(r io.ReadSeeker) io.ReadSeekCloser {
+	return nopSeekCloser{r}
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
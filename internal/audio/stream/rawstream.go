@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+)
+
+// RawDecoder decodes headerless PCM, μ-law or A-law audio as it is read.
+type RawDecoder struct {
+	r   io.Reader
+	f   codec.Format
+	buf []byte
+	err error
+}
+
+// DecodeRawStreaming decodes r as headerless audio in format f. Samples are
+// reported at 16-bit precision, and more than two channels are mixed down
+// to stereo.
+func DecodeRawStreaming(r io.Reader, f codec.Format) (*RawDecoder, beep.Format, error) {
+	format := beep.Format{
+		SampleRate:  beep.SampleRate(f.SampleRate),
+		NumChannels: min(f.NumChannels, 2),
+		Precision:   2,
+	}
+	return &RawDecoder{r: r, f: f, buf: make([]byte, f.FrameSize()*512)}, format, nil
+}
+
+func (d *RawDecoder) Stream(samples [][2]float64) (n int, ok bool) {
+	if d.err != nil {
+		return 0, false
+	}
+
+	bytesNeeded := len(samples) * d.f.FrameSize()
+	if len(d.buf) < bytesNeeded {
+		d.buf = make([]byte, bytesNeeded)
+	}
+
+	numRead, err := io.ReadFull(d.r, d.buf[:bytesNeeded])
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		d.err = err
+		return 0, false
+	}
+
+	n = d.f.DecodeFrames(samples, d.buf[:numRead])
+	if n == 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func (d *RawDecoder) Err() error {
+	return d.err
+}
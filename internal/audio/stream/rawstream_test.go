@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+)
+
+func TestDecodeRawStreaming_PCMStereo(t *testing.T) {
+	var data bytes.Buffer
+	for _, v := range []int16{16384, -16384, 0, 8192} {
+		binary.Write(&data, binary.LittleEndian, v)
+	}
+	data.WriteByte(0x7F) // a partial trailing frame is dropped
+
+	decoder, format, err := DecodeRawStreaming(&data, codec.Format{Encoding: codec.PCM16, SampleRate: 16000, NumChannels: 2})
+	if err != nil {
+		t.Fatalf("DecodeRawStreaming failed: %v", err)
+	}
+	if format.SampleRate != 16000 || format.NumChannels != 2 || format.Precision != 2 {
+		t.Errorf("unexpected format %+v", format)
+	}
+
+	samples := make([][2]float64, 8)
+	n, ok := decoder.Stream(samples)
+	if !ok || n != 2 {
+		t.Fatalf("Stream = %d, %v; want 2 frames", n, ok)
+	}
+	if samples[0] != [2]float64{0.5, -0.5} || samples[1] != [2]float64{0, 0.25} {
+		t.Errorf("unexpected samples %v", samples[:2])
+	}
+	if n, ok := decoder.Stream(samples); n != 0 || ok {
+		t.Errorf("expected the end of the stream, got %d, %v", n, ok)
+	}
+}
+
+func TestDecodeRawStreaming_MulawMono(t *testing.T) {
+	data := []byte{codec.MulawEncode(-8000), 0xFF}
+	decoder, _, err := DecodeRawStreaming(bytes.NewReader(data), codec.Format{Encoding: codec.Mulaw, SampleRate: 8000, NumChannels: 1})
+	if err != nil {
+		t.Fatalf("DecodeRawStreaming failed: %v", err)
+	}
+	samples := make([][2]float64, 4)
+	n, _ := decoder.Stream(samples)
+	if n != 2 {
+		t.Fatalf("got %d frames, want 2", n)
+	}
+	want := float64(codec.MulawDecode(data[0])) / 32768
+	if samples[0] != [2]float64{want, want} || samples[1] != [2]float64{0, 0} {
+		t.Errorf("unexpected samples %v", samples[:2])
+	}
+}
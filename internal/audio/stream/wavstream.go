@@ -1,28 +1,67 @@
 package stream
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/gopxl/beep/v2"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
 )
 
+// WAV fmt chunk audio format tags.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// subFormatSuffix is what follows the format tag in the sub-format GUID of
+// a WAVE_FORMAT_EXTENSIBLE fmt chunk: the GUID is the tag followed by
+// 0000-0010-8000-00AA00389B71.
+var subFormatSuffix = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}
+
 type wavHeader struct {
+	// AudioFormat is wavFormatPCM or wavFormatFloat; the sub-format of a
+	// WAVE_FORMAT_EXTENSIBLE header is resolved to one of them.
+	AudioFormat   uint16
 	SampleRate    uint32
 	NumChannels   uint16
 	BitsPerSample uint16
 	ByteRate      uint32
 	BlockAlign    uint16
+	// ChannelMask gives the speaker positions of the channels of a
+	// WAVE_FORMAT_EXTENSIBLE header, or is 0.
+	ChannelMask uint32
+	// DataSize is the size the data chunk header gives, which for a
+	// streaming response is a placeholder.
+	DataSize uint32
 }
 
+// StreamingDecoder decodes a WAV stream as it is read: 8, 16, 24 or 32-bit
+// integer PCM, or 32 or 64-bit IEEE float. Audio with more than two
+// channels is mixed down to stereo. When the reader is an io.Seeker, as for
+// a file in memory, the decoder knows its length and can seek.
 type StreamingDecoder struct {
-	r              io.Reader
-	format         beep.Format
-	bytesPerSample int
-	buf            []byte
-	err            error
+	r         io.Reader
+	format    beep.Format
+	frameSize int
+	width     int
+	channels  int
+	sample    func(b []byte) float64
+	mix       [][2]float64
+	buf       []byte
+	err       error
+
+	// seeker is r if it can seek, dataStart the offset of the first sample
+	// frame in it, and frames the number of frames, or -1 if r can't seek.
+	seeker    io.Seeker
+	dataStart int64
+	frames    int
+	pos       int
 }
 
 func DecodeStreaming(r io.Reader) (*StreamingDecoder, beep.Format, error) {
@@ -30,22 +69,100 @@ func DecodeStreaming(r io.Reader) (*StreamingDecoder, beep.Format, error) {
 	if err != nil {
 		return nil, beep.Format{}, err
 	}
+	if header.NumChannels == 0 {
+		return nil, beep.Format{}, fmt.Errorf("WAV header has no channels")
+	}
 
-	precision := int(header.BitsPerSample / 8)
+	precision := int(header.BitsPerSample+7) / 8
+	sample, err := sampleDecoder(header.AudioFormat, precision)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
 	format := beep.Format{
 		SampleRate:  beep.SampleRate(header.SampleRate),
-		NumChannels: int(header.NumChannels),
+		NumChannels: min(int(header.NumChannels), 2),
 		Precision:   precision,
 	}
 
-	bytesPerSample := int(header.NumChannels) * precision
+	frameSize := int(header.NumChannels) * precision
 
-	return &StreamingDecoder{
-		r:              r,
-		format:         format,
-		bytesPerSample: bytesPerSample,
-		buf:            make([]byte, bytesPerSample*512),
-	}, format, nil
+	d := &StreamingDecoder{
+		r:         r,
+		format:    format,
+		frameSize: frameSize,
+		width:     precision,
+		channels:  int(header.NumChannels),
+		sample:    sample,
+		mix:       codec.DownmixMatrix(int(header.NumChannels), header.ChannelMask),
+		buf:       make([]byte, frameSize*512),
+		frames:    -1,
+	}
+	if seeker, ok := r.(io.Seeker); ok {
+		d.findFrames(seeker, header.DataSize)
+	}
+	return d, format, nil
+}
+
+// findFrames counts the sample frames between the current offset of seeker
+// and the end of the data chunk, which ends at dataSize bytes or, if that
+// is a placeholder or runs past the end, at the end of the stream.
+func (d *StreamingDecoder) findFrames(seeker io.Seeker, dataSize uint32) {
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return
+	}
+	size := end - start
+	if dataSize != 0 && dataSize != 0xFFFFFFFF && int64(dataSize) < size {
+		size = int64(dataSize)
+	}
+	d.seeker = seeker
+	d.dataStart = start
+	d.frames = int(size) / d.frameSize
+}
+
+// sampleDecoder returns a function that decodes one little-endian sample
+// of width bytes to [-1, 1].
+func sampleDecoder(audioFormat uint16, width int) (func(b []byte) float64, error) {
+	if audioFormat == wavFormatFloat {
+		switch width {
+		case 4:
+			return func(b []byte) float64 {
+				return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			}, nil
+		case 8:
+			return func(b []byte) float64 {
+				return math.Float64frombits(binary.LittleEndian.Uint64(b))
+			}, nil
+		}
+		return nil, fmt.Errorf("unsupported float bit depth: %d (supported: 32, 64)", width*8)
+	}
+
+	switch width {
+	case 1:
+		return func(b []byte) float64 {
+			return float64(b[0])/128.0 - 1.0
+		}, nil
+	case 2:
+		return func(b []byte) float64 {
+			return float64(int16(binary.LittleEndian.Uint16(b))) / 32768.0
+		}, nil
+	case 3:
+		return func(b []byte) float64 {
+			return float64(int32(b[0])|int32(b[1])<<8|int32(int8(b[2]))<<16) / 8388608.0
+		}, nil
+	case 4:
+		return func(b []byte) float64 {
+			return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648.0
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported bit depth: %d (supported: 8, 16, 24, 32)", width*8)
 }
 
 func (d *StreamingDecoder) Stream(samples [][2]float64) (n int, ok bool) {
@@ -53,7 +170,13 @@ func (d *StreamingDecoder) Stream(samples [][2]float64) (n int, ok bool) {
 		return 0, false
 	}
 
-	bytesNeeded := len(samples) * d.bytesPerSample
+	if d.frames >= 0 {
+		samples = samples[:min(len(samples), d.frames-d.pos)]
+	}
+	bytesNeeded := len(samples) * d.frameSize
+	if bytesNeeded == 0 {
+		return 0, false
+	}
 	if len(d.buf) < bytesNeeded {
 		d.buf = make([]byte, bytesNeeded)
 	}
@@ -68,42 +191,26 @@ func (d *StreamingDecoder) Stream(samples [][2]float64) (n int, ok bool) {
 		return 0, false
 	}
 
-	numSamples := numRead / d.bytesPerSample
+	numSamples := numRead / d.frameSize
+	d.pos += numSamples
 
-	switch d.format.Precision {
-	case 1:
-		for i := 0; i < numSamples; i++ {
-			offset := i * d.bytesPerSample
-			for ch := 0; ch < d.format.NumChannels && ch < 2; ch++ {
-				val := float64(d.buf[offset+ch])/128.0 - 1.0
-				samples[i][ch] = val
-			}
-			if d.format.NumChannels == 1 {
-				samples[i][1] = samples[i][0]
-			}
-		}
-	case 2:
-		for i := 0; i < numSamples; i++ {
-			offset := i * d.bytesPerSample
-			for ch := 0; ch < d.format.NumChannels && ch < 2; ch++ {
-				val := int16(binary.LittleEndian.Uint16(d.buf[offset+ch*2:]))
-				samples[i][ch] = float64(val) / 32768.0
-			}
-			if d.format.NumChannels == 1 {
-				samples[i][1] = samples[i][0]
+	for i := 0; i < numSamples; i++ {
+		frame := d.buf[i*d.frameSize:]
+		if d.mix != nil {
+			var left, right float64
+			for ch, g := range d.mix {
+				v := d.sample(frame[ch*d.width:])
+				left += g[0] * v
+				right += g[1] * v
 			}
+			samples[i] = [2]float64{left, right}
+			continue
 		}
-	case 3:
-		for i := 0; i < numSamples; i++ {
-			offset := i * d.bytesPerSample
-			for ch := 0; ch < d.format.NumChannels && ch < 2; ch++ {
-				b := d.buf[offset+ch*3:]
-				val := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
-				samples[i][ch] = float64(val) / 8388608.0
-			}
-			if d.format.NumChannels == 1 {
-				samples[i][1] = samples[i][0]
-			}
+		samples[i][0] = d.sample(frame)
+		if d.channels == 1 {
+			samples[i][1] = samples[i][0]
+		} else {
+			samples[i][1] = d.sample(frame[d.width:])
 		}
 	}
 
@@ -114,6 +221,33 @@ func (d *StreamingDecoder) Err() error {
 	return d.err
 }
 
+// Len returns the number of sample frames, or -1 if the reader can't seek
+// and the length isn't known.
+func (d *StreamingDecoder) Len() int {
+	return d.frames
+}
+
+// Position returns the number of sample frames decoded so far.
+func (d *StreamingDecoder) Position() int {
+	return d.pos
+}
+
+// Seek moves to sample frame p. It fails if the reader can't seek.
+func (d *StreamingDecoder) Seek(p int) error {
+	if d.seeker == nil {
+		return fmt.Errorf("seek not supported")
+	}
+	if p < 0 || p > d.frames {
+		return fmt.Errorf("seek position %d out of range [0, %d]", p, d.frames)
+	}
+	if _, err := d.seeker.Seek(d.dataStart+int64(p)*int64(d.frameSize), io.SeekStart); err != nil {
+		return err
+	}
+	d.pos = p
+	d.err = nil
+	return nil
+}
+
 func readWavHeader(r io.Reader) (*wavHeader, error) {
 	var buf [12]byte
 	if _, err := io.ReadFull(r, buf[:]); err != nil {
@@ -148,9 +282,20 @@ func readWavHeader(r io.Reader) (*wavHeader, error) {
 				return nil, fmt.Errorf("failed to read fmt chunk: %w", err)
 			}
 
-			audioFormat := binary.LittleEndian.Uint16(fmtData[0:2])
-			if audioFormat != 1 {
-				return nil, fmt.Errorf("unsupported audio format: %d (only PCM supported)", audioFormat)
+			header.AudioFormat = binary.LittleEndian.Uint16(fmtData[0:2])
+			if header.AudioFormat == wavFormatExtensible {
+				if chunkSize < 40 {
+					return nil, fmt.Errorf("fmt chunk too small for WAVE_FORMAT_EXTENSIBLE")
+				}
+				header.ChannelMask = binary.LittleEndian.Uint32(fmtData[20:24])
+				guid := fmtData[24:40]
+				if !bytes.Equal(guid[2:], subFormatSuffix) {
+					return nil, fmt.Errorf("unsupported WAVE_FORMAT_EXTENSIBLE sub-format: %X", guid)
+				}
+				header.AudioFormat = binary.LittleEndian.Uint16(guid[0:2])
+			}
+			if header.AudioFormat != wavFormatPCM && header.AudioFormat != wavFormatFloat {
+				return nil, fmt.Errorf("unsupported audio format: %d (supported: PCM and IEEE float)", header.AudioFormat)
 			}
 
 			header.NumChannels = binary.LittleEndian.Uint16(fmtData[2:4])
@@ -160,6 +305,7 @@ func readWavHeader(r io.Reader) (*wavHeader, error) {
 			header.BitsPerSample = binary.LittleEndian.Uint16(fmtData[14:16])
 
 		case "data":
+			header.DataSize = chunkSize
 			return &header, nil
 
 		default:
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
@@ -209,3 +211,147 @@ func TestStreamingDecoder_ShortRead(t *testing.T) {
 		t.Error("DecodeStreaming with truncated WAV should return error")
 	}
 }
+
+// makeWAVFormat returns a WAV file with the given fmt chunk body and audio.
+func makeWAVFormat(fmtChunk, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+len(data)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// fmtChunk returns a 16-byte fmt chunk body, or a 40-byte
+// WAVE_FORMAT_EXTENSIBLE one if subFormat isn't 0.
+func fmtChunk(audioFormat uint16, channels, bits int, subFormat uint16, mask uint32) []byte {
+	var buf bytes.Buffer
+	blockAlign := channels * bits / 8
+	binary.Write(&buf, binary.LittleEndian, audioFormat)
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(48000))
+	binary.Write(&buf, binary.LittleEndian, uint32(48000*blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(bits))
+	if subFormat != 0 {
+		binary.Write(&buf, binary.LittleEndian, uint16(22))
+		binary.Write(&buf, binary.LittleEndian, uint16(bits))
+		binary.Write(&buf, binary.LittleEndian, mask)
+		binary.Write(&buf, binary.LittleEndian, subFormat)
+		buf.Write(subFormatSuffix)
+	}
+	return buf.Bytes()
+}
+
+func decodeAll(t *testing.T, wav []byte) ([][2]float64, int) {
+	t.Helper()
+	decoder, format, err := DecodeStreaming(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	samples := make([][2]float64, 16)
+	n, _ := decoder.Stream(samples)
+	return samples[:n], format.NumChannels
+}
+
+func TestStreamingDecoder_Float(t *testing.T) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []float32{0.5, -0.25, 1, 0})
+	samples, channels := decodeAll(t, makeWAVFormat(fmtChunk(3, 2, 32, 0, 0), data.Bytes()))
+	if channels != 2 || len(samples) != 2 {
+		t.Fatalf("got %d channels, %d frames", channels, len(samples))
+	}
+	if samples[0] != [2]float64{0.5, -0.25} || samples[1] != [2]float64{1, 0} {
+		t.Errorf("samples = %v", samples)
+	}
+
+	data.Reset()
+	binary.Write(&data, binary.LittleEndian, []float64{0.125, -0.5})
+	samples, _ = decodeAll(t, makeWAVFormat(fmtChunk(3, 1, 64, 0, 0), data.Bytes()))
+	if len(samples) != 2 || samples[0] != [2]float64{0.125, 0.125} || samples[1] != [2]float64{-0.5, -0.5} {
+		t.Errorf("64-bit float samples = %v", samples)
+	}
+}
+
+func TestStreamingDecoder_Int32(t *testing.T) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []int32{1 << 30, -1 << 31})
+	samples, _ := decodeAll(t, makeWAVFormat(fmtChunk(1, 1, 32, 0, 0), data.Bytes()))
+	if len(samples) != 2 || samples[0][0] != 0.5 || samples[1][0] != -1 {
+		t.Errorf("samples = %v", samples)
+	}
+}
+
+func TestStreamingDecoder_ExtensibleDownmix(t *testing.T) {
+	// 5.1: front left, front right, center, LFE, back left, back right.
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []float32{0.5, 0, 0, 1, 0, 0})
+	binary.Write(&data, binary.LittleEndian, []float32{0, 0, 0.5, 0, 0, 0})
+	samples, channels := decodeAll(t, makeWAVFormat(fmtChunk(0xFFFE, 6, 32, 3, 0x3F), data.Bytes()))
+	if channels != 2 || len(samples) != 2 {
+		t.Fatalf("got %d channels, %d frames", channels, len(samples))
+	}
+	// Each side sums 1 + √½ + √½, scaled down to 1.
+	scale := 1 / (1 + math.Sqrt2)
+	if math.Abs(samples[0][0]-0.5*scale) > 1e-6 || samples[0][1] != 0 {
+		t.Errorf("front left frame = %v, want [%v 0] with LFE dropped", samples[0], 0.5*scale)
+	}
+	center := 0.5 * math.Sqrt2 / 2 * scale
+	if math.Abs(samples[1][0]-center) > 1e-6 || math.Abs(samples[1][1]-center) > 1e-6 {
+		t.Errorf("center frame = %v, want %v on both sides", samples[1], center)
+	}
+}
+
+func TestReadWavHeader_Extensible(t *testing.T) {
+	header, err := readWavHeader(bytes.NewReader(makeWAVFormat(fmtChunk(0xFFFE, 2, 24, 1, 0x3), nil)))
+	if err != nil {
+		t.Fatalf("readWavHeader failed: %v", err)
+	}
+	if header.AudioFormat != wavFormatPCM || header.ChannelMask != 0x3 || header.BitsPerSample != 24 {
+		t.Errorf("header = %+v", header)
+	}
+
+	bad := fmtChunk(0xFFFE, 2, 16, 1, 0x3)
+	bad[len(bad)-1] ^= 0xFF
+	if _, err := readWavHeader(bytes.NewReader(makeWAVFormat(bad, nil))); err == nil || !strings.Contains(err.Error(), "sub-format") {
+		t.Errorf("expected sub-format error, got %v", err)
+	}
+	if _, _, err := DecodeStreaming(bytes.NewReader(makeWAVFormat(fmtChunk(3, 1, 16, 0, 0), nil))); err == nil || !strings.Contains(err.Error(), "float bit depth") {
+		t.Errorf("expected float bit depth error, got %v", err)
+	}
+}
+
+func TestStreamingDecoder_Seek(t *testing.T) {
+	// A LIST chunk after the audio is not decoded as samples.
+	wav := append(testhelpers.MakeValidWAV(100), []byte("LIST\x04\x00\x00\x00INFO")...)
+	decoder, _, err := DecodeStreaming(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	if decoder.Len() != 100 {
+		t.Fatalf("Len = %d, want 100", decoder.Len())
+	}
+
+	samples := make([][2]float64, 200)
+	if n, _ := decoder.Stream(samples); n != 100 || decoder.Position() != 100 {
+		t.Errorf("Stream = %d frames, Position = %d; want 100, 100", n, decoder.Position())
+	}
+	if n, ok := decoder.Stream(samples); n != 0 || ok {
+		t.Errorf("Stream at end = %d, %v; want 0, false", n, ok)
+	}
+
+	if err := decoder.Seek(90); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if n, _ := decoder.Stream(samples); n != 10 {
+		t.Errorf("Stream after Seek(90) = %d frames, want 10", n)
+	}
+	if err := decoder.Seek(-1); err == nil {
+		t.Error("Seek(-1) should fail")
+	}
+}
@@ -0,0 +1,206 @@
+// Package stretch changes the speed of audio without changing its pitch.
+package stretch
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// hopDuration is how much audio each overlap-add step produces. Frames
+	// are twice as long, 30 ms, which suits the pitch periods of speech.
+	hopDuration = 15 * time.Millisecond
+)
+
+// Stretcher plays source faster or slower without shifting its pitch, using
+// WSOLA (waveform similarity overlap-add): the output is built from Hann
+// windowed frames overlapping by half, each taken from near where the speed
+// says the source should be, at the offset where it best continues the
+// frame before it, so the joins don't break up the waveform.
+//
+// Each step outputs one hop: the second half of the previous frame, faded
+// out, over the first half of the next, faded in. The second half of the
+// previous frame starts at next, so at speed 1 the next frame starts there
+// too and the source passes through unchanged.
+type Stretcher struct {
+	source beep.Streamer
+	speed  float64
+	hop    int
+	// tolerance is how far from its nominal position a frame may start.
+	tolerance int
+	// fadeIn is the first half of the Hann window; the second half, which
+	// fades out, is 1-fadeIn.
+	fadeIn []float64
+
+	// buf holds the source from offset bufStart on.
+	buf      [][2]float64
+	bufStart int
+	eof      bool
+
+	// next is where the second half of the previous frame starts, and
+	// nominal where the next frame should start at this speed.
+	next    int
+	nominal float64
+
+	// out is the hop being played, which started at hopStart in source.
+	out      [][2]float64
+	outPos   int
+	hopStart int
+}
+
+// New returns a Stretcher playing source, audio at sample rate rate, at
+// speed times its normal speed.
+func New(source beep.Streamer, rate beep.SampleRate, speed float64) *Stretcher {
+	hop := max(1, rate.N(hopDuration))
+	s := &Stretcher{
+		source:    source,
+		speed:     speed,
+		hop:       hop,
+		tolerance: hop / 2,
+		fadeIn:    make([]float64, hop),
+	}
+	for i := range s.fadeIn {
+		s.fadeIn[i] = 0.5 - 0.5*math.Cos(math.Pi*float64(i)/float64(hop))
+	}
+	return s
+}
+
+// SetSpeed changes the speed, 1 being normal.
+func (s *Stretcher) SetSpeed(speed float64) {
+	s.speed = speed
+}
+
+// Speed returns the speed, 1 being normal.
+func (s *Stretcher) Speed() float64 {
+	return s.speed
+}
+
+// Position returns the position in source of the audio Stream returns next.
+func (s *Stretcher) Position() int {
+	if s.outPos >= len(s.out) {
+		return s.next
+	}
+	return s.hopStart + int(float64(s.outPos)*s.speed)
+}
+
+// Reset discards the buffered audio after source has moved to position p,
+// as when it seeks.
+func (s *Stretcher) Reset(p int) {
+	s.buf = s.buf[:0]
+	s.bufStart = p
+	s.eof = false
+	s.next = p
+	s.nominal = float64(p)
+	s.out = s.out[:0]
+	s.outPos = 0
+}
+
+func (s *Stretcher) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		if s.outPos >= len(s.out) && !s.step() {
+			break
+		}
+		c := copy(samples[n:], s.out[s.outPos:])
+		s.outPos += c
+		n += c
+	}
+	return n, n > 0
+}
+
+func (s *Stretcher) Err() error {
+	return s.source.Err()
+}
+
+// step computes the next hop into out, returning false once the source is
+// exhausted.
+func (s *Stretcher) step() bool {
+	s.hopStart = s.next
+	s.out = s.out[:0]
+	s.outPos = 0
+
+	if s.speed == 1 {
+		s.nominal = float64(s.next)
+	} else {
+		s.nominal += s.speed * float64(s.hop)
+	}
+	lo := max(s.bufStart, int(s.nominal)-s.tolerance)
+	hi := int(s.nominal) + s.tolerance
+	s.fill(max(hi, s.next) + 2*s.hop)
+
+	// Near the end of the source, or at speed 1, the source is played as
+	// it is.
+	end := s.bufStart + len(s.buf)
+	if s.speed == 1 || hi+s.hop > end || s.next+s.hop > end {
+		if s.next >= end {
+			return false
+		}
+		stop := min(end, s.next+s.hop)
+		s.out = append(s.out, s.buf[s.next-s.bufStart:stop-s.bufStart]...)
+		s.next = stop
+		s.trim()
+		return true
+	}
+
+	start := s.bestStart(lo, hi)
+	prev := s.buf[s.next-s.bufStart:]
+	frame := s.buf[start-s.bufStart:]
+	for i := 0; i < s.hop; i++ {
+		in, out := s.fadeIn[i], 1-s.fadeIn[i]
+		s.out = append(s.out, [2]float64{
+			prev[i][0]*out + frame[i][0]*in,
+			prev[i][1]*out + frame[i][1]*in,
+		})
+	}
+	s.next = start + s.hop
+	s.trim()
+	return true
+}
+
+// bestStart returns the offset between lo and hi at which a frame best
+// continues the previous one: where the audio is most like that at next,
+// by normalized cross-correlation of the channels' sum.
+func (s *Stretcher) bestStart(lo, hi int) int {
+	target := s.buf[s.next-s.bufStart : s.next-s.bufStart+s.hop]
+	best, bestScore := lo, math.Inf(-1)
+	for start := lo; start <= hi; start++ {
+		candidate := s.buf[start-s.bufStart : start-s.bufStart+s.hop]
+		var dot, energy float64
+		// Every other sample is enough to compare waveforms and halves
+		// the work.
+		for i := 0; i < s.hop; i += 2 {
+			a := target[i][0] + target[i][1]
+			b := candidate[i][0] + candidate[i][1]
+			dot += a * b
+			energy += b * b
+		}
+		score := dot / math.Sqrt(energy+1e-9)
+		if score > bestScore {
+			best, bestScore = start, score
+		}
+	}
+	return best
+}
+
+// fill reads source until buf reaches position p or source ends.
+func (s *Stretcher) fill(p int) {
+	for !s.eof && s.bufStart+len(s.buf) < p {
+		need := p - s.bufStart - len(s.buf)
+		chunk := make([][2]float64, max(need, 512))
+		n, ok := s.source.Stream(chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if !ok {
+			s.eof = true
+		}
+	}
+}
+
+// trim drops buffered audio no later frame can start in.
+func (s *Stretcher) trim() {
+	keep := min(s.next, int(s.nominal)-s.tolerance)
+	if drop := keep - s.bufStart; drop > 0 {
+		s.buf = s.buf[:copy(s.buf, s.buf[drop:])]
+		s.bufStart = keep
+	}
+}
@@ -0,0 +1,113 @@
+package stretch
+
+import (
+	"math"
+	"testing"
+)
+
+// sliceStreamer streams frames.
+type sliceStreamer struct {
+	frames [][2]float64
+	pos    int
+}
+
+func (s *sliceStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.pos >= len(s.frames) {
+		return 0, false
+	}
+	n := copy(samples, s.frames[s.pos:])
+	s.pos += n
+	return n, true
+}
+
+func (s *sliceStreamer) Err() error { return nil }
+
+func sine(freq float64, rate, n int) [][2]float64 {
+	frames := make([][2]float64, n)
+	for i := range frames {
+		v := 0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		frames[i] = [2]float64{v, v}
+	}
+	return frames
+}
+
+func readAll(s *Stretcher) [][2]float64 {
+	var out [][2]float64
+	buf := make([][2]float64, 700)
+	for {
+		n, ok := s.Stream(buf)
+		out = append(out, buf[:n]...)
+		if !ok {
+			return out
+		}
+	}
+}
+
+// crossingRate returns the rising zero crossings per second of the middle
+// half of frames, which for a sine is its frequency.
+func crossingRate(frames [][2]float64, rate int) float64 {
+	mid := frames[len(frames)/4 : len(frames)*3/4]
+	crossings := 0
+	for i := 1; i < len(mid); i++ {
+		if mid[i-1][0] < 0 && mid[i][0] >= 0 {
+			crossings++
+		}
+	}
+	return float64(crossings) / (float64(len(mid)) / float64(rate))
+}
+
+func TestStretcher_NormalSpeedPassesThrough(t *testing.T) {
+	in := sine(220, 24000, 24000)
+	out := readAll(New(&sliceStreamer{frames: in}, 24000, 1))
+	if len(out) != len(in) {
+		t.Fatalf("got %d frames, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("frame %d = %v, want %v", i, out[i], in[i])
+		}
+	}
+}
+
+func TestStretcher_KeepsPitch(t *testing.T) {
+	const rate = 24000
+	in := sine(220, rate, 2*rate)
+
+	tests := []struct {
+		speed float64
+	}{
+		{2}, {1.5}, {0.5},
+	}
+	for _, tt := range tests {
+		out := readAll(New(&sliceStreamer{frames: in}, rate, tt.speed))
+		want := float64(len(in)) / tt.speed
+		if got := float64(len(out)); math.Abs(got-want) > 0.02*want {
+			t.Errorf("speed %v: got %d frames, want about %.0f", tt.speed, len(out), want)
+		}
+		if f := crossingRate(out, rate); math.Abs(f-220) > 5 {
+			t.Errorf("speed %v: frequency %.1f Hz, want 220", tt.speed, f)
+		}
+	}
+}
+
+func TestStretcher_PositionAndReset(t *testing.T) {
+	const rate = 24000
+	source := &sliceStreamer{frames: sine(220, rate, rate)}
+	s := New(source, rate, 2)
+
+	buf := make([][2]float64, 3000)
+	s.Stream(buf)
+	if p := s.Position(); p < 5500 || p > 6500 {
+		t.Errorf("after 3000 frames at 2x: Position = %d, want about 6000", p)
+	}
+
+	source.pos = 12000
+	s.Reset(12000)
+	if p := s.Position(); p != 12000 {
+		t.Errorf("after Reset: Position = %d, want 12000", p)
+	}
+	s.SetSpeed(1)
+	if n, _ := s.Stream(buf[:100]); n != 100 || buf[0] != source.frames[12000] {
+		t.Errorf("after Reset: streamed %d frames starting %v, want the source from 12000", n, buf[0])
+	}
+}
@@ -3,6 +3,7 @@ package testhelpers
 import (
 	"bytes"
 	"encoding/binary"
+	"math"
 )
 
 func MakeValidWAV(sampleCount int) []byte {
@@ -31,6 +32,17 @@ func MakeValidWAV(sampleCount int) []byte {
 	return buf.Bytes()
 }
 
+// MakeToneWAV returns a WAV like MakeValidWAV holding a sine wave at freq
+// Hz with peak amplitude amp, from 0 to 1.
+func MakeToneWAV(sampleCount int, freq, amp float64) []byte {
+	data := MakeValidWAV(sampleCount)
+	for i := 0; i < sampleCount; i++ {
+		v := amp * 32767 * math.Sin(2*math.Pi*freq*float64(i)/24000)
+		binary.LittleEndian.PutUint16(data[44+2*i:], uint16(int16(math.Round(v))))
+	}
+	return data
+}
+
 func MakeMinimalWAV() []byte {
 	var buf bytes.Buffer
 
@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+// Row is a single synthesis job from a manifest. Pointer fields are optional
+// per-row model params; nil means "use the model default".
+//
+// JSONL manifests use one object per line with the json keys below. CSV
+// manifests use a header row with the same names as column titles.
+type Row struct {
+	Line int `json:"-"`
+
+	Text    string `json:"text"`
+	Speaker string `json:"speaker"`
+	ModelID string `json:"model"`
+	Lang    string `json:"lang"`
+	Format  string `json:"format"`
+	Output  string `json:"output"`
+
+	// Arcana/ArcanaV2 specific
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	MaxTokens         *int     `json:"max_tokens,omitempty"`
+
+	// Both model families
+	SamplingRate *int     `json:"sampling_rate,omitempty"`
+	SpeedAlpha   *float64 `json:"speed_alpha,omitempty"`
+
+	// Mist/MistV2 specific
+	PauseBetweenBrackets     *bool   `json:"pause_between_brackets,omitempty"`
+	PhonemizeBetweenBrackets *bool   `json:"phonemize_between_brackets,omitempty"`
+	InlineSpeedAlpha         *string `json:"inline_speed_alpha,omitempty"`
+	NoTextNormalization      *bool   `json:"no_text_normalization,omitempty"`
+	SaveOovs                 *bool   `json:"save_oovs,omitempty"`
+}
+
+// TTSOptions converts the row into request options. Format must already be
+// resolved to one of tts.Formats.
+func (r *Row) TTSOptions() *api.TTSOptions {
+	audioFormat, _ := tts.FormatContentType(r.Format, r.SamplingRate)
+	return &api.TTSOptions{
+		Speaker:     r.Speaker,
+		ModelID:     r.ModelID,
+		Lang:        r.Lang,
+		AudioFormat: audioFormat,
+
+		Temperature:              r.Temperature,
+		TopP:                     r.TopP,
+		RepetitionPenalty:        r.RepetitionPenalty,
+		MaxTokens:                r.MaxTokens,
+		SamplingRate:             r.SamplingRate,
+		SpeedAlpha:               r.SpeedAlpha,
+		PauseBetweenBrackets:     r.PauseBetweenBrackets,
+		PhonemizeBetweenBrackets: r.PhonemizeBetweenBrackets,
+		InlineSpeedAlpha:         r.InlineSpeedAlpha,
+		NoTextNormalization:      r.NoTextNormalization,
+		SaveOovs:                 r.SaveOovs,
+	}
+}
+
+// LoadManifest reads a manifest from path. Files ending in .csv are parsed as
+// CSV; everything else is parsed as JSONL.
+func LoadManifest(path string) ([]Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ParseCSV(f)
+	}
+	return ParseJSONL(f)
+}
+
+// ParseJSONL parses one JSON object per line. Blank lines and lines starting
+// with "#" are skipped.
+func ParseJSONL(r io.Reader) ([]Row, error) {
+	var rows []Row
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if line == 1 {
+			raw = bytes.TrimPrefix(raw, []byte("\ufeff"))
+		}
+		if len(raw) == 0 || raw[0] == '#' {
+			continue
+		}
+
+		var row Row
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ParseCSV parses a CSV manifest whose first record is a header naming the
+// columns. Empty cells leave the corresponding field unset.
+func ParseCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) > len(header) {
+			return nil, fmt.Errorf("line %d: %d fields but header has %d", line, len(record), len(header))
+		}
+
+		row := Row{Line: line}
+		for i, value := range record {
+			if value == "" {
+				continue
+			}
+			if err := row.setField(header[i], value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (r *Row) setField(name, value string) error {
+	switch name {
+	case "text":
+		r.Text = value
+	case "speaker":
+		r.Speaker = value
+	case "model":
+		r.ModelID = value
+	case "lang":
+		r.Lang = value
+	case "format":
+		r.Format = value
+	case "output":
+		r.Output = value
+	case "temperature":
+		return parseFloat(name, value, &r.Temperature)
+	case "top_p":
+		return parseFloat(name, value, &r.TopP)
+	case "repetition_penalty":
+		return parseFloat(name, value, &r.RepetitionPenalty)
+	case "max_tokens":
+		return parseInt(name, value, &r.MaxTokens)
+	case "sampling_rate":
+		return parseInt(name, value, &r.SamplingRate)
+	case "speed_alpha":
+		return parseFloat(name, value, &r.SpeedAlpha)
+	case "pause_between_brackets":
+		return parseBool(name, value, &r.PauseBetweenBrackets)
+	case "phonemize_between_brackets":
+		return parseBool(name, value, &r.PhonemizeBetweenBrackets)
+	case "inline_speed_alpha":
+		r.InlineSpeedAlpha = &value
+	case "no_text_normalization":
+		return parseBool(name, value, &r.NoTextNormalization)
+	case "save_oovs":
+		return parseBool(name, value, &r.SaveOovs)
+	default:
+		return fmt.Errorf("unknown column %q", name)
+	}
+	return nil
+}
+
+func parseFloat(name, value string, dst **float64) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", name, value)
+	}
+	*dst = &v
+	return nil
+}
+
+func parseInt(name, value string, dst **int) error {
+	v, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", name, value)
+	}
+	*dst = &v
+	return nil
+}
+
+func parseBool(name, value string, dst **bool) error {
+	v, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", name, value)
+	}
+	*dst = &v
+	return nil
+}
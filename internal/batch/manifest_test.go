@@ -0,0 +1,130 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONL(t *testing.T) {
+	input := `{"text": "hello", "speaker": "astra", "model": "arcana", "output": "a.wav", "temperature": 0.7}
+
+# comment
+{"text": "bye", "max_tokens": 800}
+`
+	rows, err := ParseJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseJSONL failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Line != 1 || rows[1].Line != 4 {
+		t.Errorf("unexpected line numbers: %d, %d", rows[0].Line, rows[1].Line)
+	}
+	if rows[0].Speaker != "astra" || rows[0].ModelID != "arcana" || rows[0].Output != "a.wav" {
+		t.Errorf("unexpected row: %+v", rows[0])
+	}
+	if rows[0].Temperature == nil || *rows[0].Temperature != 0.7 {
+		t.Errorf("expected temperature 0.7, got %v", rows[0].Temperature)
+	}
+	if rows[1].MaxTokens == nil || *rows[1].MaxTokens != 800 {
+		t.Errorf("expected max_tokens 800, got %v", rows[1].MaxTokens)
+	}
+	if rows[1].Temperature != nil {
+		t.Error("expected unset temperature to be nil")
+	}
+}
+
+func TestParseJSONL_UnknownField(t *testing.T) {
+	_, err := ParseJSONL(strings.NewReader(`{"text": "hi", "speeker": "astra"}`))
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("error should mention line number, got: %v", err)
+	}
+}
+
+func TestParseJSONL_BOM(t *testing.T) {
+	rows, err := ParseJSONL(strings.NewReader("\ufeff{\"text\": \"hi\"}\n"))
+	if err != nil {
+		t.Fatalf("ParseJSONL failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Text != "hi" {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "Text,speaker,model,output,speed_alpha,save_oovs\n" +
+		"\"Hello, world\",astra,arcana,a.wav,1.2,\n" +
+		"bye,celeste,mistv2,,,true\n"
+	rows, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Text != "Hello, world" {
+		t.Errorf("expected quoted text to be preserved, got %q", rows[0].Text)
+	}
+	if rows[0].Line != 2 || rows[1].Line != 3 {
+		t.Errorf("unexpected line numbers: %d, %d", rows[0].Line, rows[1].Line)
+	}
+	if rows[0].SpeedAlpha == nil || *rows[0].SpeedAlpha != 1.2 {
+		t.Errorf("expected speed_alpha 1.2, got %v", rows[0].SpeedAlpha)
+	}
+	if rows[0].SaveOovs != nil {
+		t.Error("expected empty save_oovs cell to be nil")
+	}
+	if rows[1].SaveOovs == nil || !*rows[1].SaveOovs {
+		t.Errorf("expected save_oovs true, got %v", rows[1].SaveOovs)
+	}
+	if rows[1].Output != "" {
+		t.Errorf("expected empty output, got %q", rows[1].Output)
+	}
+}
+
+func TestParseCSV_InvalidValue(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("text,max_tokens\nhi,lots\n"))
+	if err == nil {
+		t.Fatal("expected error for invalid max_tokens")
+	}
+	if !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseCSV_UnknownColumn(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("text,voice\nhi,astra\n"))
+	if err == nil {
+		t.Fatal("expected error for unknown column")
+	}
+}
+
+func TestLoadManifest_ByExtension(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "m.csv")
+	os.WriteFile(csvPath, []byte("text\nhello\n"), 0644)
+	jsonlPath := filepath.Join(dir, "m.jsonl")
+	os.WriteFile(jsonlPath, []byte(`{"text":"hello"}`+"\n"), 0644)
+
+	for _, path := range []string{csvPath, jsonlPath} {
+		rows, err := LoadManifest(path)
+		if err != nil {
+			t.Fatalf("LoadManifest(%s) failed: %v", path, err)
+		}
+		if len(rows) != 1 || rows[0].Text != "hello" {
+			t.Errorf("LoadManifest(%s) = %+v", path, rows)
+		}
+	}
+}
+
+func TestLoadManifest_Missing(t *testing.T) {
+	if _, err := LoadManifest(filepath.Join(t.TempDir(), "nope.jsonl")); err == nil {
+		t.Error("expected error for missing manifest")
+	}
+}
@@ -0,0 +1,289 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+// Defaults fill in fields a manifest row leaves empty.
+type Defaults struct {
+	Speaker string
+	ModelID string
+	Lang    string
+	Format  string
+}
+
+type Options struct {
+	// OutDir is prepended to relative output paths.
+	OutDir   string
+	Defaults Defaults
+	// Concurrency is the number of rows synthesized in parallel (minimum 1).
+	Concurrency int
+	// State, if set, is used to skip rows completed by a previous run and is
+	// updated as rows finish.
+	State *State
+	// OnResult, if set, is called after each row finishes. Calls are
+	// serialized but arrive in completion order, not manifest order.
+	OnResult func(RowResult)
+	// Edits are applied to every row's audio before it is saved.
+	Edits tts.Edits
+}
+
+// RowResult is the outcome of one row. Successful rows carry the same fields
+// as a single `rime tts --json` run.
+type RowResult struct {
+	Line int `json:"line"`
+	tts.Result
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (r RowResult) OK() bool {
+	return r.Error == ""
+}
+
+type Summary struct {
+	Total     int
+	Succeeded int
+	Skipped   int
+	Failed    int
+	// NotStarted counts rows that were never attempted because the run was
+	// cancelled. Their results carry a "not started" error but are not
+	// counted in Failed.
+	NotStarted int
+	// Results are in manifest order.
+	Results []RowResult
+}
+
+// Run synthesizes every row with a single client, using up to
+// opts.Concurrency workers. A failing row is recorded in the summary (and the
+// state file, if any) and does not stop the run. Cancelling ctx stops new rows
+// from starting and interrupts those in flight; interrupted rows are reported
+// as failed but left out of the state file.
+func Run(ctx context.Context, client *api.Client, rows []Row, opts Options) *Summary {
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Resolve everything up front so duplicate outputs are attributed to the
+	// first row that names them regardless of scheduling.
+	resolved := make([]Row, len(rows))
+	resolveErrs := make([]error, len(rows))
+	claimed := make(map[string]int)
+	for i, row := range rows {
+		resolved[i], resolveErrs[i] = Resolve(row, opts)
+		if resolveErrs[i] != nil || resolved[i].Output == "" {
+			continue
+		}
+		if owner, dup := claimed[resolved[i].Output]; dup {
+			resolveErrs[i] = fmt.Errorf("output %s is already used by line %d", resolved[i].Output, owner)
+			resolved[i].Output = ""
+			continue
+		}
+		claimed[resolved[i].Output] = resolved[i].Line
+	}
+
+	summary := &Summary{Total: len(rows), Results: make([]RowResult, len(rows))}
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := processRow(ctx, client, resolved[i], resolveErrs[i], opts)
+
+				mu.Lock()
+				summary.Results[i] = res
+				switch {
+				case !res.OK():
+					summary.Failed++
+				case res.Skipped:
+					summary.Skipped++
+				default:
+					summary.Succeeded++
+				}
+				if opts.OnResult != nil {
+					opts.OnResult(res)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for i := range rows {
+		if ctx.Err() != nil {
+			summary.NotStarted = len(rows) - i
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			summary.NotStarted = len(rows) - i
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := len(rows) - summary.NotStarted; i < len(rows); i++ {
+		summary.Results[i] = failedResult(resolved[i], fmt.Errorf("not started: %w", ctx.Err()))
+	}
+
+	return summary
+}
+
+func processRow(ctx context.Context, client *api.Client, row Row, resolveErr error, opts Options) RowResult {
+	state := opts.State
+	var res RowResult
+	if resolveErr != nil {
+		res = failedResult(row, resolveErr)
+	} else {
+		if state != nil {
+			if prev, ok := state.Completed(row, opts.Edits); ok {
+				return RowResult{Line: row.Line, Result: prev.Result, Skipped: true}
+			}
+		}
+		res = runRow(ctx, client, row, opts.Edits)
+		if !res.OK() && ctx.Err() != nil {
+			return res
+		}
+	}
+
+	if state != nil && row.Output != "" {
+		// A row whose outcome can't be recorded would be redone on resume,
+		// so the write error is always surfaced in the row's result.
+		if err := state.Record(row, opts.Edits, res); err != nil {
+			if res.OK() {
+				res.Error = err.Error()
+			} else {
+				res.Error = fmt.Sprintf("%s (%v)", res.Error, err)
+			}
+		}
+	}
+	return res
+}
+
+// runRow synthesizes an already resolved row, applies edits to it and saves
+// it.
+func runRow(ctx context.Context, client *api.Client, resolved Row, edits tts.Edits) RowResult {
+	ttsOpts := resolved.TTSOptions()
+	audio, err := tts.Synthesize(ctx, client, resolved.Text, ttsOpts)
+	if err != nil {
+		return failedResult(resolved, err)
+	}
+	report, err := edits.Apply(audio)
+	if err != nil {
+		return failedResult(resolved, err)
+	}
+
+	if dir := filepath.Dir(resolved.Output); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return failedResult(resolved, err)
+		}
+	}
+	audioData, err := tts.SaveAudio(resolved.Output, audio, resolved.Text, ttsOpts)
+	if err != nil {
+		return failedResult(resolved, err)
+	}
+
+	res := RowResult{
+		Line:   resolved.Line,
+		Result: tts.NewResult(audioData, audio.ContentType, audio.TTFB, resolved.Text, resolved.Output, ttsOpts),
+	}
+	res.Loudness = report
+	return res
+}
+
+func failedResult(row Row, err error) RowResult {
+	lang := row.Lang
+	if lang == "" {
+		lang = "eng"
+	}
+	return RowResult{
+		Line: row.Line,
+		Result: tts.Result{
+			OutputFile: row.Output,
+			Text:       row.Text,
+			Speaker:    row.Speaker,
+			ModelID:    row.ModelID,
+			Lang:       lang,
+		},
+		Error: err.Error(),
+	}
+}
+
+// Resolve applies defaults to a row, validates it and resolves its output
+// path against opts.OutDir.
+func Resolve(row Row, opts Options) (Row, error) {
+	row.Text = strings.TrimSpace(row.Text)
+	if row.Speaker == "" {
+		row.Speaker = opts.Defaults.Speaker
+	}
+	if row.ModelID == "" {
+		row.ModelID = opts.Defaults.ModelID
+	}
+	if row.Lang == "" {
+		row.Lang = opts.Defaults.Lang
+	}
+	if row.Lang == "" {
+		row.Lang = "eng"
+	}
+	if row.Format == "" {
+		row.Format = opts.Defaults.Format
+	}
+	if row.Format == "" {
+		row.Format = strings.TrimPrefix(api.GetAudioFormat(row.ModelID), "audio/")
+	}
+
+	if row.Output == "" {
+		ext := strings.TrimPrefix(opts.Edits.ContentType("audio/"+row.Format), "audio/")
+		row.Output = fmt.Sprintf("%04d.%s", row.Line, ext)
+	}
+	if opts.OutDir != "" && !filepath.IsAbs(row.Output) {
+		row.Output = filepath.Join(opts.OutDir, row.Output)
+	}
+
+	if row.Text == "" {
+		return row, fmt.Errorf("text is required")
+	}
+	if row.Speaker == "" {
+		return row, fmt.Errorf("speaker is required (set it in the manifest or with --speaker)")
+	}
+	if row.ModelID == "" {
+		return row, fmt.Errorf("model is required (set it in the manifest or with --model-id)")
+	}
+	if !api.IsValidModelID(row.ModelID) {
+		return row, fmt.Errorf("invalid model: %s (valid options: %s, %s, %s, %s)", row.ModelID, api.ModelIDArcana, api.ModelIDArcanaV2, api.ModelIDMistV2, api.ModelIDMist)
+	}
+	if !api.IsValidLang(row.Lang, row.ModelID) {
+		return row, fmt.Errorf("invalid language %q for model %s (valid: %s)", row.Lang, row.ModelID, strings.Join(api.ValidLangsForModel(row.ModelID), ", "))
+	}
+	if _, err := tts.FormatContentType(row.Format, nil); err != nil {
+		return row, err
+	}
+	_, raw := codec.ParseEncoding(row.Format)
+	if api.IsMistModel(row.ModelID) && row.Format != "mp3" && !raw {
+		return row, fmt.Errorf("%s and %s models require format mp3", api.ModelIDMist, api.ModelIDMistV2)
+	}
+	if err := opts.Edits.Check("audio/" + row.Format); err != nil {
+		return row, err
+	}
+	if err := opts.Edits.CheckOutput("audio/"+row.Format, row.Output); err != nil {
+		return row, err
+	}
+	if err := api.ValidateModelParams(row.TTSOptions()); err != nil {
+		return row, err
+	}
+	return row, nil
+}
@@ -0,0 +1,409 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+func TestResolve_Defaults(t *testing.T) {
+	row, err := Resolve(Row{Line: 3, Text: "  hi  "}, Options{
+		OutDir:   "out",
+		Defaults: Defaults{Speaker: "astra", ModelID: api.ModelIDArcana},
+	})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if row.Text != "hi" {
+		t.Errorf("expected trimmed text, got %q", row.Text)
+	}
+	if row.Lang != "eng" || row.Format != "wav" {
+		t.Errorf("unexpected lang/format: %s/%s", row.Lang, row.Format)
+	}
+	if row.Output != filepath.Join("out", "0003.wav") {
+		t.Errorf("unexpected output: %s", row.Output)
+	}
+}
+
+func TestResolve_MistDefaultsToMP3(t *testing.T) {
+	row, err := Resolve(Row{Line: 1, Text: "hi", Speaker: "astra", ModelID: api.ModelIDMistV2}, Options{})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if row.Format != "mp3" {
+		t.Errorf("expected mp3 for mistv2, got %s", row.Format)
+	}
+}
+
+func TestResolve_TelephonyFormat(t *testing.T) {
+	row, err := Resolve(Row{Line: 1, Text: "hi", Speaker: "astra", ModelID: api.ModelIDMistV2, Format: "mulaw"}, Options{})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if row.Output != "0001.mulaw" {
+		t.Errorf("unexpected output %s", row.Output)
+	}
+	if got := row.TTSOptions().AudioFormat; got != "audio/x-mulaw; channels=1; rate=8000" {
+		t.Errorf("unexpected audio format %q", got)
+	}
+}
+
+func TestResolve_TrimmedMP3IsWAV(t *testing.T) {
+	opts := Options{Edits: tts.Edits{PadStart: time.Second}}
+	row, err := Resolve(Row{Line: 2, Text: "hi", Speaker: "astra", ModelID: api.ModelIDMistV2}, opts)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if row.Output != "0002.wav" {
+		t.Errorf("unexpected output %s", row.Output)
+	}
+
+	_, err = Resolve(Row{Line: 2, Text: "hi", Speaker: "astra", ModelID: api.ModelIDMistV2, Output: "hi.mp3"}, opts)
+	if err == nil || !strings.Contains(err.Error(), "use a .wav output file") {
+		t.Errorf("expected an error for an .mp3 output, got %v", err)
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	temp := 0.5
+	tests := []struct {
+		name string
+		row  Row
+		want string
+	}{
+		{"empty text", Row{Text: " ", Speaker: "astra", ModelID: "arcana"}, "text is required"},
+		{"missing speaker", Row{Text: "hi", ModelID: "arcana"}, "speaker is required"},
+		{"missing model", Row{Text: "hi", Speaker: "astra"}, "model is required"},
+		{"invalid model", Row{Text: "hi", Speaker: "astra", ModelID: "nope"}, "invalid model"},
+		{"invalid lang", Row{Text: "hi", Speaker: "astra", ModelID: "mistv2", Lang: "jpn"}, "invalid language"},
+		{"mist wav", Row{Text: "hi", Speaker: "astra", ModelID: "mist", Format: "wav"}, "require format mp3"},
+		{"bad param", Row{Text: "hi", Speaker: "astra", ModelID: "mistv2", Temperature: &temp}, "--temperature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(tt.row, Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Resolve() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_ReusesClientAndRecordsFailures(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(2400)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.WriteHeader(http.StatusOK)
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+	dir := t.TempDir()
+	rows := []Row{
+		{Line: 1, Text: "hello", Output: "a.wav"},
+		{Line: 2, Text: ""},
+		{Line: 3, Text: "nested", Output: "sub/b.wav"},
+	}
+
+	var seen []int
+	summary := Run(context.Background(), client, rows, Options{
+		OutDir:   dir,
+		Defaults: Defaults{Speaker: "astra", ModelID: api.ModelIDArcana},
+		OnResult: func(res RowResult) { seen = append(seen, res.Line) },
+	})
+
+	if summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+		t.Errorf("OnResult called out of order: %v", seen)
+	}
+	if summary.Results[1].OK() || !strings.Contains(summary.Results[1].Error, "text is required") {
+		t.Errorf("expected row 2 to fail, got %+v", summary.Results[1])
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "b.wav"))
+	if err != nil {
+		t.Fatalf("expected nested output to be written: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("RIFF")) {
+		t.Error("expected WAV output")
+	}
+	parsed, ok := metadata.GetParsedCommentFromFile(data)
+	if !ok || parsed.Speaker != "astra" || parsed.Text != "nested" {
+		t.Errorf("expected embedded metadata, got %+v", parsed)
+	}
+
+	res := summary.Results[0]
+	if res.SizeBytes == 0 || res.Speaker != "astra" || res.ModelID != api.ModelIDArcana || res.OutputFile != filepath.Join(dir, "a.wav") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestRun_APIErrorRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+	summary := Run(context.Background(), client, []Row{{Line: 1, Text: "hi", Speaker: "astra", ModelID: "arcana"}}, Options{OutDir: t.TempDir()})
+	if summary.Failed != 1 {
+		t.Fatalf("expected 1 failure, got %+v", summary)
+	}
+	if !strings.Contains(summary.Results[0].Error, "500") {
+		t.Errorf("expected API error in result, got %q", summary.Results[0].Error)
+	}
+}
+
+func TestRun_ConcurrencyLimit(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(240)
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+	var rows []Row
+	for i := 1; i <= 8; i++ {
+		rows = append(rows, Row{Line: i, Text: "hi"})
+	}
+	summary := Run(context.Background(), client, rows, Options{
+		OutDir:      t.TempDir(),
+		Concurrency: 3,
+		Defaults:    Defaults{Speaker: "astra", ModelID: api.ModelIDArcana},
+	})
+
+	if summary.Succeeded != 8 {
+		t.Fatalf("expected 8 successes, got %+v", summary)
+	}
+	if m := atomic.LoadInt32(&maxInFlight); m > 3 || m < 2 {
+		t.Errorf("expected between 2 and 3 concurrent requests, got %d", m)
+	}
+	for i, res := range summary.Results {
+		if res.Line != i+1 {
+			t.Errorf("results not in manifest order: index %d has line %d", i, res.Line)
+		}
+	}
+}
+
+func TestRun_DuplicateOutput(t *testing.T) {
+	rows := []Row{
+		{Line: 1, Text: "a", Speaker: "astra", ModelID: "arcana", Output: "same.wav"},
+		{Line: 2, Text: "b", Speaker: "astra", ModelID: "arcana", Output: "same.wav"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(testhelpers.MakeValidWAV(240))
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+
+	summary := Run(context.Background(), client, rows, Options{OutDir: t.TempDir(), Concurrency: 2})
+	if !summary.Results[0].OK() {
+		t.Errorf("expected first row to succeed, got %q", summary.Results[0].Error)
+	}
+	if summary.Results[1].OK() || !strings.Contains(summary.Results[1].Error, "already used by line 1") {
+		t.Errorf("expected duplicate error, got %+v", summary.Results[1])
+	}
+}
+
+func TestRun_ResumeSkipsCompletedRows(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(240)
+	var requests int32
+	fail := int32(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, _ := io.ReadAll(r.Body)
+		if atomic.LoadInt32(&fail) == 1 && strings.Contains(string(body), "second") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavData)
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	rows := []Row{
+		{Line: 1, Text: "first", Speaker: "astra", ModelID: "arcana"},
+		{Line: 2, Text: "second", Speaker: "astra", ModelID: "arcana"},
+	}
+
+	state, _ := LoadState(statePath)
+	summary := Run(context.Background(), client, rows, Options{OutDir: dir, State: state})
+	if summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Fatalf("unexpected first run summary: %+v", summary)
+	}
+	state, _ = LoadState(statePath)
+	if rs := state.Rows[filepath.Join(dir, "0002.wav")]; rs.Status != StatusFailed || rs.Error == "" {
+		t.Errorf("expected failure recorded in state, got %+v", rs)
+	}
+
+	atomic.StoreInt32(&fail, 0)
+	atomic.StoreInt32(&requests, 0)
+	summary = Run(context.Background(), client, rows, Options{OutDir: dir, State: state})
+	if summary.Skipped != 1 || summary.Succeeded != 1 || summary.Failed != 0 {
+		t.Fatalf("unexpected resume summary: %+v", summary)
+	}
+	if !summary.Results[0].Skipped || summary.Results[0].SizeBytes == 0 {
+		t.Errorf("expected first row skipped with stored result, got %+v", summary.Results[0])
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected only the failed row to be retried, got %d requests", n)
+	}
+}
+
+func TestRun_ResumeRedoesRowsWhenEditsChange(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(testhelpers.MakeValidWAV(2400))
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	rows := []Row{{Line: 1, Text: "first", Speaker: "astra", ModelID: "arcana"}}
+	run := func(edits tts.Edits) *Summary {
+		state, err := LoadState(statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Run(context.Background(), client, rows, Options{OutDir: dir, State: state, Edits: edits})
+	}
+
+	padded := tts.Edits{PadEnd: 50 * time.Millisecond}
+	if summary := run(padded); summary.Succeeded != 1 {
+		t.Fatalf("unexpected first run summary: %+v", summary)
+	}
+	if summary := run(padded); summary.Skipped != 1 || atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("rerun with the same edits: %+v after %d requests, want the row skipped", summary, requests)
+	}
+	if summary := run(tts.Edits{PadEnd: 100 * time.Millisecond}); summary.Skipped != 0 || summary.Succeeded != 1 || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("rerun with a longer --pad-end: %+v after %d requests, want the row regenerated", summary, requests)
+	}
+}
+
+func TestRun_CancelStopsRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+
+	dir := t.TempDir()
+	state, _ := LoadState(filepath.Join(dir, "state.json"))
+	rows := []Row{
+		{Line: 1, Text: "one", Speaker: "astra", ModelID: "arcana"},
+		{Line: 2, Text: "two", Speaker: "astra", ModelID: "arcana"},
+		{Line: 3, Text: "three", Speaker: "astra", ModelID: "arcana"},
+	}
+	summary := Run(ctx, client, rows, Options{OutDir: dir, State: state})
+
+	if summary.Failed != 1 || summary.NotStarted != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(summary.Results[0].Error, "interrupted") {
+		t.Errorf("expected interrupted error, got %q", summary.Results[0].Error)
+	}
+	for _, res := range summary.Results[1:] {
+		if res.OK() || !strings.Contains(res.Error, "not started") {
+			t.Errorf("line %d: expected not started error, got %+v", res.Line, res)
+		}
+	}
+	if len(state.Rows) != 0 {
+		t.Errorf("interrupted rows should not be recorded in state, got %+v", state.Rows)
+	}
+}
+
+func TestRun_StateWriteErrorReportedForFailedRow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+
+	stateDir := filepath.Join(t.TempDir(), "gone")
+	if err := os.Mkdir(stateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := LoadState(filepath.Join(stateDir, "state.json"))
+	os.Remove(stateDir)
+
+	summary := Run(context.Background(), client, []Row{{Line: 1, Text: "hi", Speaker: "astra", ModelID: "arcana"}}, Options{OutDir: t.TempDir(), State: state})
+	errMsg := summary.Results[0].Error
+	if !strings.Contains(errMsg, "500") || !strings.Contains(errMsg, "failed to write state file") {
+		t.Errorf("expected both the API and state errors, got %q", errMsg)
+	}
+}
+
+func TestRun_Normalize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(testhelpers.MakeToneWAV(24000, 440, 0.05))
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL, Version: "test"})
+	target := -20.0
+	summary := Run(context.Background(), client, []Row{
+		{Line: 1, Text: "hi", Output: "a.wav"},
+		{Line: 2, Text: "hi", Output: "b.mp3", Format: "mp3"},
+	}, Options{
+		OutDir:   t.TempDir(),
+		Defaults: Defaults{Speaker: "astra", ModelID: api.ModelIDArcana},
+		Edits:    tts.Edits{Normalize: &target},
+	})
+
+	res := summary.Results[0]
+	if !res.OK() || res.Loudness == nil {
+		t.Fatalf("expected a normalized row, got %+v", res)
+	}
+	if got := float64(res.Loudness.Output.Integrated); got < -20.2 || got > -19.8 {
+		t.Errorf("output loudness = %.2f LUFS, want -20", got)
+	}
+	if res := summary.Results[1]; res.OK() || !strings.Contains(res.Error, "MP3 can't be re-encoded") {
+		t.Errorf("expected the MP3 row to be rejected, got %+v", res)
+	}
+}
@@ -0,0 +1,160 @@
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// RowState records the last outcome for one output path.
+type RowState struct {
+	Line int `json:"line"`
+	// RequestHash identifies the resolved row contents, so editing a row's
+	// text or params invalidates its previous output.
+	RequestHash string `json:"request_hash"`
+	// ContentHash is the SHA-256 of the file written to the output path.
+	ContentHash string     `json:"content_hash,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Result      tts.Result `json:"result"`
+}
+
+// State is a batch progress file. It is rewritten after every row so a run
+// interrupted at any point can be resumed.
+type State struct {
+	path string
+	mu   sync.Mutex
+	Rows map[string]RowState `json:"rows"`
+}
+
+// LoadState reads the state file at path, returning an empty state if it does
+// not exist yet.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Rows: make(map[string]RowState)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.Rows == nil {
+		s.Rows = make(map[string]RowState)
+	}
+	return s, nil
+}
+
+// Completed returns the recorded state for row if its output was produced
+// from identical request contents and edits and still exists with a
+// matching hash.
+func (s *State) Completed(row Row, edits tts.Edits) (RowState, bool) {
+	s.mu.Lock()
+	prev, ok := s.Rows[row.Output]
+	s.mu.Unlock()
+	if !ok || prev.Status != StatusOK || prev.RequestHash != requestHash(row, edits) {
+		return RowState{}, false
+	}
+	hash, err := fileHash(row.Output)
+	if err != nil || hash != prev.ContentHash {
+		return RowState{}, false
+	}
+	return prev, true
+}
+
+// Record stores the outcome for row, made with edits, and persists the
+// state file.
+func (s *State) Record(row Row, edits tts.Edits, res RowResult) error {
+	rs := RowState{
+		Line:        row.Line,
+		RequestHash: requestHash(row, edits),
+		Result:      res.Result,
+	}
+	if res.OK() {
+		rs.Status = StatusOK
+		hash, err := fileHash(row.Output)
+		if err != nil {
+			return err
+		}
+		rs.ContentHash = hash
+	} else {
+		rs.Status = StatusFailed
+		rs.Error = res.Error
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Rows[row.Output] = rs
+	return s.save()
+}
+
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".rime-batch-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// requestHash identifies what produced a row's output: the row, and the
+// edits made to its audio along with the content type they save it as.
+// Unedited rows hash as the row alone, as state files from before edits
+// were recorded do.
+func requestHash(row Row, edits tts.Edits) string {
+	var data []byte
+	if edits.IsZero() {
+		data, _ = json.Marshal(row)
+	} else {
+		data, _ = json.Marshal(struct {
+			Row         Row
+			Edits       tts.Edits
+			ContentType string
+		}{row, edits, edits.ContentType("audio/" + row.Format)})
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,84 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/tts"
+)
+
+func TestLoadState_Missing(t *testing.T) {
+	s, err := LoadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if len(s.Rows) != 0 {
+		t.Errorf("expected empty state, got %d rows", len(s.Rows))
+	}
+}
+
+func TestLoadState_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	if _, err := LoadState(path); err == nil {
+		t.Error("expected error for corrupt state file")
+	}
+}
+
+func TestState_RecordAndCompleted(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	output := filepath.Join(dir, "a.wav")
+	os.WriteFile(output, []byte("audio"), 0644)
+
+	row := Row{Line: 1, Text: "hi", Speaker: "astra", ModelID: "arcana", Lang: "eng", Format: "wav", Output: output}
+	s, _ := LoadState(statePath)
+	if err := s.Record(row, tts.Edits{}, RowResult{Line: 1, Result: tts.Result{SizeBytes: 5}}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	reloaded, err := LoadState(statePath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	prev, ok := reloaded.Completed(row, tts.Edits{})
+	if !ok {
+		t.Fatal("expected row to be completed")
+	}
+	if prev.Result.SizeBytes != 5 {
+		t.Errorf("expected stored result, got %+v", prev.Result)
+	}
+
+	changed := row
+	changed.Text = "hello"
+	if _, ok := reloaded.Completed(changed, tts.Edits{}); ok {
+		t.Error("expected edited row not to be completed")
+	}
+
+	os.WriteFile(output, []byte("other"), 0644)
+	if _, ok := reloaded.Completed(row, tts.Edits{}); ok {
+		t.Error("expected modified output not to be completed")
+	}
+
+	os.Remove(output)
+	if _, ok := reloaded.Completed(row, tts.Edits{}); ok {
+		t.Error("expected missing output not to be completed")
+	}
+}
+
+func TestState_RecordFailure(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := LoadState(filepath.Join(dir, "state.json"))
+	row := Row{Line: 2, Text: "hi", Output: filepath.Join(dir, "b.wav")}
+	if err := s.Record(row, tts.Edits{}, RowResult{Line: 2, Error: "boom"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	rs := s.Rows[row.Output]
+	if rs.Status != StatusFailed || rs.Error != "boom" {
+		t.Errorf("unexpected state: %+v", rs)
+	}
+	if _, ok := s.Completed(row, tts.Edits{}); ok {
+		t.Error("failed row should not be completed")
+	}
+}
@@ -0,0 +1,344 @@
+// Package cache keeps complete TTS responses on disk so that repeating an
+// identical request can be answered without calling the API.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/config"
+)
+
+const (
+	// EnvCache enables the cache when set to a true value such as 1.
+	EnvCache = "RIME_CACHE"
+	// EnvMaxSize overrides the cache size limit, e.g. 200MB.
+	EnvMaxSize = "RIME_CACHE_MAX_SIZE"
+	// DefaultMaxSize is the size limit used when EnvMaxSize is unset.
+	DefaultMaxSize int64 = 500 << 20
+
+	dirName  = "cache"
+	audioExt = ".audio"
+	metaExt  = ".json"
+)
+
+// Entry describes one cached response.
+type Entry struct {
+	Key         string    `json:"key"`
+	ContentType string    `json:"content_type,omitempty"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Speaker     string    `json:"speaker"`
+	ModelID     string    `json:"model_id"`
+	Lang        string    `json:"lang,omitempty"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsed    time.Time `json:"last_used"`
+	Hits        int       `json:"hits"`
+}
+
+// Stats summarizes the cache contents.
+type Stats struct {
+	Dir          string `json:"dir"`
+	Entries      int    `json:"entries"`
+	SizeBytes    int64  `json:"size_bytes"`
+	MaxSizeBytes int64  `json:"max_size_bytes"`
+	Hits         int    `json:"hits"`
+}
+
+// Cache is a directory of responses, each stored as <key>.audio with its
+// Entry in <key>.json. When a Put takes the total size over the limit, the
+// least recently used entries are removed.
+type Cache struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+// Dir returns the default cache location, ~/.rime/cache.
+func Dir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Enabled reports whether EnvCache turns the cache on.
+func Enabled() bool {
+	on, _ := strconv.ParseBool(os.Getenv(EnvCache))
+	return on
+}
+
+// MaxSizeFromEnv returns the size limit from EnvMaxSize, or DefaultMaxSize.
+func MaxSizeFromEnv() (int64, error) {
+	s := os.Getenv(EnvMaxSize)
+	if s == "" {
+		return DefaultMaxSize, nil
+	}
+	n, err := ParseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", EnvMaxSize, err)
+	}
+	return n, nil
+}
+
+// ParseSize parses a byte count with an optional KB, MB or GB suffix
+// (powers of 1024), e.g. "500MB".
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("size must be a positive number with an optional KB, MB or GB suffix")
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// New returns a cache in dir holding at most maxSize bytes of audio. The
+// directory is created on the first Put.
+func New(dir string, maxSize int64) *Cache {
+	return &Cache{dir: dir, maxSize: maxSize}
+}
+
+// Open returns the default cache with the size limit from the environment.
+func Open() (*Cache, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := MaxSizeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return New(dir, maxSize), nil
+}
+
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.dir, key+ext)
+}
+
+// Get returns the cached response for key and marks it as recently used.
+// Unreadable or truncated entries are treated as misses.
+func (c *Cache) Get(key string) ([]byte, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.readEntry(key)
+	if err != nil {
+		return nil, "", false
+	}
+	data, err := os.ReadFile(c.path(key, audioExt))
+	if err != nil || int64(len(data)) != e.SizeBytes {
+		c.remove(key)
+		return nil, "", false
+	}
+
+	e.LastUsed = time.Now().UTC()
+	e.Hits++
+	c.writeEntry(e)
+	return data, e.ContentType, true
+}
+
+// Put stores a complete response for req and evicts old entries if the
+// cache is now over its size limit. Responses larger than the limit are not
+// stored.
+func (c *Cache) Put(key string, req *api.TTSRequest, contentType string, data []byte) error {
+	if int64(len(data)) > c.maxSize {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(c.path(key, audioExt), data); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	e := &Entry{
+		Key:         key,
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Speaker:     req.Speaker,
+		ModelID:     req.ModelID,
+		Lang:        req.Lang,
+		Text:        req.Text,
+		CreatedAt:   now,
+		LastUsed:    now,
+	}
+	if err := c.writeEntry(e); err != nil {
+		c.remove(key)
+		return err
+	}
+	return c.evict()
+}
+
+// List returns the cached entries, most recently used first.
+func (c *Cache) List() ([]Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Stats returns the number and total size of cached entries.
+func (c *Cache) Stats() (Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Stats{Dir: c.dir, MaxSizeBytes: c.maxSize}
+	entries, err := c.entries()
+	if err != nil {
+		return s, err
+	}
+	for _, e := range entries {
+		s.Entries++
+		s.SizeBytes += e.SizeBytes
+		s.Hits += e.Hits
+	}
+	return s, nil
+}
+
+// Clear removes every entry and returns how many there were and the bytes
+// freed.
+func (c *Cache) Clear() (int, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	var freed int64
+	for _, e := range entries {
+		freed += e.SizeBytes
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		return 0, 0, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return len(entries), freed, nil
+}
+
+// evict removes least recently used entries until the cache fits maxSize.
+func (c *Cache) evict() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.SizeBytes
+	}
+	if total <= c.maxSize {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		c.remove(e.Key)
+		total -= e.SizeBytes
+	}
+	return nil
+}
+
+func (c *Cache) entries() ([]Entry, error) {
+	files, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	var entries []Entry
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, metaExt) {
+			continue
+		}
+		e, err := c.readEntry(strings.TrimSuffix(name, metaExt))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+func (c *Cache) readEntry(key string) (*Entry, error) {
+	data, err := os.ReadFile(c.path(key, metaExt))
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (c *Cache) writeEntry(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path(e.Key, metaExt), data)
+}
+
+func (c *Cache) remove(key string) {
+	os.Remove(c.path(key, metaExt))
+	os.Remove(c.path(key, audioExt))
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// into place, so other processes never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
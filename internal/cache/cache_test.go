@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+func testRequest(text string) *api.TTSRequest {
+	return &api.TTSRequest{Text: text, Speaker: "astra", ModelID: "arcana", Lang: "eng"}
+}
+
+func TestCache_PutGet(t *testing.T) {
+	c := New(t.TempDir(), 1<<20)
+
+	if _, _, ok := c.Get("missing"); ok {
+		t.Fatal("expected a miss for an unknown key")
+	}
+
+	data := []byte("audio-bytes")
+	if err := c.Put("k1", testRequest("hello"), "audio/wav", data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	got, contentType, ok := c.Get("k1")
+	if !ok {
+		t.Fatal("expected a hit after Put")
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %q, want %q", got, data)
+	}
+	if contentType != "audio/wav" {
+		t.Errorf("content type = %q, want audio/wav", contentType)
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hits != 1 || entries[0].Text != "hello" || entries[0].Speaker != "astra" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(t.TempDir(), 25)
+
+	for _, key := range []string{"a", "b"} {
+		if err := c.Put(key, testRequest(key), "audio/wav", bytes.Repeat([]byte{1}, 10)); err != nil {
+			t.Fatalf("Put %s failed: %v", key, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Using "a" makes "b" the least recently used.
+	if _, _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a hit for a")
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Put("c", testRequest("c"), "audio/wav", bytes.Repeat([]byte{1}, 10)); err != nil {
+		t.Fatalf("Put c failed: %v", err)
+	}
+
+	if _, _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 2 || stats.SizeBytes != 20 {
+		t.Errorf("stats = %+v, want 2 entries of 20 bytes", stats)
+	}
+}
+
+func TestCache_SkipsOversizedResponse(t *testing.T) {
+	c := New(t.TempDir(), 4)
+	if err := c.Put("big", testRequest("big"), "audio/wav", []byte("too large")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, _, ok := c.Get("big"); ok {
+		t.Error("expected a response larger than the cache not to be stored")
+	}
+}
+
+func TestCache_TruncatedEntryIsMiss(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 1<<20)
+	if err := c.Put("k", testRequest("hello"), "audio/wav", []byte("0123456789")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "k"+audioExt), []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := c.Get("k"); ok {
+		t.Fatal("expected a truncated entry to be a miss")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "k"+metaExt)); !os.IsNotExist(err) {
+		t.Error("expected the truncated entry to be removed")
+	}
+}
+
+func TestCache_Clear(t *testing.T) {
+	c := New(t.TempDir(), 1<<20)
+	c.Put("a", testRequest("a"), "audio/wav", []byte("123"))
+	c.Put("b", testRequest("b"), "audio/wav", []byte("4567"))
+
+	n, freed, err := c.Clear()
+	if err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if n != 2 || freed != 7 {
+		t.Errorf("Clear = (%d, %d), want (2, 7)", n, freed)
+	}
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected an empty cache, got %d entries", len(entries))
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"10KB", 10 << 10},
+		{"500MB", 500 << 20},
+		{"1.5gb", 3 << 29},
+		{"200 MB", 200 << 20},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "-5MB", "0"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv(EnvCache, "")
+	if Enabled() {
+		t.Error("expected the cache to be off by default")
+	}
+	t.Setenv(EnvCache, "1")
+	if !Enabled() {
+		t.Errorf("expected %s=1 to enable the cache", EnvCache)
+	}
+}
@@ -96,10 +96,13 @@ func (e *Environment) GetAPIKey() string {
 }
 
 type Config struct {
-	APIKey           string                 `toml:"api_key"`
-	APIURL           string                 `toml:"api_url"`
-	AuthHeaderPrefix *string                `toml:"auth_header_prefix,omitempty"`
-	Env              map[string]Environment `toml:"env"`
+	APIKey           string  `toml:"api_key"`
+	APIURL           string  `toml:"api_url"`
+	AuthHeaderPrefix *string `toml:"auth_header_prefix,omitempty"`
+	// OutputDevice is the audio output played through when --device isn't
+	// given, as named by 'rime devices'.
+	OutputDevice string                 `toml:"output_device,omitempty"`
+	Env          map[string]Environment `toml:"env"`
 }
 
 func LoadConfig() (*Config, error) {
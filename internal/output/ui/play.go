@@ -4,6 +4,7 @@ package ui
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -12,11 +13,12 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/speaker"
 
 	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
 	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
 	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
 	"github.com/rimelabs/rime-cli/internal/audio/stream"
 	"github.com/rimelabs/rime-cli/internal/output/formatters"
 	"github.com/rimelabs/rime-cli/internal/output/visualizer"
@@ -31,16 +33,21 @@ const (
 )
 
 type PlayModel struct {
-	filepath string
-	meta     metadata.WavMetadata
-	mp3Meta  metadata.MP3Metadata
-	isMP3    bool
+	playlist *playback.Playlist
+	// speed is the playback speed the first track starts at; later tracks
+	// keep the speed the one before had.
+	speed   float64
+	meta    metadata.WavMetadata
+	mp3Meta metadata.MP3Metadata
+	isMP3   bool
 
 	state     PlayState
 	err       error
 	audioData []byte
 
 	streamer   beep.StreamSeekCloser
+	controls   *playback.Controls
+	analyzer   *analyze.AmplitudeAnalyzer
 	sampleRate beep.SampleRate
 	playDone   chan struct{}
 	playStart  time.Time
@@ -63,18 +70,39 @@ type PlayLoadDoneMsg struct {
 
 type PlayStartedMsg struct {
 	Streamer   beep.StreamSeekCloser
+	Controls   *playback.Controls
 	SampleRate beep.SampleRate
 	PlayDone   chan struct{}
 	AudioDur   time.Duration
+	// Analyzer and Comment are set for audio played as it arrives, whose
+	// waveform is drawn as it plays and whose transcript is read from its
+	// header.
+	Analyzer *analyze.AmplitudeAnalyzer
+	Comment  *metadata.ParsedComment
 }
 
 type PlayTickMsg time.Time
 type PlayQuitMsg struct{}
 
 func NewPlayModel(filepath string) PlayModel {
+	return NewPlayModelFormat(filepath, "")
+}
+
+// NewPlayModelFormat is NewPlayModel for a file whose content type is
+// given, as for headerless audio. An empty contentType is detected from the
+// file.
+func NewPlayModelFormat(filepath string, contentType string) PlayModel {
+	return NewPlaylistModel(playback.NewPlaylist([]playback.Track{{Path: filepath, ContentType: contentType}}), 1)
+}
+
+// NewPlaylistModel plays the tracks of playlist one after another at speed
+// times their normal speed, quitting after the last unless the playlist
+// loops.
+func NewPlaylistModel(playlist *playback.Playlist, speed float64) PlayModel {
 	termWidth := GetTerminalWidth(40, 0)
 	return PlayModel{
-		filepath:  filepath,
+		playlist:  playlist,
+		speed:     speed,
 		state:     PlayStateLoading,
 		waveform:  visualizer.NewWaveform(termWidth),
 		termWidth: termWidth,
@@ -97,10 +125,16 @@ func (m PlayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, tea.Quit
 		}
+		if m.state == PlayStatePlaying && m.controls != nil {
+			return m, m.handleKey(msg)
+		}
 
 	case PlayLoadDoneMsg:
 		if msg.Err != nil {
 			m.err = msg.Err
+			if len(m.playlist.Tracks) > 1 {
+				m.err = fmt.Errorf("%s: %w", m.playlist.Track().Path, msg.Err)
+			}
 			return m, tea.Quit
 		}
 		m.audioData = msg.Audio
@@ -154,35 +188,45 @@ func (m PlayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case PlayStartedMsg:
 		m.state = PlayStatePlaying
 		m.streamer = msg.Streamer
+		m.controls = msg.Controls
 		m.sampleRate = msg.SampleRate
 		m.playDone = msg.PlayDone
 		m.audioDur = msg.AudioDur
+		m.analyzer = msg.Analyzer
 		m.playStart = time.Now()
 		m.frame = 0
+		if msg.Comment != nil {
+			m.parsedComment, m.hasComment = msg.Comment, true
+			m.transcript = visualizer.NewTranscript(msg.Comment.Text, visualizer.EstimateDurationFromText(msg.Comment.Text))
+			m.playlist.Tracks[m.playlist.Current()].Comment = msg.Comment
+		}
 		if m.hasComment && m.transcript == nil {
 			m.transcript = visualizer.NewTranscript(m.parsedComment.Text, m.audioDur)
-		} else if m.transcript != nil {
+		} else if m.transcript != nil && m.audioDur > 0 {
 			m.transcript.SetDuration(m.audioDur)
 		}
-		return m, playTick()
+		return m, nil
 
 	case PlayTickMsg:
 		m.frame++
 		if m.state == PlayStatePlaying {
-			elapsed := time.Since(m.playStart)
-			if m.audioDur > 0 {
-				progress := float64(elapsed) / float64(m.audioDur)
-				m.waveform.SetProgress(progress)
-			}
-			if m.transcript != nil {
-				m.transcript.SetElapsed(elapsed)
+			if m.analyzer != nil {
+				m.waveform.AddSample(m.analyzer.Amplitude())
 			}
+			m.syncProgress()
 		}
 
 		select {
 		case <-m.playDone:
+			if m.audioDur == 0 {
+				m.audioDur = m.elapsed()
+			}
 			if m.streamer != nil {
 				m.streamer.Close()
+				m.streamer = nil
+			}
+			if m.playlist.Next() {
+				return m, tea.Batch(m.loadTrack(), playTick())
 			}
 			m.state = PlayStateDone
 			if m.waveform != nil {
@@ -224,7 +268,20 @@ func (m PlayModel) View() string {
 				{"lang", m.parsedComment.Language},
 			}
 		} else {
-			labels = [][2]string{{"file", m.filepath}}
+			labels = [][2]string{{"file", m.playlist.Track().Path}}
+			if labels[0][1] == "-" {
+				labels[0][1] = "stdin"
+			}
+		}
+		if n := len(m.playlist.Tracks); n > 1 {
+			track := fmt.Sprintf("%d/%d", m.playlist.Position()+1, n)
+			if m.playlist.Shuffled() {
+				track += " · shuffle"
+			}
+			if m.playlist.Loop {
+				track += " · loop"
+			}
+			labels = append(labels, [2]string{"track", track})
 		}
 
 		var stats []string
@@ -232,7 +289,7 @@ func (m PlayModel) View() string {
 		if m.state == PlayStateDone {
 			dur = m.audioDur
 		} else {
-			dur = time.Since(m.playStart)
+			dur = m.elapsed()
 		}
 		if dur > 0 {
 			stats = append(stats, DimStyle.Render("Duration: ")+formatters.FormatDuration(dur))
@@ -240,21 +297,144 @@ func (m PlayModel) View() string {
 		if len(m.audioData) > 0 {
 			stats = append(stats, DimStyle.Render("Size: ")+formatters.FormatBytes(len(m.audioData)))
 		}
+		if m.controls != nil && m.state == PlayStatePlaying {
+			if m.controls.Paused() {
+				stats = append(stats, "Paused")
+			}
+			if v := m.controls.Volume(); v != 0 {
+				stats = append(stats, DimStyle.Render("Volume: ")+fmt.Sprintf("%+.0f dB", v))
+			}
+			if sp := m.controls.Speed(); sp != 1 {
+				stats = append(stats, DimStyle.Render("Speed: ")+fmt.Sprintf("%gx", sp))
+			}
+		}
 		statsLine := strings.Join(stats, DimStyle.Render(" | "))
 		b.WriteString(RenderMinimalView("Rime Play", m.waveform, m.transcript, "", m.termWidth, labels, statsLine))
+		if m.state == PlayStatePlaying {
+			b.WriteString(minimalIndent + DimStyle.Render(playKeysHelp) + "\n")
+			if len(m.playlist.Tracks) > 1 {
+				b.WriteString(minimalIndent + DimStyle.Render(playlistKeysHelp) + "\n")
+			}
+		}
+		if len(m.playlist.Tracks) > 1 {
+			b.WriteString("\n" + RenderPlaylist(m.playlist.Tracks, m.playlist.Current(), m.termWidth))
+		}
 	}
 
 	return b.String()
 }
 
+// playSeekStep is how far the left and right arrow keys seek.
+const playSeekStep = 5 * time.Second
+
+const playKeysHelp = "space pause · ←/→ seek · r restart · +/- volume · [/] speed · ctrl+c quit"
+
+const playlistKeysHelp = "n/p next/previous · s shuffle · l loop"
+
+// handleKey applies a playback control key, returning the command that
+// loads the next track if it moves through the playlist.
+func (m *PlayModel) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "n":
+		if m.playlist.Next() {
+			return m.loadTrack()
+		}
+		return nil
+	case "p":
+		if m.playlist.Previous() {
+			return m.loadTrack()
+		}
+		m.controls.Restart()
+	case "s":
+		m.playlist.SetShuffle(!m.playlist.Shuffled())
+		return nil
+	case "l":
+		m.playlist.Loop = !m.playlist.Loop
+		return nil
+	case " ":
+		m.controls.TogglePause()
+	case "left":
+		m.controls.Seek(-playSeekStep)
+	case "right":
+		m.controls.Seek(playSeekStep)
+	case "r":
+		m.controls.Restart()
+	case "+", "=":
+		m.controls.ChangeVolume(1)
+	case "-", "_":
+		m.controls.ChangeVolume(-1)
+	case "[":
+		m.controls.ChangeSpeed(-1)
+	case "]":
+		m.controls.ChangeSpeed(1)
+	default:
+		return nil
+	}
+	m.syncProgress()
+	return nil
+}
+
+// loadTrack stops the track playing and loads the playlist's current one.
+// The controls are kept until the new track starts, so its volume and speed
+// match.
+func (m *PlayModel) loadTrack() tea.Cmd {
+	if m.streamer != nil {
+		playback.Clear()
+		m.streamer.Close()
+		m.streamer = nil
+	}
+	m.playDone = nil
+	m.state = PlayStateLoading
+	m.meta, m.mp3Meta, m.isMP3 = metadata.WavMetadata{}, metadata.MP3Metadata{}, false
+	m.audioData = nil
+	m.audioDur = 0
+	m.analyzer = nil
+	m.parsedComment, m.hasComment, m.transcript = nil, false, nil
+	m.waveform = visualizer.NewWaveform(m.termWidth)
+	return m.loadFile()
+}
+
+// elapsed returns how far into the audio playback is.
+func (m *PlayModel) elapsed() time.Duration {
+	if m.controls != nil {
+		return m.controls.Position()
+	}
+	return time.Since(m.playStart)
+}
+
+// syncProgress moves the waveform playhead and the transcript to the
+// playback position.
+func (m *PlayModel) syncProgress() {
+	elapsed := m.elapsed()
+	if m.audioDur > 0 {
+		m.waveform.SetProgress(float64(elapsed) / float64(m.audioDur))
+	}
+	if m.transcript != nil {
+		m.transcript.SetElapsed(elapsed)
+	}
+}
+
 func (m *PlayModel) loadFile() tea.Cmd {
-	filepath := m.filepath
+	if playback.IsStream(m.playlist.Track().Path) {
+		return m.openStream()
+	}
+	track := m.playlist.Track()
+	filepath, contentType := track.Path, track.ContentType
 	return func() tea.Msg {
 		data, err := os.ReadFile(filepath)
 		if err != nil {
 			return PlayLoadDoneMsg{Err: err}
 		}
 
+		if contentType == "" {
+			contentType = detectformat.DetectFile(filepath, data)
+		}
+		// Headerless audio is given a WAV header so it plays and analyzes
+		// like any other WAV file.
+		if f, ok := codec.ParseContentType(contentType); ok {
+			data = codec.ToWAV(data, f)
+		}
+
 		meta := metadata.ReadMetadata(data)
 		return PlayLoadDoneMsg{Audio: data, Meta: meta}
 	}
@@ -263,13 +443,14 @@ func (m *PlayModel) loadFile() tea.Cmd {
 func (m *PlayModel) startPlayback() tea.Cmd {
 	audioData := m.audioData
 	isMP3 := m.isMP3
+	prev, speed := m.controls, m.speed
 	return func() tea.Msg {
 		reader := bytes.NewReader(audioData)
 		var streamer beep.StreamSeekCloser
 		var format beep.Format
 		var err error
 		if isMP3 {
-			streamer, format, err = stream.DecodeMP3Streaming(io.NopCloser(reader))
+			streamer, format, err = stream.DecodeMP3Streaming(stream.NopSeekCloser(reader))
 		} else {
 			decoder, f, decodeErr := stream.DecodeStreaming(reader)
 			if decodeErr != nil {
@@ -298,24 +479,69 @@ func (m *PlayModel) startPlayback() tea.Cmd {
 			}
 		}
 
-		err = speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
+		started, err := playControlled(streamer, format.SampleRate, prev, speed, false)
 		if err != nil {
 			streamer.Close()
 			return PlayQuitMsg{}
 		}
+		started.AudioDur = audioDur
+		return started
+	}
+}
 
-		playDone := make(chan struct{})
-		speaker.Play(beep.Seq(streamer, beep.Callback(func() {
-			close(playDone)
-		})))
-
-		return PlayStartedMsg{
-			Streamer:   streamer,
-			SampleRate: format.SampleRate,
-			PlayDone:   playDone,
-			AudioDur:   audioDur,
+// openStream plays the current track, stdin or a URL, as it arrives.
+func (m *PlayModel) openStream() tea.Cmd {
+	track := m.playlist.Track()
+	prev, speed := m.controls, m.speed
+	return func() tea.Msg {
+		source, format, err := playback.OpenStream(context.Background(), track.Path, track.ContentType)
+		if err != nil {
+			return PlayLoadDoneMsg{Err: err}
 		}
+		started, err := playControlled(source, format.SampleRate, prev, speed, true)
+		if err != nil {
+			source.Close()
+			return PlayLoadDoneMsg{Err: err}
+		}
+		started.Comment = source.Comment
+		return started
+	}
+}
+
+// playControlled starts playing streamer, audio at rate, through playback
+// controls whose volume and speed match prev's, or at speed if this is the
+// first track. Audio that is live, arriving as it plays, is analyzed to draw
+// its waveform.
+func playControlled(streamer beep.StreamSeekCloser, rate beep.SampleRate, prev *playback.Controls, speed float64, live bool) (PlayStartedMsg, error) {
+	outputRate, err := playback.InitSpeaker(rate)
+	if err != nil {
+		return PlayStartedMsg{}, err
+	}
+
+	controls := playback.NewControls(streamer, rate, outputRate, playback.OutputLock)
+	if prev != nil {
+		controls.Match(prev)
+	} else {
+		controls.SetSpeed(speed)
+	}
+	out := controls.Streamer()
+	var analyzer *analyze.AmplitudeAnalyzer
+	if live {
+		analyzer = analyze.NewAmplitudeAnalyzer(out)
+		out = analyzer
 	}
+	playDone := make(chan struct{})
+	playback.Play(beep.Seq(out, beep.Callback(func() {
+		close(playDone)
+	})))
+
+	return PlayStartedMsg{
+		Streamer:   streamer,
+		Controls:   controls,
+		SampleRate: rate,
+		PlayDone:   playDone,
+		Analyzer:   analyzer,
+	}, nil
 }
 
 type wavStreamerAdapter struct {
@@ -332,15 +558,15 @@ func (w *wavStreamerAdapter) Err() error {
 }
 
 func (w *wavStreamerAdapter) Len() int {
-	return -1
+	return w.decoder.Len()
 }
 
 func (w *wavStreamerAdapter) Position() int {
-	return -1
+	return w.decoder.Position()
 }
 
 func (w *wavStreamerAdapter) Seek(p int) error {
-	return fmt.Errorf("seek not supported")
+	return w.decoder.Seek(p)
 }
 
 func (w *wavStreamerAdapter) Close() error {
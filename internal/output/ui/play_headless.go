@@ -6,6 +6,8 @@ import (
 	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
 )
 
 type PlayModel struct {
@@ -13,6 +15,14 @@ type PlayModel struct {
 }
 
 func NewPlayModel(filepath string) PlayModel {
+	return NewPlayModelFormat(filepath, "")
+}
+
+func NewPlayModelFormat(filepath string, contentType string) PlayModel {
+	return NewPlaylistModel(nil, 1)
+}
+
+func NewPlaylistModel(playlist *playback.Playlist, speed float64) PlayModel {
 	return PlayModel{
 		err: fmt.Errorf("play command requires audio support"),
 	}
@@ -0,0 +1,105 @@
+//go:build !headless
+
+package ui
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+	"github.com/rimelabs/rime-cli/internal/audio/stream"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+	"github.com/rimelabs/rime-cli/internal/output/visualizer"
+)
+
+func playingModel(t *testing.T) PlayModel {
+	t.Helper()
+	// 240000 samples is ten seconds at 24 kHz.
+	decoder, format, err := stream.DecodeStreaming(bytes.NewReader(testhelpers.MakeValidWAV(240000)))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	m := NewPlayModel("test.wav")
+	m.state = PlayStatePlaying
+	m.controls = playback.NewControls(decoder, format.SampleRate, format.SampleRate, &sync.Mutex{})
+	m.audioDur = 10 * time.Second
+	m.transcript = visualizer.NewTranscript("one two three four", m.audioDur)
+	return m
+}
+
+func TestPlayModel_SeekKeys(t *testing.T) {
+	m := playingModel(t)
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	m = next.(PlayModel)
+	if got := m.controls.Position(); got != playSeekStep {
+		t.Errorf("after →: Position = %v, want %v", got, playSeekStep)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m = next.(PlayModel)
+	if got := m.controls.Position(); got != 0 {
+		t.Errorf("after ←: Position = %v, want 0", got)
+	}
+
+	m.controls.Seek(7 * time.Second)
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(PlayModel)
+	if got := m.controls.Position(); got != 0 {
+		t.Errorf("after r: Position = %v, want 0", got)
+	}
+}
+
+func TestPlayModel_ControlKeys(t *testing.T) {
+	m := playingModel(t)
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		{Type: tea.KeyRunes, Runes: []rune("+")},
+		{Type: tea.KeyRunes, Runes: []rune("]")},
+	} {
+		next, _ := m.Update(key)
+		m = next.(PlayModel)
+	}
+	if !m.controls.Paused() {
+		t.Error("space should pause")
+	}
+	if m.controls.Volume() != playback.VolumeStep {
+		t.Errorf("Volume = %v, want %v", m.controls.Volume(), playback.VolumeStep)
+	}
+	if m.controls.Speed() != 1+playback.SpeedStep {
+		t.Errorf("Speed = %v, want %v", m.controls.Speed(), 1+playback.SpeedStep)
+	}
+	if view := m.View(); !bytes.Contains([]byte(view), []byte("Paused")) {
+		t.Errorf("view should show playback is paused:\n%s", view)
+	}
+}
+
+func TestPlayModel_PlaylistKeys(t *testing.T) {
+	m := playingModel(t)
+	m.playlist = playback.NewPlaylist([]playback.Track{{Path: "one.wav"}, {Path: "two.wav"}})
+
+	for _, key := range []string{"s", "l"} {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(PlayModel)
+	}
+	if !m.playlist.Shuffled() || !m.playlist.Loop {
+		t.Errorf("s and l should turn on shuffle and loop: shuffled %v, loop %v", m.playlist.Shuffled(), m.playlist.Loop)
+	}
+	view := m.View()
+	for _, want := range []string{"track: ", "shuffle", "loop", "▶ one.wav", "two.wav"} {
+		if !bytes.Contains([]byte(view), []byte(want)) {
+			t.Errorf("view should contain %q:\n%s", want, view)
+		}
+	}
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = next.(PlayModel)
+	if m.playlist.Track().Path != "two.wav" || m.state != PlayStateLoading || cmd == nil {
+		t.Errorf("n should load the next track: track %s, state %v", m.playlist.Track().Path, m.state)
+	}
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+)
+
+// playlistRows is the most tracks RenderPlaylist shows at once.
+const playlistRows = 8
+
+// RenderPlaylist lists tracks with the current one marked, each followed by
+// the speaker, model and language of the transcript embedded in it. Long
+// playlists are scrolled to keep the current track in view.
+func RenderPlaylist(tracks []playback.Track, current, termWidth int) string {
+	start := max(0, min(current-playlistRows/2, len(tracks)-playlistRows))
+	end := min(len(tracks), start+playlistRows)
+
+	nameWidth := 0
+	for _, t := range tracks[start:end] {
+		nameWidth = max(nameWidth, lipgloss.Width(filepath.Base(t.Path)))
+	}
+
+	var b strings.Builder
+	row := lipgloss.NewStyle().MaxWidth(max(0, termWidth-lipgloss.Width(minimalIndent)))
+	if start > 0 {
+		b.WriteString(minimalIndent + DimStyle.Render("  ⋮") + "\n")
+	}
+	for i := start; i < end; i++ {
+		name := filepath.Base(tracks[i].Path)
+		line := name
+		if c := tracks[i].Comment; c != nil {
+			line += strings.Repeat(" ", nameWidth-lipgloss.Width(name)+2) +
+				DimStyle.Render(c.Speaker+" · "+c.ModelID+" · "+c.Language)
+		}
+		if i == current {
+			line = HeaderStyle.Render("▶ ") + line
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(minimalIndent + row.Render(line) + "\n")
+	}
+	if end < len(tracks) {
+		b.WriteString(minimalIndent + DimStyle.Render("  ⋮") + "\n")
+	}
+	return b.String()
+}
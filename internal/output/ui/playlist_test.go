@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+)
+
+func TestRenderPlaylist(t *testing.T) {
+	tracks := []playback.Track{
+		{Path: "prompts/balance.wav", Comment: &metadata.ParsedComment{Speaker: "astra", ModelID: "arcana", Language: "eng"}},
+		{Path: "prompts/goodbye.wav"},
+	}
+	out := RenderPlaylist(tracks, 1, 80)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out)
+	}
+	if !strings.Contains(lines[0], "balance.wav") || !strings.Contains(lines[0], "astra · arcana · eng") {
+		t.Errorf("first line should show the file and its voice: %q", lines[0])
+	}
+	if strings.Contains(lines[0], "▶") || !strings.Contains(lines[1], "▶ ") {
+		t.Errorf("only the current track should be marked:\n%s", out)
+	}
+}
+
+func TestRenderPlaylist_Scrolls(t *testing.T) {
+	var tracks []playback.Track
+	for i := 0; i < 30; i++ {
+		tracks = append(tracks, playback.Track{Path: fmt.Sprintf("clip-%02d.wav", i)})
+	}
+	out := RenderPlaylist(tracks, 20, 30)
+	if !strings.Contains(out, "▶ clip-20.wav") {
+		t.Errorf("current track should be in view:\n%s", out)
+	}
+	if strings.Contains(out, "clip-00.wav") || strings.Count(out, "⋮") != 2 {
+		t.Errorf("long playlist should be scrolled:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if w := lipgloss.Width(line); w > 30 {
+			t.Errorf("line width %d exceeds 30: %q", w, line)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,13 +14,14 @@ import (
 
 	"github.com/rimelabs/rime-cli/internal/api"
 	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
 	"github.com/rimelabs/rime-cli/internal/audio/decode"
 	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
 	"github.com/rimelabs/rime-cli/internal/audio/metadata"
-	"github.com/rimelabs/rime-cli/internal/config"
 	"github.com/rimelabs/rime-cli/internal/output/formatters"
 	"github.com/rimelabs/rime-cli/internal/output/styles"
 	"github.com/rimelabs/rime-cli/internal/output/visualizer"
+	"github.com/rimelabs/rime-cli/internal/tts"
 )
 
 type TTSState int
@@ -31,6 +33,8 @@ const (
 )
 
 type TTSModel struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
 	text       string
 	opts       *api.TTSOptions
 	output     string
@@ -39,10 +43,15 @@ type TTSModel struct {
 	baseURL    string
 	configEnv  string
 	configFile string
+	chunk      *tts.ChunkOptions
+	retries    int
+	cache      api.ResponseCache
 
 	state       TTSState
 	err         error
 	ttfb        time.Duration
+	cached      bool
+	words       []api.WordTiming
 	audioBuf    *bytes.Buffer
 	contentType string
 
@@ -70,6 +79,8 @@ type StreamStartedMsg struct {
 	PlayDone    chan struct{}
 	AudioBuf    *bytes.Buffer
 	TTFB        time.Duration
+	Cached      bool
+	Words       []api.WordTiming
 	ContentType string
 	Err         error
 }
@@ -77,7 +88,10 @@ type StreamStartedMsg struct {
 type TTSTickMsg time.Time
 type TTSQuitMsg struct{}
 
-func NewTTSModel(text string, opts *api.TTSOptions, output string, shouldPlay bool, version string, baseURL string, configEnv string, configFile string, minimal bool) TTSModel {
+// NewTTSModel returns a model that synthesizes and plays text. Cancelling ctx,
+// or pressing Ctrl+C once, stops the request and playback; any audio received
+// so far is still written to output.
+func NewTTSModel(ctx context.Context, text string, opts *api.TTSOptions, output string, shouldPlay bool, version string, baseURL string, configEnv string, configFile string, chunk *tts.ChunkOptions, retries int, cache api.ResponseCache, minimal bool) TTSModel {
 	predictedDuration := visualizer.EstimateDurationFromText(text)
 	var termWidth int
 	var rightContentWidth int
@@ -92,7 +106,10 @@ func NewTTSModel(text string, opts *api.TTSOptions, output string, shouldPlay bo
 		waveform = visualizer.NewWaveformTwoRow(rightContentWidth)
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
 	return TTSModel{
+		ctx:               ctx,
+		cancel:            cancel,
 		text:              text,
 		opts:              opts,
 		output:            output,
@@ -101,6 +118,9 @@ func NewTTSModel(text string, opts *api.TTSOptions, output string, shouldPlay bo
 		baseURL:           baseURL,
 		configEnv:         configEnv,
 		configFile:        configFile,
+		chunk:             chunk,
+		retries:           retries,
+		cache:             cache,
 		state:             TTSStateConnecting,
 		waveform:          waveform,
 		transcript:        visualizer.NewTranscript(text, predictedDuration),
@@ -121,6 +141,14 @@ func (m TTSModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.Type == tea.KeyCtrlC {
+			if m.state == TTSStatePlaying && m.ctx.Err() == nil {
+				// Stop the request and playback but keep running until
+				// playDone closes so the audio received so far is saved.
+				// A second Ctrl+C quits at once.
+				m.cancel()
+				return m, nil
+			}
+			m.cancel()
 			return m, tea.Quit
 		}
 
@@ -137,8 +165,17 @@ func (m TTSModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.playDone = msg.PlayDone
 		m.audioBuf = msg.AudioBuf
 		m.ttfb = msg.TTFB
+		m.cached = msg.Cached
+		m.words = msg.Words
 		m.contentType = msg.ContentType
 		m.playStart = time.Now()
+		if len(msg.Words) > 0 && m.transcript != nil {
+			starts := make([]time.Duration, len(msg.Words))
+			for i, w := range msg.Words {
+				starts[i] = w.Start()
+			}
+			m.transcript.SetWordStarts(starts)
+		}
 		return m, ttsTick()
 
 	case TTSTickMsg:
@@ -170,6 +207,8 @@ func (m TTSModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.audioDur = analyze.CalculateDuration(m.audioBuf.Bytes(), int(m.sampleRate), m.numChannels, m.precision*8)
 				} else if contentType == "audio/mpeg" || contentType == "audio/mp3" {
 					m.audioDur = analyze.CalculateMP3DurationFromData(m.audioBuf.Bytes())
+				} else if codec.IsRaw(contentType) {
+					m.audioDur = tts.CalculateDuration(m.audioBuf.Bytes(), contentType)
 				} else {
 					m.audioDur = time.Duration(float64(m.audioBuf.Len()) / 16000.0 * float64(time.Second))
 				}
@@ -181,7 +220,7 @@ func (m TTSModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				var audioData []byte
 				if contentType == "audio/wav" {
-					audioData = metadata.FixWavHeader(m.audioBuf.Bytes())
+					audioData = metadata.FixWavHeader(metadata.TrimPartialFrame(m.audioBuf.Bytes()))
 				} else {
 					audioData = m.audioBuf.Bytes()
 				}
@@ -198,7 +237,7 @@ func (m TTSModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 
-				amps, err := analyze.AnalyzeAmplitudes(audioData, samplesPerSecond)
+				amps, err := analyze.AnalyzeAmplitudesFromReader(bytes.NewReader(audioData), contentType, samplesPerSecond)
 				if err == nil && m.waveform != nil {
 					m.waveform.SetSamples(amps)
 					m.waveform.SetProgress(1.0)
@@ -255,7 +294,11 @@ func (m TTSModel) View() string {
 		}
 
 		if m.state == TTSStateDone && m.output != "" && m.output != "-" {
-			b.WriteString(styles.Successf("Audio saved to %s", m.output) + "\n")
+			if m.ctx.Err() != nil {
+				b.WriteString(styles.Successf("Partial audio saved to %s", m.output) + "\n")
+			} else {
+				b.WriteString(styles.Successf("Audio saved to %s", m.output) + "\n")
+			}
 		}
 	}
 
@@ -263,6 +306,7 @@ func (m TTSModel) View() string {
 }
 
 func (m *TTSModel) startStreaming() tea.Cmd {
+	ctx := m.ctx
 	text := m.text
 	opts := m.opts
 	shouldPlay := m.shouldPlay
@@ -270,23 +314,16 @@ func (m *TTSModel) startStreaming() tea.Cmd {
 	baseURL := m.baseURL
 	configEnv := m.configEnv
 	configFile := m.configFile
+	chunk := m.chunk
+	retries := m.retries
+	cache := m.cache
 	return func() tea.Msg {
-		resolved, err := config.ResolveConfigWithOptions(config.ResolveOptions{
-			EnvName:        configEnv,
-			APIURLOverride: baseURL,
-			ConfigFile:     configFile,
-		})
+		client, err := tts.NewClient(version, baseURL, configEnv, configFile, retries, cache)
 		if err != nil {
 			return StreamStartedMsg{Err: err}
 		}
 
-		client := api.NewClient(api.ClientOptions{
-			APIKey:           resolved.APIKey,
-			APIURL:           resolved.APIURL,
-			AuthHeaderPrefix: resolved.AuthHeaderPrefix,
-			Version:          version,
-		})
-		result, err := client.TTSStream(text, opts)
+		result, err := tts.Stream(ctx, client, text, opts, chunk)
 		if err != nil {
 			return StreamStartedMsg{Err: err}
 		}
@@ -296,7 +333,10 @@ func (m *TTSModel) startStreaming() tea.Cmd {
 			peekBuf := make([]byte, 512)
 			n, _ := result.Body.Read(peekBuf)
 			contentType = detectformat.DetectFormat(peekBuf[:n])
-			result.Body = io.NopCloser(io.MultiReader(bytes.NewReader(peekBuf[:n]), result.Body))
+			result.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(peekBuf[:n]), result.Body), result.Body}
 		}
 		if contentType == "" {
 			contentType = "audio/wav"
@@ -316,7 +356,7 @@ func (m *TTSModel) startStreaming() tea.Cmd {
 		playDone := make(chan struct{})
 
 		if shouldPlay {
-			err = m.startPlayback(format, analyzer, result.Body, playDone)
+			err = m.startPlayback(ctx, format, analyzer, result.Body, playDone)
 			if err != nil {
 				return StreamStartedMsg{Err: err}
 			}
@@ -336,6 +376,8 @@ func (m *TTSModel) startStreaming() tea.Cmd {
 			PlayDone:    playDone,
 			AudioBuf:    &audioBuf,
 			TTFB:        result.TTFB,
+			Cached:      result.Cached,
+			Words:       result.Words,
 			ContentType: contentType,
 		}
 	}
@@ -358,34 +400,12 @@ func (m *TTSModel) finalize() tea.Cmd {
 
 			var audioData []byte
 			if contentType == "audio/wav" {
-				audioData = metadata.FixWavHeader(audioBuf.Bytes())
+				audioData = metadata.FixWavHeader(metadata.TrimPartialFrame(audioBuf.Bytes()))
 			} else {
 				audioData = audioBuf.Bytes()
 			}
 
-			spk, modelId, lang := api.EffectiveOpts(opts)
-			truncatedText := formatters.TruncateText(text, 50)
-
-			if contentType == "audio/mpeg" || contentType == "audio/mp3" {
-				meta := metadata.MP3Metadata{
-					Artist:  "Rime AI TTS",
-					Title:   fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
-					Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, text),
-				}
-				var embedErr error
-				audioData, embedErr = metadata.EmbedMP3Metadata(audioData, meta)
-				if embedErr != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to embed MP3 metadata: %v\n", embedErr)
-				}
-			} else {
-				meta := metadata.WavMetadata{
-					Artist:  "Rime AI TTS",
-					Name:    fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
-					Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, text),
-				}
-				audioData = metadata.EmbedMetadata(audioData, meta)
-			}
-
+			audioData = tts.EmbedMetadata(audioData, contentType, text, opts)
 			os.WriteFile(output, audioData, 0644)
 		}
 
@@ -396,7 +416,9 @@ func (m *TTSModel) finalize() tea.Cmd {
 
 func (m TTSModel) buildStats() []string {
 	var stats []string
-	if m.ttfb > 0 {
+	if m.cached {
+		stats = append(stats, DimStyle.Render("TTFB: ")+"cached")
+	} else if m.ttfb > 0 {
 		stats = append(stats, DimStyle.Render("TTFB: ")+fmt.Sprintf("%dms", m.ttfb.Milliseconds()))
 	}
 	var dur time.Duration
@@ -420,6 +442,29 @@ func ttsTick() tea.Cmd {
 	})
 }
 
+// Audio returns the audio received, or nil if none arrived. The data is not
+// tagged with metadata.
+func (m TTSModel) Audio() *tts.Audio {
+	if m.audioBuf == nil || m.audioBuf.Len() == 0 {
+		return nil
+	}
+	contentType := m.contentType
+	if contentType == "" {
+		contentType = detectformat.DetectFormat(m.audioBuf.Bytes())
+	}
+	data := m.audioBuf.Bytes()
+	if contentType == "" || contentType == "audio/wav" {
+		contentType = "audio/wav"
+		data = metadata.FixWavHeader(metadata.TrimPartialFrame(data))
+	}
+	return &tts.Audio{Data: data, ContentType: contentType, TTFB: m.ttfb, Cached: m.cached, Words: m.words}
+}
+
+// Err returns the error that ended the model, or an error wrapping
+// context.Canceled if it was interrupted.
 func (m TTSModel) Err() error {
+	if m.err == nil && m.ctx.Err() != nil {
+		return fmt.Errorf("interrupted: %w", m.ctx.Err())
+	}
 	return m.err
 }
@@ -1,26 +1,42 @@
-//go:build !headless
-
 package ui
 
 import (
+	"context"
 	"io"
-	"time"
+	"sync"
 
 	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/speaker"
 	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
 )
 
-func (m *TTSModel) startPlayback(format beep.Format, analyzer *analyze.AmplitudeAnalyzer, body io.ReadCloser, playDone chan struct{}) error {
-	err := speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
+func (m *TTSModel) startPlayback(ctx context.Context, format beep.Format, analyzer *analyze.AmplitudeAnalyzer, body io.ReadCloser, playDone chan struct{}) error {
+	outputRate, err := playback.InitSpeaker(format.SampleRate)
 	if err != nil {
 		body.Close()
 		return err
 	}
+	var out beep.Streamer = analyzer
+	if outputRate != format.SampleRate {
+		out = beep.Resample(4, format.SampleRate, outputRate, analyzer)
+	}
 
-	speaker.Play(beep.Seq(analyzer, beep.Callback(func() {
-		body.Close()
-		close(playDone)
-	})))
+	var once sync.Once
+	finish := func() {
+		once.Do(func() {
+			body.Close()
+			close(playDone)
+		})
+	}
+	playback.Play(beep.Seq(out, beep.Callback(finish)))
+
+	go func() {
+		select {
+		case <-playDone:
+		case <-ctx.Done():
+			playback.Clear()
+			finish()
+		}
+	}()
 	return nil
 }
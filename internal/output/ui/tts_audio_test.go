@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+	"github.com/rimelabs/rime-cli/internal/audio/stream"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTTSModel_StartPlaybackThroughNullDevice(t *testing.T) {
+	if err := playback.SelectDevice(playback.NullDevice); err != nil {
+		t.Fatal(err)
+	}
+
+	// 4800 samples is 200ms at 24 kHz.
+	decoder, format, err := stream.DecodeStreaming(bytes.NewReader(testhelpers.MakeValidWAV(4800)))
+	if err != nil {
+		t.Fatalf("DecodeStreaming failed: %v", err)
+	}
+	body := &closeRecorder{Reader: bytes.NewReader(nil)}
+	playDone := make(chan struct{})
+
+	m := &TTSModel{}
+	if err := m.startPlayback(context.Background(), format, analyze.NewAmplitudeAnalyzer(decoder), body, playDone); err != nil {
+		t.Fatalf("startPlayback failed: %v", err)
+	}
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("playback through the null device never finished")
+	}
+	if !body.closed {
+		t.Error("body was not closed after playback")
+	}
+}
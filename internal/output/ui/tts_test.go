@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+var ctrlC = tea.KeyMsg{Type: tea.KeyCtrlC}
+
+func TestTTSModel_CtrlCWhileConnecting(t *testing.T) {
+	m := NewTTSModel(context.Background(), "hello", &api.TTSOptions{}, "", false, "test", "", "", "", nil, 0, nil, true)
+
+	next, cmd := m.Update(ctrlC)
+	if cmd == nil {
+		t.Fatal("expected quit command")
+	}
+	if err := next.(TTSModel).Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", err)
+	}
+}
+
+func TestTTSModel_CtrlCWhilePlayingWaitsForSave(t *testing.T) {
+	m := NewTTSModel(context.Background(), "hello", &api.TTSOptions{}, "out.wav", false, "test", "", "", "", nil, 0, nil, true)
+	m.state = TTSStatePlaying
+	m.playDone = make(chan struct{})
+
+	next, cmd := m.Update(ctrlC)
+	if cmd != nil {
+		t.Error("first Ctrl+C should not quit while audio can still be saved")
+	}
+	m = next.(TTSModel)
+	if m.ctx.Err() == nil {
+		t.Error("first Ctrl+C should cancel the request")
+	}
+
+	if _, cmd := m.Update(ctrlC); cmd == nil {
+		t.Error("second Ctrl+C should quit")
+	}
+}
@@ -22,6 +22,8 @@ type Transcript struct {
 	words    []string
 	duration time.Duration
 	elapsed  time.Duration
+	// starts holds the start time of each spoken word, when known.
+	starts []time.Duration
 }
 
 func NewTranscript(text string, duration time.Duration) *Transcript {
@@ -40,7 +42,29 @@ func (t *Transcript) SetElapsed(elapsed time.Duration) {
 	t.elapsed = elapsed
 }
 
+// SetWordStarts replaces the linear estimate with the actual start times of
+// the spoken words, in order. The API's words need not match the displayed
+// ones one to one (punctuation, numbers read out), so when the counts differ
+// progress through the timed words is mapped proportionally.
+func (t *Transcript) SetWordStarts(starts []time.Duration) {
+	t.starts = starts
+}
+
 func (t *Transcript) revealCount() int {
+	if len(t.starts) > 0 {
+		spoken := 0
+		for _, s := range t.starts {
+			if s > t.elapsed {
+				break
+			}
+			spoken++
+		}
+		if spoken == len(t.starts) {
+			return len(t.words)
+		}
+		return spoken * len(t.words) / len(t.starts)
+	}
+
 	n := len(t.words)
 	if t.duration > 0 && t.elapsed >= 0 {
 		progress := float64(t.elapsed) / float64(t.duration)
@@ -253,3 +253,41 @@ func TestEstimateDurationFromText(t *testing.T) {
 		t.Errorf("EstimateDurationFromText(\"\") = %v, expected 0", emptyDuration)
 	}
 }
+
+func TestTranscript_WordStarts(t *testing.T) {
+	// Most of the speech is the first word, which a linear estimate would
+	// get badly wrong.
+	tx := NewTranscript("supercalifragilistic is long", 3*time.Second)
+	tx.SetWordStarts([]time.Duration{0, 2400 * time.Millisecond, 2600 * time.Millisecond})
+
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 1},
+		{2 * time.Second, 1},
+		{2500 * time.Millisecond, 2},
+		{2700 * time.Millisecond, 3},
+	}
+	for _, tt := range tests {
+		tx.SetElapsed(tt.elapsed)
+		if got := tx.revealCount(); got != tt.want {
+			t.Errorf("revealCount at %v = %d, want %d", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestTranscript_WordStartsCountMismatch(t *testing.T) {
+	// "$5" is spoken as two timed words.
+	tx := NewTranscript("it costs $5 today", 0)
+	tx.SetWordStarts([]time.Duration{0, 200 * time.Millisecond, 500 * time.Millisecond, 700 * time.Millisecond, 900 * time.Millisecond})
+
+	tx.SetElapsed(750 * time.Millisecond)
+	if got := tx.revealCount(); got != 3 {
+		t.Errorf("revealCount = %d, want 3 (4 of 5 timed words)", got)
+	}
+	tx.SetElapsed(time.Second)
+	if got := tx.revealCount(); got != 4 {
+		t.Errorf("revealCount = %d, want all 4 words", got)
+	}
+}
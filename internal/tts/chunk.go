@@ -0,0 +1,330 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/stitch"
+)
+
+// DefaultChunkChars is the default maximum chunk length for --chunk.
+const DefaultChunkChars = 500
+
+// chunkPrefetch is how many chunks are requested ahead of the one being read.
+const chunkPrefetch = 2
+
+// ChunkOptions enables splitting long text into several requests whose audio
+// is stitched back into one stream.
+type ChunkOptions struct {
+	// MaxChars is the longest chunk SplitText may produce, in characters.
+	MaxChars int
+	// Silence is inserted between consecutive chunks.
+	Silence time.Duration
+}
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// SplitText splits text into chunks of at most maxChars characters. Whole
+// paragraphs are packed together where they fit; longer paragraphs are split
+// between sentences, and sentences that are still too long between clauses or
+// words.
+func SplitText(text string, maxChars int) []string {
+	var chunks []string
+	var cur strings.Builder
+	curLen := 0
+
+	flush := func() {
+		if curLen > 0 {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			curLen = 0
+		}
+	}
+	add := func(piece, sep string) {
+		n := len([]rune(piece))
+		if curLen > 0 && curLen+len(sep)+n > maxChars {
+			flush()
+		}
+		if curLen > 0 {
+			cur.WriteString(sep)
+			curLen += len(sep)
+		}
+		cur.WriteString(piece)
+		curLen += n
+	}
+
+	for _, para := range paragraphBreak.Split(text, -1) {
+		para = strings.Join(strings.Fields(para), " ")
+		if para == "" {
+			continue
+		}
+		if len([]rune(para)) <= maxChars {
+			add(para, "\n\n")
+			continue
+		}
+		sep := "\n\n"
+		for _, sentence := range splitSentences(para) {
+			for _, piece := range splitLong(sentence, maxChars) {
+				add(piece, sep)
+				sep = " "
+			}
+		}
+	}
+	flush()
+	return chunks
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+func isClosing(r rune) bool {
+	switch r {
+	case '"', '\'', '”', '’', ')', ']', '»', '」', '』':
+		return true
+	}
+	return false
+}
+
+// splitSentences splits a paragraph after sentence-ending punctuation and any
+// closing quotes or brackets that follow it.
+func splitSentences(para string) []string {
+	runes := []rune(para)
+	var sentences []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !isSentenceEnd(runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && (isSentenceEnd(runes[end]) || isClosing(runes[end])) {
+			end++
+		}
+		cjk := runes[i] == '。' || runes[i] == '！' || runes[i] == '？'
+		if end < len(runes) && !cjk && !unicode.IsSpace(runes[end]) {
+			i = end - 1
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+		i = end - 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// splitLong splits a sentence longer than maxChars, preferring a clause
+// boundary in the second half of each piece, then a space, then a hard cut.
+func splitLong(sentence string, maxChars int) []string {
+	runes := []rune(sentence)
+	var pieces []string
+	for len(runes) > maxChars {
+		cut := -1
+		for i := maxChars - 1; i >= maxChars/2; i-- {
+			if strings.ContainsRune(",;:—", runes[i]) && i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+				cut = i + 1
+				break
+			}
+		}
+		if cut < 0 {
+			for i := maxChars; i > 0; i-- {
+				if unicode.IsSpace(runes[i]) {
+					cut = i
+					break
+				}
+			}
+		}
+		if cut <= 0 {
+			cut = maxChars
+		}
+		if piece := strings.TrimSpace(string(runes[:cut])); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		runes = []rune(strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace))
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+// Stream starts synthesizing text. With chunk nil it is a single streaming
+// request. Otherwise text is split with SplitText and the chunks are
+// requested in order, each starting while earlier ones are still being read,
+// and the returned body is their audio stitched into one WAV or MP3 stream.
+// ContentType, TTFB and Cached describe the first chunk. Cancelling ctx aborts every
+// outstanding request and makes reads from the body fail.
+//
+// If opts.AudioFormat is a headerless format such as "audio/x-mulaw", the
+// body is in that format, transcoded locally if the API can't produce it.
+// Likewise "audio/wav; rate=11025" (see WithResample) gives WAV at that
+// rate, resampled locally if the model doesn't offer it.
+func Stream(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions, chunk *ChunkOptions) (*api.TTSStreamResult, error) {
+	if opts != nil {
+		if target, ok := codec.ParseContentType(opts.AudioFormat); ok {
+			return streamRaw(ctx, client, text, opts, chunk, target)
+		}
+		if rate := resampleRate(opts.AudioFormat); rate > 0 {
+			return streamResampled(ctx, client, text, opts, chunk, rate)
+		}
+	}
+	return streamEncoded(ctx, client, text, opts, chunk)
+}
+
+// streamEncoded is Stream for WAV and MP3.
+func streamEncoded(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions, chunk *ChunkOptions) (*api.TTSStreamResult, error) {
+	if chunk == nil {
+		return client.TTSStreamContext(ctx, text, opts)
+	}
+	if opts != nil && opts.Timestamps {
+		return nil, fmt.Errorf("word timestamps cannot be combined with chunking")
+	}
+	chunks := SplitText(text, chunk.MaxChars)
+	if len(chunks) <= 1 {
+		return client.TTSStreamContext(ctx, text, opts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	first, err := client.TTSStreamContext(ctx, chunks[0], opts)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("chunk 1 of %d: %w", len(chunks), err)
+	}
+
+	contentType := first.ContentType
+	if contentType == "" {
+		peekBuf := make([]byte, 512)
+		n, _ := first.Body.Read(peekBuf)
+		contentType = detectformat.DetectFormat(peekBuf[:n])
+		first.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peekBuf[:n]), first.Body), first.Body}
+	}
+	if contentType == "" {
+		contentType = "audio/wav"
+	}
+
+	p := startPrefetch(ctx, client, chunks, opts)
+	firstBody := first.Body
+	next := func() (io.ReadCloser, error) {
+		if firstBody != nil {
+			body := firstBody
+			firstBody = nil
+			return body, nil
+		}
+		return p.next()
+	}
+
+	var body io.ReadCloser
+	if IsMP3(contentType) {
+		body = stitch.MP3(next, chunk.Silence)
+	} else {
+		body = stitch.WAV(next, chunk.Silence)
+	}
+
+	return &api.TTSStreamResult{
+		Body:        &chunkedBody{ReadCloser: body, cancel: cancel},
+		ContentType: contentType,
+		TTFB:        first.TTFB,
+		Cached:      first.Cached,
+	}, nil
+}
+
+type fetchedChunk struct {
+	data []byte
+	err  error
+}
+
+// prefetcher downloads chunks[1:] in the background, keeping at most
+// chunkPrefetch of them requested but not yet consumed. It stops when ctx is
+// cancelled.
+type prefetcher struct {
+	ctx     context.Context
+	total   int
+	results []chan fetchedChunk
+	slots   chan struct{}
+	pos     int
+}
+
+func startPrefetch(ctx context.Context, client *api.Client, chunks []string, opts *api.TTSOptions) *prefetcher {
+	rest := chunks[1:]
+	p := &prefetcher{
+		ctx:     ctx,
+		total:   len(chunks),
+		results: make([]chan fetchedChunk, len(rest)),
+		slots:   make(chan struct{}, chunkPrefetch),
+	}
+	for i := range p.results {
+		p.results[i] = make(chan fetchedChunk, 1)
+	}
+
+	go func() {
+		for i, text := range rest {
+			select {
+			case p.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int, text string) {
+				data, err := fetchChunk(ctx, client, text, opts)
+				p.results[i] <- fetchedChunk{data: data, err: err}
+			}(i, text)
+		}
+	}()
+	return p
+}
+
+func fetchChunk(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions) ([]byte, error) {
+	result, err := client.TTSStreamContext(ctx, text, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
+
+func (p *prefetcher) next() (io.ReadCloser, error) {
+	if p.pos >= len(p.results) {
+		return nil, io.EOF
+	}
+	select {
+	case res := <-p.results[p.pos]:
+		<-p.slots
+		p.pos++
+		if res.err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %w", p.pos+1, p.total, res.err)
+		}
+		return io.NopCloser(bytes.NewReader(res.data)), nil
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+}
+
+// chunkedBody stops prefetching when the stitched stream is closed.
+type chunkedBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *chunkedBody) Close() error {
+	b.cancel()
+	return b.ReadCloser.Close()
+}
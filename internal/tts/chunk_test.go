@@ -0,0 +1,237 @@
+package tts
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{
+			name:     "short text is one chunk",
+			text:     "Hello there.",
+			maxChars: 100,
+			want:     []string{"Hello there."},
+		},
+		{
+			name:     "paragraphs packed together",
+			text:     "First para.\n\nSecond para.\n\n\nThird para that is longer.",
+			maxChars: 30,
+			want:     []string{"First para.\n\nSecond para.", "Third para that is longer."},
+		},
+		{
+			name:     "long paragraph split at sentences",
+			text:     "One two three. Four five six! Seven eight nine? Ten.",
+			maxChars: 30,
+			want:     []string{"One two three. Four five six!", "Seven eight nine? Ten."},
+		},
+		{
+			name:     "closing quotes stay with sentence",
+			text:     `He said "stop." Then he left.`,
+			maxChars: 20,
+			want:     []string{`He said "stop."`, "Then he left."},
+		},
+		{
+			name:     "decimal points are not sentence ends",
+			text:     "It costs 3.50 today. Tomorrow it costs more.",
+			maxChars: 25,
+			want:     []string{"It costs 3.50 today.", "Tomorrow it costs more."},
+		},
+		{
+			name:     "long sentence split at clause",
+			text:     "alpha beta gamma, delta epsilon zeta eta",
+			maxChars: 20,
+			want:     []string{"alpha beta gamma,", "delta epsilon zeta", "eta"},
+		},
+		{
+			name:     "unbroken text hard cut",
+			text:     "abcdefghij",
+			maxChars: 4,
+			want:     []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:     "line breaks within paragraph collapse",
+			text:     "one\ntwo\r\nthree",
+			maxChars: 100,
+			want:     []string{"one two three"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.maxChars)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitText() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chunk %d = %q, want %q", i, got[i], tt.want[i])
+				}
+				if n := len([]rune(got[i])); n > tt.maxChars {
+					t.Errorf("chunk %d has %d chars, max %d", i, n, tt.maxChars)
+				}
+			}
+		})
+	}
+}
+
+// chunkServer returns one 24kHz WAV per request whose samples all equal the
+// number of words in the requested text, and records the texts it received.
+func chunkServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.TTSRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		mu.Lock()
+		texts = append(texts, req.Text)
+		mu.Unlock()
+
+		if strings.Contains(req.Text, "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad chunk"))
+			return
+		}
+
+		value := uint16(len(strings.Fields(req.Text)))
+		wav := testhelpers.MakeValidWAV(100)
+		for i := 44; i < len(wav); i += 2 {
+			binary.LittleEndian.PutUint16(wav[i:], value)
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wav)
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), texts...)
+	}
+}
+
+func TestSynthesizeChunked_StitchesWAV(t *testing.T) {
+	server, texts := chunkServer(t)
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	opts := &api.TTSOptions{Speaker: "astra", ModelID: api.ModelIDArcana}
+	text := "One.\n\nTwo two.\n\nGo go go."
+	chunk := &ChunkOptions{MaxChars: 10, Silence: 5 * time.Millisecond}
+
+	audio, err := SynthesizeChunked(context.Background(), client, text, opts, chunk)
+	if err != nil {
+		t.Fatalf("SynthesizeChunked() error = %v", err)
+	}
+
+	if got := texts(); len(got) != 3 {
+		t.Fatalf("expected 3 requests, got %q", got)
+	}
+	if audio.ContentType != "audio/wav" {
+		t.Errorf("ContentType = %q, want audio/wav", audio.ContentType)
+	}
+
+	// 3 x 100 samples plus 2 x 120 samples of silence.
+	wantSamples := 3*100 + 2*120
+	if got, want := len(audio.Data), 44+wantSamples*2; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	if size := binary.LittleEndian.Uint32(audio.Data[40:44]); size != uint32(wantSamples*2) {
+		t.Errorf("data size = %d, want %d", size, wantSamples*2)
+	}
+
+	samples := audio.Data[44:]
+	sample := func(i int) uint16 { return binary.LittleEndian.Uint16(samples[i*2:]) }
+	for i, want := range map[int]uint16{0: 1, 99: 1, 100: 0, 220: 2, 319: 2, 320: 0, 440: 3, 539: 3} {
+		if got := sample(i); got != want {
+			t.Errorf("sample %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestSynthesizeChunked_ChunkError(t *testing.T) {
+	server, _ := chunkServer(t)
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	opts := &api.TTSOptions{Speaker: "astra", ModelID: api.ModelIDArcana}
+
+	_, err := SynthesizeChunked(context.Background(), client, "First part.\n\nWill fail.", opts, &ChunkOptions{MaxChars: 12})
+	if err == nil {
+		t.Fatal("expected error from failing chunk")
+	}
+	if !strings.Contains(err.Error(), "chunk 2 of 2") || !strings.Contains(err.Error(), "bad chunk") {
+		t.Errorf("error should identify the failing chunk, got %v", err)
+	}
+}
+
+func TestSynthesizeChunked_SingleChunk(t *testing.T) {
+	server, texts := chunkServer(t)
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	opts := &api.TTSOptions{Speaker: "astra", ModelID: api.ModelIDArcana}
+
+	audio, err := SynthesizeChunked(context.Background(), client, "Short text.", opts, &ChunkOptions{MaxChars: DefaultChunkChars})
+	if err != nil {
+		t.Fatalf("SynthesizeChunked() error = %v", err)
+	}
+	if got := texts(); len(got) != 1 || got[0] != "Short text." {
+		t.Errorf("requests = %q, want the text unchanged", got)
+	}
+	if len(audio.Data) != 44+200 {
+		t.Errorf("len = %d, want %d", len(audio.Data), 44+200)
+	}
+}
+
+func TestStream_CloseCancelsPrefetch(t *testing.T) {
+	started := make(chan struct{}, 4)
+	cancelled := make(chan struct{}, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.TTSRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Text == "One." {
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write(testhelpers.MakeValidWAV(100))
+			return
+		}
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	opts := &api.TTSOptions{Speaker: "astra", ModelID: api.ModelIDArcana}
+	result, err := Stream(context.Background(), client, "One.\n\nTwo.\n\nThree.", opts, &ChunkOptions{MaxChars: 5})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	<-started
+	result.Body.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight chunk request was not cancelled by Close")
+	}
+}
@@ -0,0 +1,103 @@
+package tts
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
+)
+
+// Edits are changes made to synthesized audio once all of it has arrived.
+type Edits struct {
+	// Normalize, if set, is the loudness in LUFS to normalize the audio to.
+	Normalize *float64
+	// TrimSilence, if set, removes silence from the start and end.
+	TrimSilence *convert.TrimOptions
+	// PadStart and PadEnd are silence added before and after the audio,
+	// once any silence has been trimmed.
+	PadStart, PadEnd time.Duration
+}
+
+// IsZero reports whether e leaves audio unchanged.
+func (e Edits) IsZero() bool {
+	return e.Normalize == nil && e.TrimSilence == nil && e.PadStart == 0 && e.PadEnd == 0
+}
+
+// reencodesMP3 reports whether e turns MP3 audio into WAV. Trimming and
+// padding decode MP3 and write it out again as WAV.
+func (e Edits) reencodesMP3() bool {
+	return e.TrimSilence != nil || e.PadStart > 0 || e.PadEnd > 0
+}
+
+// Check reports whether audio of contentType can be edited as e says.
+func (e Edits) Check(contentType string) error {
+	if e.Normalize != nil && IsMP3(contentType) {
+		return fmt.Errorf("--normalize needs WAV or headerless output; MP3 can't be re-encoded")
+	}
+	return nil
+}
+
+// CheckOutput reports whether audio of contentType can be saved to path
+// once it is edited: MP3 that becomes WAV can't keep an .mp3 name.
+func (e Edits) CheckOutput(contentType, path string) error {
+	if e.ContentType(contentType) != contentType && strings.EqualFold(filepath.Ext(path), ".mp3") {
+		return fmt.Errorf("trimming or padding MP3 audio turns it into WAV; use a .wav output file instead of %s", path)
+	}
+	return nil
+}
+
+// ContentType returns the content type audio of contentType has once it
+// is edited.
+func (e Edits) ContentType(contentType string) string {
+	if IsMP3(contentType) && e.reencodesMP3() {
+		return "audio/wav"
+	}
+	return contentType
+}
+
+// Apply edits audio in place, shifting its word timings to match, and
+// returns the loudness measured before and after if it was normalized.
+func (e Edits) Apply(audio *Audio) (*loudness.Report, error) {
+	if e.IsZero() {
+		return nil, nil
+	}
+	if err := e.Check(audio.ContentType); err != nil {
+		return nil, err
+	}
+	contentType := e.ContentType(audio.ContentType)
+	res, err := convert.Convert(audio.Data, audio.ContentType, contentType, convert.Options{
+		Normalize:   e.Normalize,
+		TrimSilence: e.TrimSilence,
+		PadStart:    e.PadStart,
+		PadEnd:      e.PadEnd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit audio: %w", err)
+	}
+	audio.Data = res.Data
+	audio.ContentType = contentType
+	if res.Offset != 0 && len(audio.Words) > 0 {
+		// The duration comes from the edited WAV's header: MP3 turned into
+		// WAV keeps the MP3's sample rate and channels.
+		audio.Words = shiftWords(audio.Words, res.Offset, CalculateDuration(res.Data, contentType))
+	}
+	return res.Loudness, nil
+}
+
+// shiftWords moves word timings by offset, keeping them within the audio's
+// duration.
+func shiftWords(words []api.WordTiming, offset, duration time.Duration) []api.WordTiming {
+	shift := func(ms int64) int64 {
+		t := time.Duration(ms)*time.Millisecond + offset
+		return max(0, min(t, duration)).Milliseconds()
+	}
+	shifted := make([]api.WordTiming, len(words))
+	for i, w := range words {
+		shifted[i] = api.WordTiming{Word: w.Word, StartMs: shift(w.StartMs), EndMs: shift(w.EndMs)}
+	}
+	return shifted
+}
@@ -0,0 +1,90 @@
+package tts
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+)
+
+func TestEdits_TrimShiftsWords(t *testing.T) {
+	// 100 ms of silence, 200 ms of speech and 100 ms of silence.
+	samples := make([]int16, 9600)
+	for i := 2400; i < 7200; i++ {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/24000))
+	}
+	audio := &Audio{
+		Data:        pcmWAV(24000, samples...),
+		ContentType: "audio/wav",
+		Words:       []api.WordTiming{{Word: "hello", StartMs: 100, EndMs: 300}},
+	}
+	edits := Edits{
+		TrimSilence: &convert.TrimOptions{Threshold: -50, MinDuration: 50 * time.Millisecond},
+		PadEnd:      50 * time.Millisecond,
+	}
+	if _, err := edits.Apply(audio); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if got := CalculateDuration(audio.Data, audio.ContentType); got != 250*time.Millisecond {
+		t.Errorf("duration = %s, want 250ms", got)
+	}
+	if w := audio.Words[0]; w.StartMs != 0 || w.EndMs != 200 {
+		t.Errorf("word timing = %d-%d ms, want 0-200", w.StartMs, w.EndMs)
+	}
+}
+
+// silentMP3 returns frames of silent 16 kHz mono MPEG-2 Layer III audio,
+// 36 ms each.
+func silentMP3(frames int) []byte {
+	frame := make([]byte, 72)
+	copy(frame, []byte{0xFF, 0xF3, 0x28, 0xC0})
+	var data []byte
+	for i := 0; i < frames; i++ {
+		data = append(data, frame...)
+	}
+	return data
+}
+
+func TestEdits_PaddedMP3KeepsWordTimings(t *testing.T) {
+	audio := &Audio{
+		Data:        silentMP3(28),
+		ContentType: "audio/mp3",
+		Words:       []api.WordTiming{{Word: "hello", StartMs: 100, EndMs: 400}, {Word: "world", StartMs: 500, EndMs: 1000}},
+	}
+	edits := Edits{PadStart: 100 * time.Millisecond, PadEnd: 100 * time.Millisecond}
+	if _, err := edits.Apply(audio); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if audio.ContentType != "audio/wav" {
+		t.Fatalf("content type = %q, want audio/wav", audio.ContentType)
+	}
+	// The WAV keeps the MP3's 16 kHz stereo, which read as 24 kHz mono
+	// would put the end of the audio in the wrong place.
+	if got := CalculateDuration(audio.Data, audio.ContentType); got < 1200*time.Millisecond || got > 1220*time.Millisecond {
+		t.Errorf("duration = %s, want the MP3's 1.008s plus 200ms of padding", got)
+	}
+	if w := audio.Words[len(audio.Words)-1]; w.StartMs != 600 || w.EndMs != 1100 {
+		t.Errorf("last word timing = %d-%d ms, want 600-1100", w.StartMs, w.EndMs)
+	}
+}
+
+func TestEdits_MP3(t *testing.T) {
+	target := -16.0
+	if err := (Edits{Normalize: &target}).Check("audio/mp3"); err == nil {
+		t.Error("expected an error normalizing MP3")
+	}
+	trim := Edits{PadStart: time.Second}
+	if err := trim.Check("audio/mp3"); err != nil {
+		t.Errorf("padding MP3: %v", err)
+	}
+	if got := trim.ContentType("audio/mp3"); got != "audio/wav" {
+		t.Errorf("padded MP3 content type = %q, want audio/wav", got)
+	}
+	if got := trim.ContentType("audio/wav"); got != "audio/wav" {
+		t.Errorf("padded WAV content type = %q, want audio/wav", got)
+	}
+}
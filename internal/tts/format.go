@@ -0,0 +1,192 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+	"github.com/rimelabs/rime-cli/internal/audio/convert"
+	"github.com/rimelabs/rime-cli/internal/audio/decode"
+)
+
+// Formats lists the names accepted by FormatContentType.
+var Formats = []string{"wav", "mp3", "pcm", "mulaw", "alaw"}
+
+// FormatContentType returns the content type to request for a --format
+// name. The headerless formats are mono, at samplingRate if it is set and
+// otherwise 24 kHz for pcm and 8 kHz for mulaw and alaw.
+func FormatContentType(name string, samplingRate *int) (string, error) {
+	switch name {
+	case "wav", "mp3":
+		return "audio/" + name, nil
+	}
+	enc, ok := codec.ParseEncoding(name)
+	if !ok {
+		return "", fmt.Errorf("unsupported format: %s (supported: wav, mp3, pcm, mulaw, alaw)", name)
+	}
+	f := codec.Format{Encoding: enc, SampleRate: enc.DefaultRate(), NumChannels: 1}
+	if samplingRate != nil {
+		f.SampleRate = *samplingRate
+	}
+	return f.ContentType(), nil
+}
+
+// nativeEncodings are the headerless encodings the API returns itself when
+// asked for them in the Accept header. The others are transcoded locally
+// from the model's usual WAV or MP3 response.
+var nativeEncodings = map[codec.Encoding]bool{
+	codec.PCM16: true,
+	codec.Mulaw: true,
+}
+
+// streamRaw synthesizes text as headerless audio in format target. The API
+// is asked for target directly where it supports it, unless the response
+// must be chunked or carry word timings; otherwise, or if the API refuses
+// the format, the usual response is transcoded.
+func streamRaw(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions, chunk *ChunkOptions, target codec.Format) (*api.TTSStreamResult, error) {
+	reqOpts := *opts
+	// Asking for the target rate means the response rarely needs
+	// resampling, whichever way it is produced.
+	reqOpts.SamplingRate = nativeRate(opts, target.SampleRate)
+
+	if nativeEncodings[target.Encoding] && chunk == nil && !opts.Timestamps {
+		reqOpts.AudioFormat = target.Encoding.ContentType()
+		result, err := client.TTSStreamContext(ctx, text, &reqOpts)
+		if err == nil {
+			return asRaw(result, target)
+		}
+		if !formatRefused(err) {
+			return nil, err
+		}
+	}
+
+	reqOpts.AudioFormat = ""
+	result, err := streamEncoded(ctx, client, text, &reqOpts, chunk)
+	if err != nil {
+		return nil, err
+	}
+	return asRaw(result, target)
+}
+
+// formatRefused reports whether the API rejected the requested Accept type.
+func formatRefused(err error) bool {
+	var apiErr *api.APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotAcceptable || apiErr.StatusCode == http.StatusUnsupportedMediaType)
+}
+
+// asRaw returns result as audio in format target, transcoding it if the API
+// answered with WAV or MP3 instead.
+func asRaw(result *api.TTSStreamResult, target codec.Format) (*api.TTSStreamResult, error) {
+	contentType, _, _ := mime.ParseMediaType(result.ContentType)
+	if f, ok := codec.ParseContentType(result.ContentType); ok && f.Encoding == target.Encoding {
+		contentType = ""
+	} else if contentType != "audio/wav" && !IsMP3(contentType) {
+		// An unlabelled response is taken to be what was asked for unless
+		// it has a WAV header. MP3 can't be sniffed reliably here: μ-law
+		// silence looks like an MP3 sync word.
+		peek := make([]byte, 4)
+		n, _ := io.ReadFull(result.Body, peek)
+		result.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peek[:n]), result.Body), result.Body}
+		contentType = ""
+		if bytes.Equal(peek[:n], []byte("RIFF")) {
+			contentType = "audio/wav"
+		}
+	}
+
+	if contentType != "" {
+		decoder, format, err := decode.DecodeAudio(result.Body, contentType)
+		if err != nil {
+			result.Body.Close()
+			return nil, fmt.Errorf("failed to transcode to %s: %w", target.Encoding, err)
+		}
+		result.Body = struct {
+			io.Reader
+			io.Closer
+		}{convert.NewRawReader(decoder, format, target), result.Body}
+	}
+	result.ContentType = target.ContentType()
+	return result, nil
+}
+
+// nativeRate returns rate if the model can produce it, and otherwise the
+// sampling rate opts already asks for, leaving the difference to be
+// resampled locally.
+func nativeRate(opts *api.TTSOptions, rate int) *int {
+	check := *opts
+	check.SamplingRate = &rate
+	if api.ValidateModelParams(&check) == nil {
+		return &rate
+	}
+	return opts.SamplingRate
+}
+
+// WithResample returns contentType changed to carry sample rate rate. For
+// headerless formats that is the rate parameter. WAV gets a rate parameter
+// too, which Stream takes as a request to resample the model's WAV output.
+// MP3 can't be re-encoded, so it can't be resampled.
+func WithResample(contentType string, rate int) (string, error) {
+	if rate <= 0 {
+		return "", fmt.Errorf("--resample must be greater than 0, got %d", rate)
+	}
+	if f, ok := codec.ParseContentType(contentType); ok {
+		f.SampleRate = rate
+		return f.ContentType(), nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "audio/wav" {
+		return "", fmt.Errorf("--resample needs WAV or headerless output; MP3 can't be re-encoded")
+	}
+	return mime.FormatMediaType("audio/wav", map[string]string{"rate": strconv.Itoa(rate)}), nil
+}
+
+// resampleRate returns the rate parameter of a WAV content type, or 0 if it
+// has none.
+func resampleRate(contentType string) int {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "audio/wav" {
+		return 0
+	}
+	rate, err := strconv.Atoi(params["rate"])
+	if err != nil || rate <= 0 {
+		return 0
+	}
+	return rate
+}
+
+// streamResampled synthesizes text as WAV at sample rate rate. The API is
+// asked for rate itself if the model offers it; otherwise the model's WAV
+// output is resampled locally.
+func streamResampled(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions, chunk *ChunkOptions, rate int) (*api.TTSStreamResult, error) {
+	reqOpts := *opts
+	reqOpts.AudioFormat = "audio/wav"
+	reqOpts.SamplingRate = nativeRate(opts, rate)
+
+	result, err := streamEncoded(ctx, client, text, &reqOpts, chunk)
+	if err != nil {
+		return nil, err
+	}
+	if reqOpts.SamplingRate != nil && *reqOpts.SamplingRate == rate {
+		return result, nil
+	}
+
+	decoder, format, err := decode.DecodeAudio(result.Body, "audio/wav")
+	if err != nil {
+		result.Body.Close()
+		return nil, fmt.Errorf("failed to resample: %w", err)
+	}
+	result.Body = struct {
+		io.Reader
+		io.Closer
+	}{convert.NewWAVReader(decoder, format, convert.Options{SampleRate: rate}), result.Body}
+	result.ContentType = "audio/wav"
+	return result, nil
+}
@@ -0,0 +1,210 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
+)
+
+// pcmWAV returns a 16-bit mono WAV holding samples.
+func pcmWAV(rate int, samples ...int16) []byte {
+	var pcm bytes.Buffer
+	binary.Write(&pcm, binary.LittleEndian, samples)
+	return codec.ToWAV(pcm.Bytes(), codec.Format{Encoding: codec.PCM16, SampleRate: rate, NumChannels: 1})
+}
+
+func TestFormatContentType(t *testing.T) {
+	rate := 16000
+	tests := []struct {
+		name string
+		rate *int
+		want string
+	}{
+		{"wav", nil, "audio/wav"},
+		{"mp3", nil, "audio/mp3"},
+		{"pcm", nil, "audio/pcm; channels=1; rate=24000"},
+		{"mulaw", nil, "audio/x-mulaw; channels=1; rate=8000"},
+		{"alaw", &rate, "audio/x-alaw; channels=1; rate=16000"},
+	}
+	for _, tt := range tests {
+		if got, err := FormatContentType(tt.name, tt.rate); err != nil || got != tt.want {
+			t.Errorf("FormatContentType(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := FormatContentType("ogg", nil); err == nil {
+		t.Error("expected an error for ogg")
+	}
+}
+
+func TestSynthesize_NativeMulaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.TTSRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Accept") != codec.ContentTypeMulaw || req.SamplingRate == nil || *req.SamplingRate != 8000 {
+			t.Errorf("expected an 8 kHz μ-law request, got Accept %q and %+v", r.Header.Get("Accept"), req)
+		}
+		w.Header().Set("Content-Type", codec.ContentTypeMulaw)
+		w.Write([]byte{0x01, 0x02, 0xFF})
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	contentType, _ := FormatContentType("mulaw", nil)
+	audio, err := Synthesize(context.Background(), client, "hello", &api.TTSOptions{Speaker: "astra", ModelID: "arcana", AudioFormat: contentType})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	if !bytes.Equal(audio.Data, []byte{0x01, 0x02, 0xFF}) || audio.ContentType != contentType {
+		t.Errorf("got %x as %q", audio.Data, audio.ContentType)
+	}
+}
+
+func TestSynthesize_AlawTranscodedFromWAV(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "audio/wav" {
+			t.Errorf("A-law should be requested as WAV, got Accept %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(pcmWAV(8000, 1000, -1000, 0))
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	contentType, _ := FormatContentType("alaw", nil)
+	audio, err := Synthesize(context.Background(), client, "hello", &api.TTSOptions{Speaker: "astra", ModelID: "arcana", AudioFormat: contentType})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	want := []byte{codec.AlawEncode(1000), codec.AlawEncode(-1000), codec.AlawEncode(0)}
+	if !bytes.Equal(audio.Data, want) {
+		t.Errorf("got %x, want %x", audio.Data, want)
+	}
+	if d := CalculateDuration(audio.Data, audio.ContentType); d.Microseconds() != 375 {
+		t.Errorf("duration = %v, want 375µs", d)
+	}
+}
+
+func TestSynthesize_PCMFallsBackWhenRefused(t *testing.T) {
+	var accepts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepts = append(accepts, r.Header.Get("Accept"))
+		if r.Header.Get("Accept") == codec.ContentTypePCM {
+			w.WriteHeader(http.StatusNotAcceptable)
+			w.Write([]byte(`{"message":"unsupported format"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(pcmWAV(24000, 1000, 2000))
+	}))
+	defer server.Close()
+
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+	contentType, _ := FormatContentType("pcm", nil)
+	audio, err := Synthesize(context.Background(), client, "hello", &api.TTSOptions{Speaker: "astra", ModelID: "arcana", AudioFormat: contentType})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	if len(accepts) != 2 || accepts[1] != "audio/wav" {
+		t.Errorf("requests sent with Accept %v", accepts)
+	}
+	var want bytes.Buffer
+	binary.Write(&want, binary.LittleEndian, []int16{1000, 2000})
+	if !bytes.Equal(audio.Data, want.Bytes()) {
+		t.Errorf("got %x, want %x", audio.Data, want.Bytes())
+	}
+}
+
+func TestWithResample(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		wantErr     bool
+	}{
+		{"audio/wav", "audio/wav; rate=11025", false},
+		{"audio/x-mulaw; channels=1; rate=8000", "audio/x-mulaw; channels=1; rate=11025", false},
+		{"audio/mp3", "", true},
+	}
+	for _, tt := range tests {
+		got, err := WithResample(tt.contentType, 11025)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("WithResample(%q) = %q, %v; want %q", tt.contentType, got, err, tt.want)
+		}
+	}
+}
+
+func TestSynthesize_Resample(t *testing.T) {
+	var requested []*int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.TTSRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = append(requested, req.SamplingRate)
+		if r.Header.Get("Accept") != "audio/wav" {
+			t.Errorf("expected a WAV request, got Accept %q", r.Header.Get("Accept"))
+		}
+		rate := 24000
+		if req.SamplingRate != nil {
+			rate = *req.SamplingRate
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(pcmWAV(rate, make([]int16, rate/10)...))
+	}))
+	defer server.Close()
+	client := api.NewClient(api.ClientOptions{APIKey: "test-key", APIURL: server.URL})
+
+	// 11025 Hz isn't an arcana rate, so 24 kHz audio is resampled.
+	contentType, _ := WithResample("audio/wav", 11025)
+	audio, err := Synthesize(context.Background(), client, "hello", &api.TTSOptions{Speaker: "astra", ModelID: "arcana", AudioFormat: contentType})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	if requested[0] != nil {
+		t.Errorf("requested sampling rate %d, want the model default", *requested[0])
+	}
+	if audio.ContentType != "audio/wav" || binary.LittleEndian.Uint32(audio.Data[24:28]) != 11025 {
+		t.Errorf("got %q at %d Hz", audio.ContentType, binary.LittleEndian.Uint32(audio.Data[24:28]))
+	}
+	if frames := (len(audio.Data) - 44) / 2; frames != 1103 {
+		t.Errorf("got %d frames, want 1103", frames)
+	}
+
+	// 16000 Hz is, so it is requested directly.
+	contentType, _ = WithResample("audio/wav", 16000)
+	audio, err = Synthesize(context.Background(), client, "hello", &api.TTSOptions{Speaker: "astra", ModelID: "arcana", AudioFormat: contentType})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	if requested[1] == nil || *requested[1] != 16000 {
+		t.Errorf("requested sampling rate %v, want 16000", requested[1])
+	}
+	if !bytes.Equal(audio.Data, pcmWAV(16000, make([]int16, 1600)...)) {
+		t.Error("natively produced audio was modified")
+	}
+}
+
+func TestCalculateDuration_ReadsWAVHeader(t *testing.T) {
+	stereo := codec.ToWAV(make([]byte, 4800*4), codec.Format{Encoding: codec.PCM16, SampleRate: 48000, NumChannels: 2})
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        time.Duration
+	}{
+		{"24 kHz mono", pcmWAV(24000, make([]int16, 2400)...), "audio/wav", 100 * time.Millisecond},
+		{"resampled to 11025 Hz", pcmWAV(11025, make([]int16, 2205)...), "audio/wav", 200 * time.Millisecond},
+		{"48 kHz stereo", stereo, "audio/wav", 100 * time.Millisecond},
+		{"headerless mulaw", make([]byte, 800), "audio/x-mulaw; channels=1; rate=8000", 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := CalculateDuration(tt.data, tt.contentType); got != tt.want {
+			t.Errorf("%s: CalculateDuration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
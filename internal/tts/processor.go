@@ -2,6 +2,7 @@ package tts
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/rimelabs/rime-cli/internal/api"
 	"github.com/rimelabs/rime-cli/internal/audio/analyze"
+	"github.com/rimelabs/rime-cli/internal/audio/codec"
 	"github.com/rimelabs/rime-cli/internal/audio/detectformat"
+	"github.com/rimelabs/rime-cli/internal/audio/loudness"
 	"github.com/rimelabs/rime-cli/internal/audio/metadata"
 	"github.com/rimelabs/rime-cli/internal/audio/playback"
 	"github.com/rimelabs/rime-cli/internal/config"
@@ -33,6 +36,14 @@ type Result struct {
 	Speaker    string `json:"speaker"`
 	ModelID    string `json:"model_id"`
 	Lang       string `json:"lang"`
+	Cached     bool   `json:"cached,omitempty"`
+	// Words holds word timings when they were requested.
+	Words []api.WordTiming `json:"words,omitempty"`
+	// SubtitlesFile is where captions were written, if they were requested.
+	SubtitlesFile string `json:"subtitles_file,omitempty"`
+	// Loudness is the measured loudness before and after normalization,
+	// if it was requested.
+	Loudness *loudness.Report `json:"loudness,omitempty"`
 }
 
 type RunOptions struct {
@@ -46,34 +57,75 @@ type RunOptions struct {
 	BaseURL    string
 	ConfigEnv  string
 	ConfigFile string
+	// Chunk, if set, splits long text into several requests.
+	Chunk *ChunkOptions
+	// Retries is the number of times a failed request is retried.
+	Retries int
+	// Cache, if set, is used to answer repeated requests.
+	Cache api.ResponseCache
+	// Subtitles, if set, writes captions for the audio.
+	Subtitles *SubtitleOptions
+	// Edits are applied to the audio before it is saved or played.
+	Edits Edits
 }
 
-func RunNonInteractive(opts RunOptions) error {
+// Audio is a fully received TTS response.
+type Audio struct {
+	Data        []byte
+	ContentType string
+	TTFB        time.Duration
+	// Cached is set when the audio came from the response cache.
+	Cached bool
+	// Words holds the word timings if opts.Timestamps was set.
+	Words []api.WordTiming
+}
+
+// NewClient resolves the configured environment and builds an API client
+// that retries failed requests up to retries times and, if cache is non-nil,
+// answers repeated requests from it.
+func NewClient(version, baseURL, configEnv, configFile string, retries int, cache api.ResponseCache) (*api.Client, error) {
 	resolved, err := config.ResolveConfigWithOptions(config.ResolveOptions{
-		EnvName:        opts.ConfigEnv,
-		APIURLOverride: opts.BaseURL,
-		ConfigFile:     opts.ConfigFile,
+		EnvName:        configEnv,
+		APIURLOverride: baseURL,
+		ConfigFile:     configFile,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	client := api.NewClient(api.ClientOptions{
+	return api.NewClient(api.ClientOptions{
 		APIKey:           resolved.APIKey,
 		APIURL:           resolved.APIURL,
 		AuthHeaderPrefix: resolved.AuthHeaderPrefix,
-		Version:          opts.Version,
-	})
-	result, err := client.TTSStream(opts.Text, opts.TTSOptions)
+		Version:          version,
+		Retry:            api.DefaultRetryPolicy(retries),
+		Cache:            cache,
+	}), nil
+}
+
+// Synthesize streams a TTS request to completion. WAV responses have their
+// placeholder header sizes fixed so the returned data is a valid file.
+func Synthesize(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions) (*Audio, error) {
+	return SynthesizeChunked(ctx, client, text, opts, nil)
+}
+
+// SynthesizeChunked is Synthesize with optional long-text chunking; see Stream.
+// If ctx is cancelled after audio has started arriving, the audio received so
+// far is returned along with the error.
+func SynthesizeChunked(ctx context.Context, client *api.Client, text string, opts *api.TTSOptions, chunk *ChunkOptions) (*Audio, error) {
+	result, err := Stream(ctx, client, text, opts, chunk)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx)
+		}
+		return nil, err
 	}
 	defer result.Body.Close()
 
 	var audioBuf bytes.Buffer
-	_, err = io.Copy(&audioBuf, result.Body)
-	if err != nil {
-		return err
+	_, copyErr := io.Copy(&audioBuf, result.Body)
+	if copyErr != nil && ctx.Err() == nil {
+		return nil, copyErr
 	}
 
 	contentType := result.ContentType
@@ -84,38 +136,127 @@ func RunNonInteractive(opts RunOptions) error {
 		contentType = "audio/wav"
 	}
 
-	var audioData []byte
+	audioData := audioBuf.Bytes()
 	if contentType == "audio/wav" {
-		audioData = metadata.FixWavHeader(audioBuf.Bytes())
-	} else {
-		audioData = audioBuf.Bytes()
+		if copyErr != nil {
+			audioData = metadata.TrimPartialFrame(audioData)
+		}
+		audioData = metadata.FixWavHeader(audioData)
+	} else if f, ok := codec.ParseContentType(contentType); ok {
+		audioData = audioData[:len(audioData)/f.FrameSize()*f.FrameSize()]
 	}
 
-	if opts.Output != "" && opts.Output != "-" {
-		spk, modelId, lang := api.EffectiveOpts(opts.TTSOptions)
-		truncatedText := formatters.TruncateText(opts.Text, 50)
-
-		if contentType == "audio/mpeg" || contentType == "audio/mp3" {
-			meta := metadata.MP3Metadata{
-				Artist:  "Rime AI TTS",
-				Title:   fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
-				Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, opts.Text),
-			}
-			var embedErr error
-			audioData, embedErr = metadata.EmbedMP3Metadata(audioData, meta)
-			if embedErr != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to embed MP3 metadata: %v\n", embedErr)
+	audio := &Audio{
+		Data:        audioData,
+		ContentType: contentType,
+		TTFB:        result.TTFB,
+		Cached:      result.Cached,
+		Words:       result.Words,
+	}
+	if copyErr != nil {
+		return audio, interrupted(ctx)
+	}
+	return audio, nil
+}
+
+func interrupted(ctx context.Context) error {
+	return fmt.Errorf("interrupted: %w", ctx.Err())
+}
+
+// IsMP3 reports whether contentType names an MP3 stream.
+func IsMP3(contentType string) bool {
+	return contentType == "audio/mpeg" || contentType == "audio/mp3"
+}
+
+// EmbedMetadata tags audio with the speaker, model, language and text used to
+// generate it, in the comment format metadata.ParseComment understands.
+// Headerless audio has nowhere to keep tags and is returned unchanged.
+func EmbedMetadata(audioData []byte, contentType string, text string, opts *api.TTSOptions) []byte {
+	if codec.IsRaw(contentType) {
+		return audioData
+	}
+	spk, modelId, lang := api.EffectiveOpts(opts)
+	truncatedText := formatters.TruncateText(text, 50)
+
+	if IsMP3(contentType) {
+		meta := metadata.MP3Metadata{
+			Artist:  "Rime AI TTS",
+			Title:   fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
+			Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, text),
+		}
+		tagged, err := metadata.EmbedMP3Metadata(audioData, meta)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to embed MP3 metadata: %v\n", err)
+			return audioData
+		}
+		return tagged
+	}
+
+	meta := metadata.WavMetadata{
+		Artist:  "Rime AI TTS",
+		Name:    fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
+		Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, text),
+	}
+	return metadata.EmbedMetadata(audioData, meta)
+}
+
+// SaveAudio embeds metadata and writes the audio to path, returning the bytes
+// that were written.
+func SaveAudio(path string, audio *Audio, text string, opts *api.TTSOptions) ([]byte, error) {
+	audioData := EmbedMetadata(audio.Data, audio.ContentType, text, opts)
+	if err := os.WriteFile(path, audioData, 0644); err != nil {
+		return nil, err
+	}
+	return audioData, nil
+}
+
+// NewResult builds the JSON result for a completed synthesis.
+func NewResult(audioData []byte, contentType string, ttfb time.Duration, text string, output string, opts *api.TTSOptions) Result {
+	spk, modelId, lang := api.EffectiveOpts(opts)
+	return Result{
+		TTFBMs:     ttfb.Milliseconds(),
+		DurationMs: CalculateDuration(audioData, contentType).Milliseconds(),
+		SizeBytes:  len(audioData),
+		OutputFile: output,
+		Text:       text,
+		Speaker:    spk,
+		ModelID:    modelId,
+		Lang:       lang,
+	}
+}
+
+// RunNonInteractive synthesizes, saves and plays opts.Text. If ctx is
+// cancelled part way through and an output file was given, the audio received
+// so far is still saved there.
+func RunNonInteractive(ctx context.Context, opts RunOptions) error {
+	client, err := NewClient(opts.Version, opts.BaseURL, opts.ConfigEnv, opts.ConfigFile, opts.Retries, opts.Cache)
+	if err != nil {
+		return err
+	}
+
+	audio, err := SynthesizeChunked(ctx, client, opts.Text, opts.TTSOptions, opts.Chunk)
+	if err != nil {
+		if audio != nil && len(audio.Data) > 0 && opts.Output != "" && opts.Output != "-" {
+			if _, saveErr := SaveAudio(opts.Output, audio, opts.Text, opts.TTSOptions); saveErr != nil {
+				return fmt.Errorf("%w (saving partial audio failed: %v)", err, saveErr)
 			}
-		} else {
-			meta := metadata.WavMetadata{
-				Artist:  "Rime AI TTS",
-				Name:    fmt.Sprintf("Rime AI TTS [%s-%s-%s]: %s", spk, modelId, lang, truncatedText),
-				Comment: fmt.Sprintf("[%s-%s-%s]: %s", spk, modelId, lang, opts.Text),
+			if !opts.Quiet && !opts.JSON {
+				fmt.Fprintln(os.Stderr, styles.Successf("Partial audio saved to %s", opts.Output))
 			}
-			audioData = metadata.EmbedMetadata(audioData, meta)
 		}
+		return err
+	}
 
-		if err := os.WriteFile(opts.Output, audioData, 0644); err != nil {
+	report, err := opts.Edits.Apply(audio)
+	if err != nil {
+		return err
+	}
+	contentType := audio.ContentType
+	audioData := audio.Data
+
+	if opts.Output != "" && opts.Output != "-" {
+		audioData, err = SaveAudio(opts.Output, audio, opts.Text, opts.TTSOptions)
+		if err != nil {
 			return err
 		}
 		if !opts.Quiet && !opts.JSON {
@@ -123,43 +264,63 @@ func RunNonInteractive(opts RunOptions) error {
 		}
 	}
 
+	if opts.Subtitles != nil {
+		if err := WriteSubtitles(opts.Subtitles, audio, opts.Text); err != nil {
+			return err
+		}
+		if !opts.Quiet && !opts.JSON {
+			fmt.Fprintln(os.Stderr, styles.Successf("Subtitles saved to %s", opts.Subtitles.Path))
+		}
+	}
+
 	if opts.Play && !opts.JSON {
-		if err := playback.PlayAudioData(audioData, contentType); err != nil {
+		if err := playback.PlayAudioDataContext(ctx, audioData, contentType); err != nil {
 			return err
 		}
 	}
 
 	if opts.JSON {
-		audioDur := calculateDuration(audioData, contentType)
-		spk, modelId, lang := api.EffectiveOpts(opts.TTSOptions)
-		ttsResult := Result{
-			TTFBMs:     result.TTFB.Milliseconds(),
-			DurationMs: audioDur.Milliseconds(),
-			SizeBytes:  len(audioData),
-			OutputFile: opts.Output,
-			Text:       opts.Text,
-			Speaker:    spk,
-			ModelID:    modelId,
-			Lang:       lang,
+		ttsResult := NewResult(audioData, contentType, audio.TTFB, opts.Text, opts.Output, opts.TTSOptions)
+		ttsResult.Cached = audio.Cached
+		ttsResult.Words = audio.Words
+		ttsResult.Loudness = report
+		if opts.Subtitles != nil {
+			ttsResult.SubtitlesFile = opts.Subtitles.Path
 		}
 		return json.NewEncoder(os.Stdout).Encode(ttsResult)
 	}
 
 	if !opts.Quiet {
-		audioDur := calculateDuration(audioData, contentType)
-		stats := fmt.Sprintf("TTFB: %dms | Duration: %s | Size: %s",
-			result.TTFB.Milliseconds(),
+		audioDur := CalculateDuration(audioData, contentType)
+		ttfb := fmt.Sprintf("%dms", audio.TTFB.Milliseconds())
+		if audio.Cached {
+			ttfb = "cached"
+		}
+		stats := fmt.Sprintf("TTFB: %s | Duration: %s | Size: %s",
+			ttfb,
 			formatters.FormatDuration(audioDur),
 			formatters.FormatBytes(len(audioData)))
+		if report != nil {
+			stats += " | " + report.String()
+		}
 		fmt.Fprintln(os.Stderr, styles.Dim(stats))
 	}
 
 	return nil
 }
 
-func calculateDuration(audioData []byte, contentType string) time.Duration {
-	if contentType == "audio/mpeg" || contentType == "audio/mp3" {
+// CalculateDuration returns the playback duration of a complete audio file.
+// WAV durations follow the sample format in the header; the API's default
+// format is only assumed if the header can't be read.
+func CalculateDuration(audioData []byte, contentType string) time.Duration {
+	if f, ok := codec.ParseContentType(contentType); ok {
+		return f.Duration(len(audioData))
+	}
+	if IsMP3(contentType) {
 		return analyze.CalculateMP3DurationFromData(audioData)
 	}
+	if d := analyze.CalculateWavDuration(audioData); d > 0 {
+		return d
+	}
 	return analyze.CalculateDuration(audioData, defaultSampleRate, defaultNumChannels, defaultBitsPerSample)
 }
@@ -2,11 +2,20 @@ package tts
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/rimelabs/rime-cli/internal/api"
 	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
@@ -49,7 +58,7 @@ func TestRunNonInteractive_SaveToFile(t *testing.T) {
 		JSON:       false,
 		Version:    "test-version",
 	}
-	err := RunNonInteractive(runOpts)
+	err := RunNonInteractive(context.Background(), runOpts)
 	if err != nil {
 		t.Fatalf("RunNonInteractive failed: %v", err)
 	}
@@ -88,8 +97,172 @@ func TestRunNonInteractive_MissingAPIKey(t *testing.T) {
 		JSON:       false,
 		Version:    "test-version",
 	}
-	err := RunNonInteractive(runOpts)
+	err := RunNonInteractive(context.Background(), runOpts)
 	if err == nil {
 		t.Error("RunNonInteractive should return error when API key is missing")
 	}
 }
+
+func TestRunNonInteractive_CancelSavesPartialFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		header := testhelpers.MakeValidWAV(0)
+		binary.LittleEndian.PutUint32(header[4:8], 0xFFFFFFFF)
+		binary.LittleEndian.PutUint32(header[40:44], 0xFFFFFFFF)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(header)
+		w.Write(make([]byte, 1001)) // ends mid-sample
+		w.(http.Flusher).Flush()
+		// Give the client time to start reading the body before interrupting.
+		time.AfterFunc(100*time.Millisecond, cancel)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+	os.Setenv("RIME_API_URL", server.URL)
+	defer os.Unsetenv("RIME_API_URL")
+
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+
+	outputFile := filepath.Join(tmpDir, "partial.wav")
+	err := RunNonInteractive(ctx, RunOptions{
+		Text:       "hello",
+		TTSOptions: &api.TTSOptions{Speaker: "astra", ModelID: "arcana"},
+		Output:     outputFile,
+		Quiet:      true,
+		Version:    "test-version",
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("partial output should be saved: %v", err)
+	}
+	pos := bytes.LastIndex(data, []byte("data"))
+	if pos < 0 {
+		t.Fatal("partial output has no data chunk")
+	}
+	if size := binary.LittleEndian.Uint32(data[pos+4 : pos+8]); size != 1000 {
+		t.Errorf("data size = %d, want 1000 (whole samples only)", size)
+	}
+	if riff := binary.LittleEndian.Uint32(data[4:8]); int(riff) != len(data)-8 {
+		t.Errorf("RIFF size = %d, want %d", riff, len(data)-8)
+	}
+}
+
+func TestRunNonInteractive_JSONIncludesWordTimings(t *testing.T) {
+	wavData := testhelpers.MakeValidWAV(24000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"audioContent":%q,"word_timestamps":{"words":["hello"],"start":[0.1],"end":[0.6]}}`,
+			base64.StdEncoding.EncodeToString(wavData))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("RIME_API_URL", server.URL)
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := RunNonInteractive(context.Background(), RunOptions{
+		Text:       "hello",
+		TTSOptions: &api.TTSOptions{Speaker: "astra", ModelID: "arcana", Timestamps: true},
+		Output:     filepath.Join(tmpDir, "out.wav"),
+		JSON:       true,
+		Version:    "test-version",
+	})
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("RunNonInteractive failed: %v", err)
+	}
+
+	var result Result
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(result.Words) != 1 || result.Words[0] != (api.WordTiming{Word: "hello", StartMs: 100, EndMs: 600}) {
+		t.Errorf("unexpected words: %+v", result.Words)
+	}
+}
+
+func TestRunNonInteractive_Normalize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(testhelpers.MakeToneWAV(24000, 440, 0.03))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("RIME_API_URL", server.URL)
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	target := -16.0
+	output := filepath.Join(tmpDir, "out.wav")
+	err := RunNonInteractive(context.Background(), RunOptions{
+		Text:       "hello",
+		TTSOptions: &api.TTSOptions{Speaker: "astra", ModelID: "arcana"},
+		Output:     output,
+		JSON:       true,
+		Version:    "test-version",
+		Edits:      Edits{Normalize: &target},
+	})
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("RunNonInteractive failed: %v", err)
+	}
+
+	var result Result
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if result.Loudness == nil {
+		t.Fatal("expected loudness in the result")
+	}
+	if in := float64(result.Loudness.Input.Integrated); in > -25 {
+		t.Errorf("input loudness = %.2f LUFS, expected a quiet tone", in)
+	}
+	if out := float64(result.Loudness.Output.Integrated); math.Abs(out-target) > 0.2 {
+		t.Errorf("output loudness = %.2f LUFS, want %.1f", out, target)
+	}
+
+	saved, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("output not written: %v", err)
+	}
+	if peak := maxSample(saved[len(saved)-2*24000:]); peak < 3000 {
+		t.Errorf("saved audio peaks at %d, expected it to be louder than the 983 sent", peak)
+	}
+}
+
+// maxSample returns the largest magnitude among 16-bit samples in pcm.
+func maxSample(pcm []byte) int {
+	var peak int
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := int(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		peak = max(peak, v, -v)
+	}
+	return peak
+}
@@ -0,0 +1,254 @@
+package tts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+const (
+	// DefaultSubtitleLineLength is the default longest caption line, in
+	// characters.
+	DefaultSubtitleLineLength = 42
+	// DefaultSubtitleMaxDuration is the default longest time a cue stays on
+	// screen.
+	DefaultSubtitleMaxDuration = 7 * time.Second
+
+	// subtitleMaxLines is how many lines a single cue may wrap to.
+	subtitleMaxLines = 2
+)
+
+// Subtitle formats, chosen from the file extension.
+const (
+	SubtitleSRT = "srt"
+	SubtitleVTT = "vtt"
+)
+
+// SubtitleOptions requests captions for the synthesized audio.
+type SubtitleOptions struct {
+	// Path is the .srt or .vtt file to write.
+	Path string
+	// LineLength is the longest caption line, in characters.
+	LineLength int
+	// MaxDuration is the longest a single cue stays on screen.
+	MaxDuration time.Duration
+}
+
+func (o *SubtitleOptions) limits() (int, time.Duration) {
+	lineLength, maxDuration := DefaultSubtitleLineLength, DefaultSubtitleMaxDuration
+	if o != nil && o.LineLength > 0 {
+		lineLength = o.LineLength
+	}
+	if o != nil && o.MaxDuration > 0 {
+		maxDuration = o.MaxDuration
+	}
+	return lineLength, maxDuration
+}
+
+// Cue is one caption: text shown from Start to End.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// SubtitleFormat returns the subtitle format for path from its extension.
+func SubtitleFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".srt":
+		return SubtitleSRT, nil
+	case ".vtt":
+		return SubtitleVTT, nil
+	default:
+		return "", fmt.Errorf("unsupported subtitle format %q (use a .srt or .vtt file)", ext)
+	}
+}
+
+// WordCues groups word timings into cues. A cue ends after a sentence, or
+// before a word that would make it wrap to more than two lines or stay on
+// screen longer than the maximum duration.
+func WordCues(words []api.WordTiming, opts *SubtitleOptions) []Cue {
+	lineLength, maxDuration := opts.limits()
+
+	var cues []Cue
+	var cur []string
+	var start, end time.Duration
+	flush := func() {
+		if len(cur) > 0 {
+			cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(cur, " ")})
+			cur = nil
+		}
+	}
+
+	for _, w := range words {
+		word := strings.TrimSpace(w.Word)
+		if word == "" {
+			continue
+		}
+		if len(cur) > 0 {
+			text := strings.Join(cur, " ") + " " + word
+			if len(wrapLines(text, lineLength)) > subtitleMaxLines || w.End()-start > maxDuration {
+				flush()
+			}
+		}
+		if len(cur) == 0 {
+			start = w.Start()
+		}
+		cur = append(cur, word)
+		end = max(w.End(), start)
+		if endsSentence(word) {
+			flush()
+		}
+	}
+	flush()
+	return cues
+}
+
+// TextCues builds cues when no word timings are available. duration is
+// shared out between sentences in proportion to their length in characters,
+// and within a sentence between its words the same way, before grouping the
+// words as WordCues does.
+func TextCues(text string, duration time.Duration, opts *SubtitleOptions) []Cue {
+	var sentences []string
+	total := 0
+	for _, para := range paragraphBreak.Split(text, -1) {
+		para = strings.Join(strings.Fields(para), " ")
+		if para == "" {
+			continue
+		}
+		for _, s := range splitSentences(para) {
+			sentences = append(sentences, s)
+			total += utf8.RuneCountInString(s)
+		}
+	}
+	if total == 0 || duration <= 0 {
+		return nil
+	}
+
+	at := func(chars int) int64 {
+		return duration.Milliseconds() * int64(chars) / int64(total)
+	}
+	var words []api.WordTiming
+	base := 0
+	for _, s := range sentences {
+		sentenceLen := utf8.RuneCountInString(s)
+		fields := strings.Fields(s)
+		offset := 0
+		for i, field := range fields {
+			next := sentenceLen
+			if i < len(fields)-1 {
+				next = offset + utf8.RuneCountInString(field) + 1
+			}
+			words = append(words, api.WordTiming{
+				Word:    field,
+				StartMs: at(base + offset),
+				EndMs:   at(base + next),
+			})
+			offset = next
+		}
+		base += sentenceLen
+	}
+	return WordCues(words, opts)
+}
+
+// endsSentence reports whether word ends with sentence-ending punctuation,
+// ignoring any closing quotes or brackets after it.
+func endsSentence(word string) bool {
+	word = strings.TrimRightFunc(word, isClosing)
+	r, _ := utf8.DecodeLastRuneInString(word)
+	return isSentenceEnd(r)
+}
+
+// wrapLines breaks text between words into lines of at most width
+// characters. A word longer than width gets a line of its own.
+func wrapLines(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		n := utf8.RuneCountInString(word)
+		if lineLen > 0 && lineLen+1+n > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			line.WriteByte(' ')
+			lineLen++
+		}
+		line.WriteString(word)
+		lineLen += n
+	}
+	if lineLen > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// WriteCues writes cues to w in format (SubtitleSRT or SubtitleVTT), wrapping
+// each cue's text into lines of at most lineLength characters.
+func WriteCues(w io.Writer, format string, cues []Cue, lineLength int) error {
+	bw := bufio.NewWriter(w)
+	sep := ","
+	if format == SubtitleVTT {
+		sep = "."
+		bw.WriteString("WEBVTT\n\n")
+	}
+	for i, cue := range cues {
+		if format == SubtitleSRT {
+			fmt.Fprintf(bw, "%d\n", i+1)
+		}
+		fmt.Fprintf(bw, "%s --> %s\n", formatCueTime(cue.Start, sep), formatCueTime(cue.End, sep))
+		for _, line := range wrapLines(cue.Text, lineLength) {
+			fmt.Fprintf(bw, "%s\n", line)
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+func formatCueTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// WriteSubtitles writes captions for audio to subs.Path. They follow the
+// word timings when audio has them; otherwise text is spread over the
+// measured length of the audio.
+func WriteSubtitles(subs *SubtitleOptions, audio *Audio, text string) error {
+	format, err := SubtitleFormat(subs.Path)
+	if err != nil {
+		return err
+	}
+
+	var cues []Cue
+	if len(audio.Words) > 0 {
+		cues = WordCues(audio.Words, subs)
+	} else {
+		cues = TextCues(text, CalculateDuration(audio.Data, audio.ContentType), subs)
+	}
+	if len(cues) == 0 {
+		return fmt.Errorf("no subtitles to write: the audio length could not be measured")
+	}
+
+	f, err := os.Create(subs.Path)
+	if err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	lineLength, _ := subs.limits()
+	if err := WriteCues(f, format, cues, lineLength); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	return nil
+}
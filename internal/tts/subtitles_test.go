@@ -0,0 +1,165 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/testhelpers"
+	"github.com/rimelabs/rime-cli/internal/config"
+)
+
+func word(w string, startMs, endMs int64) api.WordTiming {
+	return api.WordTiming{Word: w, StartMs: startMs, EndMs: endMs}
+}
+
+func checkCues(t *testing.T, got, want []Cue) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d cues %+v, want %d %+v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWordCues(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name  string
+		words []api.WordTiming
+		opts  *SubtitleOptions
+		want  []Cue
+	}{
+		{
+			name:  "breaks after sentences",
+			words: []api.WordTiming{word("Hello", 0, 400), word("world.", 400, 900), word("Next", 1000, 1400)},
+			want:  []Cue{{0, 900 * ms, "Hello world."}, {1000 * ms, 1400 * ms, "Next"}},
+		},
+		{
+			name: "at most two lines",
+			words: []api.WordTiming{
+				word("one", 0, 100), word("two", 100, 200), word("three", 200, 300),
+				word("four", 300, 400), word("five", 400, 500),
+			},
+			opts: &SubtitleOptions{LineLength: 10, MaxDuration: time.Minute},
+			want: []Cue{{0, 400 * ms, "one two three four"}, {400 * ms, 500 * ms, "five"}},
+		},
+		{
+			name:  "max duration",
+			words: []api.WordTiming{word("a", 0, 400), word("b", 400, 800), word("c", 800, 1200)},
+			opts:  &SubtitleOptions{MaxDuration: time.Second},
+			want:  []Cue{{0, 800 * ms, "a b"}, {800 * ms, 1200 * ms, "c"}},
+		},
+		{
+			name:  "closing quote after sentence end",
+			words: []api.WordTiming{word(`"Stop!"`, 0, 500), word("she", 600, 800), word("said.", 800, 1100)},
+			want:  []Cue{{0, 500 * ms, `"Stop!"`}, {600 * ms, 1100 * ms, "she said."}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCues(t, WordCues(tt.words, tt.opts), tt.want)
+		})
+	}
+}
+
+func TestTextCues_WeightsSentencesByLength(t *testing.T) {
+	ms := time.Millisecond
+	got := TextCues("Hello there. How are you?\n\nGoodbye, friend.", 4000*ms, nil)
+	// 12 + 12 + 16 characters over 4 seconds.
+	checkCues(t, got, []Cue{
+		{0, 1200 * ms, "Hello there."},
+		{1200 * ms, 2400 * ms, "How are you?"},
+		{2400 * ms, 4000 * ms, "Goodbye, friend."},
+	})
+
+	if cues := TextCues("Hello.", 0, nil); cues != nil {
+		t.Errorf("expected no cues without a duration, got %+v", cues)
+	}
+}
+
+func TestWriteCues(t *testing.T) {
+	cues := []Cue{
+		{50 * time.Millisecond, 900 * time.Millisecond, "Hello world."},
+		{time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, time.Hour + 2*time.Minute + 5*time.Second, "A longer line that wraps"},
+	}
+
+	var srt bytes.Buffer
+	if err := WriteCues(&srt, SubtitleSRT, cues, 16); err != nil {
+		t.Fatalf("WriteCues failed: %v", err)
+	}
+	wantSRT := "1\n00:00:00,050 --> 00:00:00,900\nHello world.\n\n" +
+		"2\n01:02:03,004 --> 01:02:05,000\nA longer line\nthat wraps\n\n"
+	if srt.String() != wantSRT {
+		t.Errorf("SRT output:\n%s\nwant:\n%s", srt.String(), wantSRT)
+	}
+
+	var vtt bytes.Buffer
+	if err := WriteCues(&vtt, SubtitleVTT, cues, 16); err != nil {
+		t.Fatalf("WriteCues failed: %v", err)
+	}
+	wantVTT := "WEBVTT\n\n00:00:00.050 --> 00:00:00.900\nHello world.\n\n" +
+		"01:02:03.004 --> 01:02:05.000\nA longer line\nthat wraps\n\n"
+	if vtt.String() != wantVTT {
+		t.Errorf("VTT output:\n%s\nwant:\n%s", vtt.String(), wantVTT)
+	}
+}
+
+func TestSubtitleFormat(t *testing.T) {
+	for path, want := range map[string]string{"clip.srt": SubtitleSRT, "CLIP.VTT": SubtitleVTT} {
+		if got, err := SubtitleFormat(path); err != nil || got != want {
+			t.Errorf("SubtitleFormat(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := SubtitleFormat("clip.txt"); err == nil {
+		t.Error("expected an error for a .txt file")
+	}
+}
+
+func TestRunNonInteractive_WritesSubtitles(t *testing.T) {
+	// Two seconds of audio at 24kHz, with no word timings.
+	wavData := testhelpers.MakeValidWAV(48000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavData)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("RIME_API_URL", server.URL)
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+
+	subsPath := filepath.Join(tmpDir, "clip.srt")
+	err := RunNonInteractive(context.Background(), RunOptions{
+		Text:       "Hello there. How are you?",
+		TTSOptions: &api.TTSOptions{Speaker: "astra", ModelID: "arcana"},
+		Output:     filepath.Join(tmpDir, "clip.wav"),
+		Quiet:      true,
+		Version:    "test-version",
+		Subtitles:  &SubtitleOptions{Path: subsPath},
+	})
+	if err != nil {
+		t.Fatalf("RunNonInteractive failed: %v", err)
+	}
+
+	data, err := os.ReadFile(subsPath)
+	if err != nil {
+		t.Fatalf("subtitles not written: %v", err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,000\nHello there.\n\n2\n00:00:01,000 --> 00:00:02,000\nHow are you?\n\n"
+	if string(data) != want {
+		t.Errorf("subtitles:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -0,0 +1,205 @@
+package tts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/audio/playback"
+	"github.com/rimelabs/rime-cli/internal/output/formatters"
+	"github.com/rimelabs/rime-cli/internal/output/styles"
+)
+
+type sentText struct {
+	text string
+	err  error
+}
+
+// RunWebSocket synthesizes over a WebSocket streaming session. With input
+// nil, opts.Text is sent in one piece. Otherwise input is read line by line
+// and each line is sent as soon as it arrives, with a blank line flushing
+// what has been sent so far, so audio starts before the input ends.
+//
+// Audio is played as it arrives if opts.Play is set, streamed to stdout if
+// opts.Output is "-", and otherwise saved to opts.Output, if set, once the
+// session ends. If ctx is cancelled, the audio received so far is still
+// saved.
+func RunWebSocket(ctx context.Context, opts RunOptions, input io.Reader) error {
+	client, err := NewClient(opts.Version, opts.BaseURL, opts.ConfigEnv, opts.ConfigFile, 0, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	sess, err := client.NewStreamingSession(ctx, opts.TTSOptions)
+	if err != nil {
+		return err
+	}
+
+	contentType := "audio/mp3"
+	var header []byte
+	if sess.ContentType == "audio/pcm" {
+		contentType = "audio/wav"
+		header = metadata.StreamingWavHeader(sess.SampleRate, 1)
+	}
+
+	sent := make(chan sentText, 1)
+	go func() {
+		text, err := sendInput(sess, opts.Text, input)
+		sent <- sentText{text, err}
+	}()
+
+	var sinks []io.Writer
+	var saved bytes.Buffer
+	if opts.Output == "-" {
+		sinks = append(sinks, os.Stdout)
+	} else {
+		sinks = append(sinks, &saved)
+	}
+	var player *io.PipeWriter
+	playErr := make(chan error, 1)
+	if opts.Play {
+		pr, pw := io.Pipe()
+		player = pw
+		sinks = append(sinks, pw)
+		go func() {
+			err := playback.PlayStreamContext(ctx, pr, contentType)
+			pr.CloseWithError(fmt.Errorf("playback stopped: %v", err))
+			playErr <- err
+		}()
+	}
+	out := io.MultiWriter(sinks...)
+
+	var writeErr error
+	if _, err := out.Write(header); err != nil {
+		writeErr = err
+	}
+	var ttfb time.Duration
+	for chunk := range sess.Audio {
+		if ttfb == 0 {
+			ttfb = time.Since(start)
+		}
+		if writeErr != nil {
+			continue
+		}
+		if _, err := out.Write(chunk); err != nil {
+			writeErr = err
+			cancel()
+		}
+	}
+	err = sess.Err()
+	if writeErr != nil {
+		err = writeErr
+	}
+
+	text := opts.Text
+	select {
+	case s := <-sent:
+		if s.text != "" {
+			text = s.text
+		}
+		if err == nil {
+			err = s.err
+		}
+	default:
+		// The session ended before all input was sent; the sender is
+		// blocked reading input and is abandoned.
+	}
+
+	if player != nil {
+		if err == nil {
+			player.Close()
+			err = <-playErr
+		} else {
+			player.CloseWithError(err)
+			<-playErr
+		}
+	}
+
+	if opts.Output == "-" {
+		if err != nil && ctx.Err() != nil && writeErr == nil {
+			return interrupted(ctx)
+		}
+		return err
+	}
+
+	audioData := saved.Bytes()
+	if contentType == "audio/wav" {
+		audioData = metadata.FixWavHeader(metadata.TrimPartialFrame(audioData))
+	}
+	if opts.Output != "" && (err == nil || len(audioData) > len(header)) {
+		written, saveErr := SaveAudio(opts.Output, &Audio{Data: audioData, ContentType: contentType}, text, opts.TTSOptions)
+		if saveErr != nil {
+			return fmt.Errorf("failed to write output file: %w", saveErr)
+		}
+		audioData = written
+		if err != nil && !opts.Quiet && !opts.JSON {
+			fmt.Fprintln(os.Stderr, styles.Dim(fmt.Sprintf("Partial audio saved to %s", opts.Output)))
+		}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil && writeErr == nil {
+			return interrupted(ctx)
+		}
+		return err
+	}
+
+	if opts.JSON {
+		result := NewResult(audioData, contentType, ttfb, text, opts.Output, opts.TTSOptions)
+		return json.NewEncoder(os.Stdout).Encode(result)
+	}
+	if !opts.Quiet {
+		stats := fmt.Sprintf("TTFB: %dms | Duration: %s | Size: %s",
+			ttfb.Milliseconds(),
+			formatters.FormatDuration(CalculateDuration(audioData, contentType)),
+			formatters.FormatBytes(len(audioData)))
+		fmt.Fprintln(os.Stderr, styles.Dim(stats))
+	}
+	return nil
+}
+
+// sendInput sends text, or the lines of input if it is non-nil, and closes
+// the session. It returns all the text sent.
+func sendInput(sess *api.StreamingSession, text string, input io.Reader) (string, error) {
+	if input == nil {
+		if err := sess.Send(text); err != nil {
+			return text, err
+		}
+		return text, sess.Close()
+	}
+
+	var all strings.Builder
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if err := sess.Flush(); err != nil {
+				return all.String(), err
+			}
+			continue
+		}
+		// Lines are joined with a space so words split across lines stay
+		// separate.
+		if err := sess.Send(line + " "); err != nil {
+			return all.String(), err
+		}
+		all.WriteString(line + " ")
+	}
+	if err := scanner.Err(); err != nil {
+		return all.String(), fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(all.String()), sess.Close()
+}
@@ -0,0 +1,108 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/audio/metadata"
+	"github.com/rimelabs/rime-cli/internal/config"
+	"github.com/rimelabs/rime-cli/internal/ws"
+)
+
+func TestRunWebSocket_StdinLines(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+
+	// Stands in for the streaming API: 200 bytes of PCM per text message
+	// and 100 per flush.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Accept(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg map[string]string
+			json.Unmarshal(data, &msg)
+			mu.Lock()
+			received = append(received, string(data))
+			mu.Unlock()
+
+			n := 0
+			switch {
+			case msg["text"] != "":
+				n = 200
+			case msg["operation"] == "flush":
+				n = 100
+			case msg["operation"] == "eos":
+				return
+			}
+			reply, _ := json.Marshal(map[string]string{"type": "chunk", "data": base64.StdEncoding.EncodeToString(make([]byte, n))})
+			conn.WriteMessage(ws.TextMessage, reply)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("RIME_API_URL", server.URL)
+	if err := config.SaveAPIKey("test-key"); err != nil {
+		t.Fatalf("Failed to save API key: %v", err)
+	}
+
+	outputFile := filepath.Join(tmpDir, "out.wav")
+	input := strings.NewReader("Hello there.\n\nHow are\nyou?\n")
+	err := RunWebSocket(context.Background(), RunOptions{
+		TTSOptions: &api.TTSOptions{Speaker: "astra", ModelID: "arcana"},
+		Output:     outputFile,
+		Quiet:      true,
+		Version:    "test-version",
+	}, input)
+	if err != nil {
+		t.Fatalf("RunWebSocket failed: %v", err)
+	}
+
+	mu.Lock()
+	got := strings.Join(received, "\n")
+	mu.Unlock()
+	want := strings.Join([]string{
+		`{"text":"Hello there. "}`,
+		`{"operation":"flush"}`,
+		`{"text":"How are "}`,
+		`{"text":"you? "}`,
+		`{"operation":"eos"}`,
+	}, "\n")
+	if got != want {
+		t.Errorf("messages sent:\n%s\nwant:\n%s", got, want)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("output not saved: %v", err)
+	}
+	if binary.LittleEndian.Uint32(data[24:28]) != api.DefaultStreamingSampleRate {
+		t.Errorf("unexpected sample rate %d", binary.LittleEndian.Uint32(data[24:28]))
+	}
+	pos := bytes.LastIndex(data, []byte("data"))
+	if size := binary.LittleEndian.Uint32(data[pos+4 : pos+8]); size != 700 {
+		t.Errorf("data size = %d, want 700", size)
+	}
+	if meta := metadata.ReadMetadata(data); !strings.Contains(meta.Comment, "Hello there. How are you?") {
+		t.Errorf("expected the streamed text in the metadata, got %q", meta.Comment)
+	}
+}
@@ -0,0 +1,212 @@
+// Package voices caches the public voice catalog and answers speaker lookups
+// for validation and shell completion.
+package voices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+	"github.com/rimelabs/rime-cli/internal/config"
+)
+
+// DefaultTTL is how long a fetched catalog is used before it is refreshed.
+const DefaultTTL = 24 * time.Hour
+
+const cacheFile = "voices.json"
+
+// Catalog is the voice list as cached on disk.
+type Catalog struct {
+	FetchedAt time.Time   `json:"fetched_at"`
+	Voices    []api.Voice `json:"voices"`
+	// Stale is set when a refresh failed and an expired cache was used.
+	Stale bool `json:"-"`
+}
+
+type LoadOptions struct {
+	// TTL is the maximum cache age (DefaultTTL if zero).
+	TTL time.Duration
+	// Refresh fetches the catalog even if the cache is fresh.
+	Refresh bool
+}
+
+// CachePath returns the location of the cached catalog, ~/.rime/voices.json.
+func CachePath() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheFile), nil
+}
+
+// Cached returns the cached catalog regardless of its age, or an error
+// satisfying os.IsNotExist if nothing has been cached yet.
+func Cached() (*Catalog, error) {
+	path, err := CachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse voice cache %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Load returns the cached catalog if it is fresh and otherwise fetches and
+// caches a new one. If the fetch fails but an expired cache exists, that is
+// returned with Stale set rather than failing.
+func Load(ctx context.Context, client *api.VoicesClient, opts LoadOptions) (*Catalog, error) {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	cached, _ := Cached()
+	if cached != nil && !opts.Refresh && time.Since(cached.FetchedAt) < ttl {
+		return cached, nil
+	}
+
+	list, err := client.ListVoices(ctx)
+	if err != nil {
+		if cached != nil && ctx.Err() == nil {
+			cached.Stale = true
+			return cached, nil
+		}
+		return nil, fmt.Errorf("failed to fetch voice catalog: %w", err)
+	}
+
+	c := &Catalog{FetchedAt: time.Now().UTC(), Voices: list}
+	if err := c.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache voice catalog: %v\n", err)
+	}
+	return c, nil
+}
+
+func (c *Catalog) save() error {
+	path, err := CachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Filter selects voices. Empty fields match everything; Tags must all match.
+type Filter struct {
+	ModelID string
+	Lang    string
+	Gender  string
+	Tags    []string
+}
+
+// Filter returns the matching voices sorted by model and speaker.
+func (c *Catalog) Filter(f Filter) []api.Voice {
+	var out []api.Voice
+	for _, v := range c.Voices {
+		if f.ModelID != "" && !strings.EqualFold(v.ModelID, f.ModelID) {
+			continue
+		}
+		if f.Lang != "" && api.CanonicalLang(v.Lang) != api.CanonicalLang(f.Lang) {
+			continue
+		}
+		if f.Gender != "" && !strings.EqualFold(v.Gender, f.Gender) {
+			continue
+		}
+		if !hasTags(v, f.Tags) {
+			continue
+		}
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ModelID != out[j].ModelID {
+			return out[i].ModelID < out[j].ModelID
+		}
+		if out[i].Speaker != out[j].Speaker {
+			return out[i].Speaker < out[j].Speaker
+		}
+		return out[i].Lang < out[j].Lang
+	})
+	return out
+}
+
+func hasTags(v api.Voice, want []string) bool {
+	tags := v.Tags()
+	for _, w := range want {
+		w = strings.ToLower(w)
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Speakers returns the distinct speaker names available for modelID (all
+// models if empty), sorted.
+func (c *Catalog) Speakers(modelID string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, v := range c.Filter(Filter{ModelID: modelID}) {
+		if !seen[v.Speaker] {
+			seen[v.Speaker] = true
+			names = append(names, v.Speaker)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ModelsFor returns the models that offer speaker, sorted.
+func (c *Catalog) ModelsFor(speaker string) []string {
+	seen := make(map[string]bool)
+	var models []string
+	for _, v := range c.Voices {
+		if strings.EqualFold(v.Speaker, speaker) && !seen[v.ModelID] {
+			seen[v.ModelID] = true
+			models = append(models, v.ModelID)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// CheckSpeaker reports a speaker the catalog lists only for other models,
+// which the API would otherwise answer with an empty response. Speakers the
+// catalog does not know at all are allowed, since accounts can have private
+// voices.
+func (c *Catalog) CheckSpeaker(speaker, modelID string) error {
+	models := c.ModelsFor(speaker)
+	if len(models) == 0 {
+		return nil
+	}
+	for _, m := range models {
+		if strings.EqualFold(m, modelID) {
+			return nil
+		}
+	}
+	return fmt.Errorf("speaker %q is not available for model %s (available for: %s). Run 'rime voices --model %s' to list its speakers",
+		speaker, modelID, strings.Join(models, ", "), modelID)
+}
@@ -0,0 +1,129 @@
+package voices
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rimelabs/rime-cli/internal/api"
+)
+
+var testVoices = []api.Voice{
+	{Speaker: "astra", ModelID: "arcana", Lang: "eng", Gender: "Female", Genre: []string{"Conversational"}},
+	{Speaker: "luna", ModelID: "arcana", Lang: "spa", Gender: "Female", Genre: []string{"Conversational", "Narration"}},
+	{Speaker: "cove", ModelID: "mistv2", Lang: "eng", Gender: "Male", Genre: []string{"General"}},
+}
+
+func voicesServer(t *testing.T, status *int32) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if status != nil && atomic.LoadInt32(status) != http.StatusOK {
+			w.WriteHeader(int(atomic.LoadInt32(status)))
+			return
+		}
+		json.NewEncoder(w).Encode(testVoices)
+	}))
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(api.EnvVoicesURL, server.URL)
+	return server, &calls
+}
+
+func TestLoad_CachesWithinTTL(t *testing.T) {
+	server, calls := voicesServer(t, nil)
+	defer server.Close()
+	client := api.NewVoicesClient("test")
+
+	for i := 0; i < 2; i++ {
+		c, err := Load(context.Background(), client, LoadOptions{})
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if len(c.Voices) != 3 {
+			t.Fatalf("got %d voices, want 3", len(c.Voices))
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 fetch, got %d", *calls)
+	}
+
+	if _, err := Load(context.Background(), client, LoadOptions{Refresh: true}); err != nil {
+		t.Fatalf("Load(Refresh) error = %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Refresh should fetch again, got %d fetches", *calls)
+	}
+}
+
+func TestLoad_StaleCacheOnFetchError(t *testing.T) {
+	status := int32(http.StatusOK)
+	server, _ := voicesServer(t, &status)
+	defer server.Close()
+	client := api.NewVoicesClient("test")
+
+	if _, err := Load(context.Background(), client, LoadOptions{}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+	c, err := Load(context.Background(), client, LoadOptions{TTL: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("expected stale cache, got error %v", err)
+	}
+	if !c.Stale || len(c.Voices) != 3 {
+		t.Errorf("expected stale catalog with 3 voices, got %+v", c)
+	}
+
+	path, _ := CachePath()
+	os.Remove(path)
+	if _, err := Load(context.Background(), client, LoadOptions{}); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected fetch error without a cache, got %v", err)
+	}
+}
+
+func TestCatalog_Filter(t *testing.T) {
+	c := &Catalog{Voices: testVoices}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"astra", "luna", "cove"}},
+		{"model", Filter{ModelID: "arcana"}, []string{"astra", "luna"}},
+		{"iso1 lang", Filter{Lang: "es"}, []string{"luna"}},
+		{"tag is case-insensitive", Filter{Tags: []string{"conversational"}}, []string{"astra", "luna"}},
+		{"all tags must match", Filter{Tags: []string{"conversational", "narration"}}, []string{"luna"}},
+		{"gender", Filter{Gender: "male"}, []string{"cove"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range c.Filter(tt.filter) {
+			got = append(got, v.Speaker)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCatalog_CheckSpeaker(t *testing.T) {
+	c := &Catalog{Voices: testVoices}
+
+	if err := c.CheckSpeaker("astra", "arcana"); err != nil {
+		t.Errorf("astra on arcana: %v", err)
+	}
+	if err := c.CheckSpeaker("custom-clone", "arcana"); err != nil {
+		t.Errorf("unknown speakers should be allowed, got %v", err)
+	}
+	err := c.CheckSpeaker("astra", "mistv2")
+	if err == nil || !strings.Contains(err.Error(), "available for: arcana") {
+		t.Errorf("expected wrong-model error, got %v", err)
+	}
+}
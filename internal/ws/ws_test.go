@@ -0,0 +1,137 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestDial_EchoesMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("expected the dial header to be sent, got %q", r.Header.Get("Authorization"))
+		}
+		conn, err := Accept(w, r)
+		if err != nil {
+			t.Errorf("Accept failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(msgType, data)
+		}
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), wsURL(server), http.Header{"Authorization": {"Bearer key"}})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	messages := []struct {
+		msgType int
+		data    []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, bytes.Repeat([]byte{0xab}, 300)},
+		{BinaryMessage, bytes.Repeat([]byte{0xcd}, 70000)},
+	}
+	for _, m := range messages {
+		if err := conn.WriteMessage(m.msgType, m.data); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage failed: %v", err)
+		}
+		if msgType != m.msgType || !bytes.Equal(data, m.data) {
+			t.Errorf("echo of %d-byte message: got type %d with %d bytes", len(m.data), msgType, len(data))
+		}
+	}
+}
+
+func TestReadMessage_Close(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Accept(w, r)
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(TextMessage, []byte("bye"))
+		conn.sendClose(4001)
+		conn.conn.Close()
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), wsURL(server), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "bye" {
+		t.Fatalf("ReadMessage = %q, %v", data, err)
+	}
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 4001 {
+		t.Errorf("expected a CloseError with code 4001, got %v", err)
+	}
+}
+
+func TestReadMessage_NormalCloseIsEOF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Accept(w, r)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), wsURL(server), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, _, err := conn.ReadMessage(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDial_HandshakeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"bad key"}`))
+	}))
+	defer server.Close()
+
+	_, err := Dial(context.Background(), wsURL(server), nil)
+	var hsErr *HandshakeError
+	if !errors.As(err, &hsErr) {
+		t.Fatalf("expected a HandshakeError, got %v", err)
+	}
+	if hsErr.Response.StatusCode != http.StatusUnauthorized || string(hsErr.Body) != `{"message":"bad key"}` {
+		t.Errorf("unexpected handshake error: %d %q", hsErr.Response.StatusCode, hsErr.Body)
+	}
+}
+
+func TestDial_InvalidScheme(t *testing.T) {
+	if _, err := Dial(context.Background(), "http://example.com", nil); err == nil {
+		t.Error("expected an error for a non-websocket URL")
+	}
+}
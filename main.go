@@ -14,6 +14,6 @@ func main() {
 	rootCmd := cmd.NewRootCmd(version)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, styles.Error(err.Error()))
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }